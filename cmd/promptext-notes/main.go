@@ -15,6 +15,12 @@ import (
 )
 
 func main() {
+	// Subcommands
+	if len(os.Args) > 1 && os.Args[1] == "cache" {
+		runCache(os.Args[2:])
+		return
+	}
+
 	// Parse flags
 	version := flag.String("version", "", "Version to generate notes for (e.g., v0.7.4)")
 	sinceTag := flag.String("since", "", "Generate notes since this tag (auto-detects if empty)")
@@ -24,10 +30,12 @@ func main() {
 	// AI flags
 	generate := flag.Bool("generate", false, "Generate AI-enhanced changelog (requires AI provider)")
 	aiPrompt := flag.Bool("ai-prompt", false, "Generate prompt for AI to enhance release notes (legacy mode)")
-	providerFlag := flag.String("provider", "", "AI provider (anthropic, openai, cerebras, groq, openrouter, ollama)")
+	providerFlag := flag.String("provider", "", "AI provider (anthropic, openai, cerebras, groq, openrouter, gemini, azure-openai, ollama, exec, replay)")
 	modelFlag := flag.String("model", "", "AI model to use")
+	includeFiles := flag.String("include-files", "", "Comma-separated glob patterns of files to include in AI context and diff (e.g., *.go,*.ts,*.py)")
 	excludeFiles := flag.String("exclude-files", "", "Comma-separated list of files to exclude from AI context (e.g., CHANGELOG.md,README.md)")
 	polish := flag.Bool("polish", false, "Enable 2-stage polish workflow (discovery + refinement)")
+	noCache := flag.Bool("no-cache", false, "Don't read or write the AI response cache")
 
 	// Other flags
 	quiet := flag.Bool("quiet", false, "Suppress progress messages")
@@ -51,6 +59,14 @@ func main() {
 	if *modelFlag != "" {
 		cfg.AI.Model = *modelFlag
 	}
+	if *includeFiles != "" {
+		// Override config inclusions with CLI flag
+		patterns := strings.Split(*includeFiles, ",")
+		for i, pattern := range patterns {
+			patterns[i] = strings.TrimSpace(pattern)
+		}
+		cfg.Filters.Files.Include = patterns
+	}
 	if *excludeFiles != "" {
 		// Override config exclusions with CLI flag
 		files := strings.Split(*excludeFiles, ",")
@@ -64,6 +80,9 @@ func main() {
 		// Enable polish workflow from CLI
 		cfg.AI.Polish.Enabled = true
 	}
+	if *noCache {
+		cfg.AI.Cache.Mode = "off"
+	}
 
 	// Validate configuration
 	if err := cfg.Validate(); err != nil {
@@ -99,15 +118,13 @@ func main() {
 		UseAI:        *generate,
 		AIPromptOnly: *aiPrompt,
 		Verbose:      !*quiet,
+		IncludeFiles: cfg.Filters.Files.Include, // Pass inclusions from config
 		ExcludeFiles: cfg.Filters.Files.Exclude, // Pass exclusions from config
 	}
 
-	// Create context with timeout
-	ctx, cancel := context.WithTimeout(context.Background(), cfg.AI.Timeout*2)
-	defer cancel()
-
-	// Generate release notes
-	outputText, err := workflow.GenerateReleaseNotes(ctx, opts, provider, cfg)
+	// No overall deadline: large releases make many requests (map chunks,
+	// continuations, stages, ensemble candidates) and ai.timeout bounds each one
+	outputText, err := workflow.GenerateReleaseNotes(context.Background(), opts, provider, cfg)
 	if err != nil {
 		log.Fatalf("Failed to generate release notes: %v", err)
 	}
@@ -124,3 +141,31 @@ func main() {
 		fmt.Print(outputText)
 	}
 }
+
+// runCache runs the cache subcommand: "cache clear" removes every cached AI
+// response from the configured cache directory
+func runCache(args []string) {
+	flags := flag.NewFlagSet("cache", flag.ExitOnError)
+	configFile := flags.String("config", ".promptext-notes.yml", "Configuration file path")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: promptext-notes cache clear [--config file]")
+		flags.PrintDefaults()
+	}
+	if len(args) == 0 || args[0] != "clear" {
+		flags.Usage()
+		os.Exit(2)
+	}
+	_ = flags.Parse(args[1:])
+
+	cfg := config.LoadOrDefault(*configFile)
+	dir, err := ai.CacheDir(cfg)
+	if err != nil {
+		log.Fatalf("Failed to clear cache: %v", err)
+	}
+
+	removed, err := ai.NewResponseCache(dir, 0).Clear()
+	if err != nil {
+		log.Fatalf("Failed to clear cache: %v", err)
+	}
+	fmt.Fprintf(os.Stderr, "Removed %d cached response(s) from %s\n", removed, dir)
+}
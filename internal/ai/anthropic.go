@@ -19,6 +19,7 @@ type AnthropicProvider struct {
 	apiKey     string
 	config     *config.Config
 	httpClient *http.Client
+	pricing    *PricingTable
 }
 
 // anthropicRequest represents the Anthropic API request format
@@ -28,6 +29,30 @@ type anthropicRequest struct {
 	Temperature float64            `json:"temperature,omitempty"`
 	Messages    []anthropicMessage `json:"messages"`
 	System      string             `json:"system,omitempty"`
+	Thinking    *anthropicThinking `json:"thinking,omitempty"`
+
+	Tools      []anthropicTool      `json:"tools,omitempty"`
+	ToolChoice *anthropicToolChoice `json:"tool_choice,omitempty"`
+}
+
+// anthropicTool is a tool the model can call; its input schema is how
+// Anthropic returns structured output
+type anthropicTool struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description"`
+	InputSchema map[string]interface{} `json:"input_schema"`
+}
+
+// anthropicToolChoice forces a call to the named tool
+type anthropicToolChoice struct {
+	Type string `json:"type"`
+	Name string `json:"name"`
+}
+
+// anthropicThinking enables extended thinking with a token budget
+type anthropicThinking struct {
+	Type         string `json:"type"`
+	BudgetTokens int    `json:"budget_tokens"`
 }
 
 // anthropicMessage represents a message in the conversation
@@ -38,18 +63,20 @@ type anthropicMessage struct {
 
 // anthropicResponse represents the Anthropic API response format
 type anthropicResponse struct {
-	ID      string             `json:"id"`
-	Type    string             `json:"type"`
-	Role    string             `json:"role"`
-	Content []anthropicContent `json:"content"`
-	Model   string             `json:"model"`
-	Usage   anthropicUsage     `json:"usage"`
+	ID         string             `json:"id"`
+	Type       string             `json:"type"`
+	Role       string             `json:"role"`
+	Content    []anthropicContent `json:"content"`
+	Model      string             `json:"model"`
+	StopReason string             `json:"stop_reason"`
+	Usage      anthropicUsage     `json:"usage"`
 }
 
 // anthropicContent represents content blocks in the response
 type anthropicContent struct {
-	Type string `json:"type"`
-	Text string `json:"text"`
+	Type  string          `json:"type"`
+	Text  string          `json:"text"`
+	Input json.RawMessage `json:"input,omitempty"` // tool_use blocks
 }
 
 // anthropicUsage represents token usage information
@@ -74,11 +101,10 @@ func NewAnthropicProvider(apiKey string, cfg *config.Config) (*AnthropicProvider
 	}
 
 	return &AnthropicProvider{
-		apiKey: apiKey,
-		config: cfg,
-		httpClient: &http.Client{
-			Timeout: cfg.AI.Timeout,
-		},
+		apiKey:     apiKey,
+		config:     cfg,
+		pricing:    NewPricingTable(cfg),
+		httpClient: newHTTPClient(cfg),
 	}, nil
 }
 
@@ -103,10 +129,12 @@ func (p *AnthropicProvider) ValidateConfig() error {
 // NewRequest creates a request from a prompt using provider's configured defaults
 func (p *AnthropicProvider) NewRequest(prompt string) *Request {
 	return &Request{
-		Prompt:      prompt,
-		Model:       p.config.AI.Model,
-		MaxTokens:   p.config.AI.MaxTokens,
-		Temperature: p.config.AI.Temperature,
+		Prompt:          prompt,
+		Model:           p.config.AI.Model,
+		MaxTokens:       p.config.AI.MaxTokens,
+		Temperature:     p.config.AI.Temperature,
+		ReasoningEffort: p.config.AI.ReasoningEffort,
+		ThinkingBudget:  p.config.AI.ThinkingBudget,
 	}
 }
 
@@ -152,6 +180,29 @@ func (p *AnthropicProvider) generateOnce(ctx context.Context, req *Request) (*Re
 		apiReq.System = req.SystemPrompt
 	}
 
+	// Enable extended thinking on models that support it. Thinking tokens count
+	// toward max_tokens and temperature must be left unset while thinking.
+	if budget := thinkingBudget(req); budget > 0 && anthropicSupportsThinking(req.Model) {
+		apiReq.Thinking = &anthropicThinking{
+			Type:         "enabled",
+			BudgetTokens: budget,
+		}
+		apiReq.MaxTokens = req.MaxTokens + budget
+		apiReq.Temperature = 0
+	}
+
+	// Structured output is a forced call to a tool taking the schema as input.
+	// Forced tool calls are incompatible with thinking, which then relies on
+	// the schema in the prompt.
+	if req.JSONSchema != nil && apiReq.Thinking == nil {
+		apiReq.Tools = []anthropicTool{{
+			Name:        req.JSONSchema.Name,
+			Description: "Record the output in the required format",
+			InputSchema: req.JSONSchema.Schema,
+		}}
+		apiReq.ToolChoice = &anthropicToolChoice{Type: "tool", Name: req.JSONSchema.Name}
+	}
+
 	// Marshal request to JSON
 	jsonData, err := json.Marshal(apiReq)
 	if err != nil {
@@ -202,55 +253,78 @@ func (p *AnthropicProvider) generateOnce(ctx context.Context, req *Request) (*Re
 		return nil, fmt.Errorf("failed to parse response: %w", err)
 	}
 
-	// Extract text from content blocks
+	// Extract text from content blocks (thinking blocks are intentionally
+	// skipped); structured output arrives as the input of a tool call
 	var content strings.Builder
 	for _, block := range apiResp.Content {
-		if block.Type == "text" {
+		switch block.Type {
+		case "text":
 			content.WriteString(block.Text)
+		case "tool_use":
+			content.Write(block.Input)
 		}
 	}
 
-	// Calculate cost estimate (approximate pricing as of 2024)
-	costEstimate := p.estimateCost(apiResp.Model, apiResp.Usage.InputTokens, apiResp.Usage.OutputTokens)
+	// Calculate cost estimate from the pricing table
+	costEstimate, _ := p.pricing.Cost("anthropic", apiResp.Model, apiResp.Usage.InputTokens, apiResp.Usage.OutputTokens)
 
 	return &Response{
 		Content:      content.String(),
 		TokensUsed:   apiResp.Usage.InputTokens + apiResp.Usage.OutputTokens,
+		InputTokens:  apiResp.Usage.InputTokens,
+		OutputTokens: apiResp.Usage.OutputTokens,
 		Model:        apiResp.Model,
 		Provider:     "anthropic",
 		CostEstimate: costEstimate,
+		StopReason:   normalizeAnthropicStopReason(apiResp.StopReason),
 		Metadata: map[string]interface{}{
 			"input_tokens":  apiResp.Usage.InputTokens,
 			"output_tokens": apiResp.Usage.OutputTokens,
+			"stop_reason":   apiResp.StopReason,
 			"id":            apiResp.ID,
 		},
 	}, nil
 }
 
-// estimateCost calculates approximate cost based on token usage
-func (p *AnthropicProvider) estimateCost(model string, inputTokens, outputTokens int) float64 {
-	// Pricing as of January 2025 (per million tokens)
-	var inputCost, outputCost float64
-
-	switch {
-	case strings.Contains(model, "haiku"):
-		inputCost = 0.80
-		outputCost = 4.00
-	case strings.Contains(model, "sonnet"):
-		inputCost = 3.00
-		outputCost = 15.00
-	case strings.Contains(model, "opus"):
-		inputCost = 15.00
-		outputCost = 75.00
+// thinkingBudget returns the extended thinking budget for a request, deriving
+// one from the reasoning effort when no explicit budget is configured
+func thinkingBudget(req *Request) int {
+	if req.ThinkingBudget > 0 {
+		return req.ThinkingBudget
+	}
+
+	switch req.ReasoningEffort {
+	case "low":
+		return 1024
+	case "medium":
+		return 4096
+	case "high":
+		return 16384
 	default:
-		// Unknown model, use Haiku pricing as baseline
-		inputCost = 0.80
-		outputCost = 4.00
+		return 0
 	}
+}
 
-	// Calculate cost in dollars
-	cost := (float64(inputTokens) * inputCost / 1_000_000) +
-		(float64(outputTokens) * outputCost / 1_000_000)
+// anthropicSupportsThinking reports whether the model supports extended thinking
+func anthropicSupportsThinking(model string) bool {
+	for _, family := range []string{"claude-3-7", "sonnet-4", "opus-4", "haiku-4"} {
+		if strings.Contains(model, family) {
+			return true
+		}
+	}
+	return false
+}
 
-	return cost
+// normalizeAnthropicStopReason maps Anthropic's stop_reason to a StopReason
+func normalizeAnthropicStopReason(reason string) string {
+	switch reason {
+	case "end_turn", "stop_sequence", "tool_use":
+		return StopReasonComplete
+	case "max_tokens":
+		return StopReasonMaxTokens
+	case "refusal":
+		return StopReasonContentFilter
+	default:
+		return StopReasonUnknown
+	}
 }
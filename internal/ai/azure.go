@@ -0,0 +1,251 @@
+package ai
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/1broseidon/promptext-notes/internal/config"
+)
+
+const azureDefaultAPIVersion = "2024-10-21"
+
+// AzureOpenAIProvider implements the Provider interface for Azure OpenAI
+type AzureOpenAIProvider struct {
+	apiKey     string
+	config     *config.Config
+	httpClient *http.Client
+	pricing    *PricingTable
+	endpoint   string
+	apiVersion string
+}
+
+// azureError represents an error response from Azure OpenAI
+type azureError struct {
+	Error struct {
+		Message    string `json:"message"`
+		Type       string `json:"type"`
+		Code       string `json:"code"`
+		InnerError struct {
+			Code string `json:"code"`
+		} `json:"innererror"`
+	} `json:"error"`
+}
+
+// NewAzureOpenAIProvider creates a new Azure OpenAI provider
+func NewAzureOpenAIProvider(apiKey string, cfg *config.Config) (*AzureOpenAIProvider, error) {
+	if apiKey == "" {
+		return nil, fmt.Errorf("azure OpenAI API key is required")
+	}
+
+	// Endpoint from config (custom domains) or built from the resource name
+	endpoint := cfg.AI.Custom["azure_endpoint"]
+	if endpoint == "" {
+		if resource, ok := cfg.AI.Custom["azure_resource"]; ok && resource != "" {
+			endpoint = fmt.Sprintf("https://%s.openai.azure.com", resource)
+		}
+	}
+
+	apiVersion := azureDefaultAPIVersion
+	if version, ok := cfg.AI.Custom["azure_api_version"]; ok && version != "" {
+		apiVersion = version
+	}
+
+	return &AzureOpenAIProvider{
+		apiKey:     apiKey,
+		config:     cfg,
+		endpoint:   strings.TrimSuffix(endpoint, "/"),
+		apiVersion: apiVersion,
+		pricing:    NewPricingTable(cfg),
+		httpClient: newHTTPClient(cfg),
+	}, nil
+}
+
+// Name returns the provider name
+func (p *AzureOpenAIProvider) Name() string {
+	return "azure-openai"
+}
+
+// ValidateConfig checks if the configuration is valid
+func (p *AzureOpenAIProvider) ValidateConfig() error {
+	if p.apiKey == "" {
+		return fmt.Errorf("azure OpenAI API key is not set")
+	}
+
+	if p.endpoint == "" {
+		return fmt.Errorf("azure OpenAI resource is not specified (set custom.azure_resource or custom.azure_endpoint)")
+	}
+
+	if p.config.AI.Model == "" && p.config.AI.Custom["azure_deployment"] == "" {
+		return fmt.Errorf("azure OpenAI deployment is not specified")
+	}
+
+	return nil
+}
+
+// NewRequest creates a request from a prompt using provider's configured defaults
+func (p *AzureOpenAIProvider) NewRequest(prompt string) *Request {
+	return &Request{
+		Prompt:          prompt,
+		Model:           p.config.AI.Model,
+		MaxTokens:       p.config.AI.MaxTokens,
+		Temperature:     p.config.AI.Temperature,
+		ReasoningEffort: p.config.AI.ReasoningEffort,
+	}
+}
+
+// Generate sends a request to Azure OpenAI and returns the response
+func (p *AzureOpenAIProvider) Generate(ctx context.Context, req *Request) (*Response, error) {
+	if err := p.ValidateConfig(); err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+
+	var response *Response
+	var generateErr error
+
+	// Use retry with backoff
+	err := RetryWithBackoff(ctx, p.config, func(ctx context.Context) error {
+		response, generateErr = p.generateOnce(ctx, req)
+		return generateErr
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
+
+// deploymentURL builds the chat completions URL for the request's deployment.
+// The deployment defaults to the model name; custom.azure_deployment overrides it.
+func (p *AzureOpenAIProvider) deploymentURL(req *Request) string {
+	deployment := req.Model
+	if override, ok := p.config.AI.Custom["azure_deployment"]; ok && override != "" {
+		deployment = override
+	}
+
+	return fmt.Sprintf("%s/openai/deployments/%s/chat/completions?api-version=%s",
+		p.endpoint, deployment, p.apiVersion)
+}
+
+// generateOnce performs a single generation attempt
+func (p *AzureOpenAIProvider) generateOnce(ctx context.Context, req *Request) (*Response, error) {
+	// Build messages array
+	messages := []openaiMessage{}
+
+	// Add system prompt if provided
+	if req.SystemPrompt != "" {
+		messages = append(messages, openaiMessage{
+			Role:    "system",
+			Content: req.SystemPrompt,
+		})
+	}
+
+	// Add user prompt
+	messages = append(messages, openaiMessage{
+		Role:    "user",
+		Content: req.Prompt,
+	})
+
+	// Build request payload (OpenAI format, routing is done by the deployment URL)
+	apiReq := openaiRequest{
+		Model:          req.Model,
+		Messages:       messages,
+		ResponseFormat: openaiResponseFormatFor(req, true),
+	}
+
+	// Reasoning models take max_completion_tokens and reject temperature
+	if isOpenAIReasoningModel(req.Model) {
+		apiReq.MaxCompletionTokens = req.MaxTokens
+		apiReq.ReasoningEffort = req.ReasoningEffort
+	} else {
+		apiReq.MaxTokens = req.MaxTokens
+		apiReq.Temperature = req.Temperature
+	}
+
+	// Marshal request to JSON
+	jsonData, err := json.Marshal(apiReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	// Create HTTP request
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", p.deploymentURL(req), bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	// Set headers
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("api-key", p.apiKey)
+
+	// Send request
+	httpResp, err := p.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer httpResp.Body.Close()
+
+	// Read response body
+	body, err := io.ReadAll(httpResp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	// Handle non-200 responses
+	if httpResp.StatusCode != http.StatusOK {
+		var apiErr azureError
+		if err := json.Unmarshal(body, &apiErr); err != nil || apiErr.Error.Message == "" {
+			return nil, fmt.Errorf("azure OpenAI API error (status %d): %s", httpResp.StatusCode, string(body))
+		}
+		// Prompt rejected by the Azure content filter
+		if apiErr.Error.Code == "content_filter" || apiErr.Error.InnerError.Code == "ResponsibleAIPolicyViolation" {
+			return nil, &ContentFilterError{Provider: "azure-openai", Reason: apiErr.Error.Message}
+		}
+		return nil, fmt.Errorf("azure OpenAI API error: %s", apiErr.Error.Message)
+	}
+
+	// Parse response (OpenAI format)
+	var apiResp openaiResponse
+	if err := json.Unmarshal(body, &apiResp); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	// Extract content from first choice
+	if len(apiResp.Choices) == 0 {
+		return nil, fmt.Errorf("no choices in response")
+	}
+
+	// Completion stopped by the Azure content filter
+	if apiResp.Choices[0].FinishReason == "content_filter" {
+		return nil, &ContentFilterError{Provider: "azure-openai", Reason: "response was filtered"}
+	}
+
+	// Drop any inline reasoning so only the answer reaches the changelog
+	content := stripReasoning(apiResp.Choices[0].Message.Content)
+
+	// Calculate cost estimate (Azure bills the same per-token rates as OpenAI)
+	costEstimate, _ := p.pricing.Cost("azure-openai", apiResp.Model, apiResp.Usage.PromptTokens, apiResp.Usage.CompletionTokens)
+
+	return &Response{
+		Content:      content,
+		TokensUsed:   apiResp.Usage.TotalTokens,
+		InputTokens:  apiResp.Usage.PromptTokens,
+		OutputTokens: apiResp.Usage.CompletionTokens,
+		Model:        apiResp.Model,
+		Provider:     "azure-openai",
+		CostEstimate: costEstimate,
+		StopReason:   normalizeFinishReason(apiResp.Choices[0].FinishReason),
+		Metadata: map[string]interface{}{
+			"prompt_tokens":     apiResp.Usage.PromptTokens,
+			"completion_tokens": apiResp.Usage.CompletionTokens,
+			"reasoning_tokens":  apiResp.Usage.CompletionTokensDetails.ReasoningTokens,
+			"finish_reason":     apiResp.Choices[0].FinishReason,
+			"id":                apiResp.ID,
+		},
+	}, nil
+}
@@ -0,0 +1,123 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/1broseidon/promptext-notes/internal/config"
+)
+
+// newTestAzureProvider creates an Azure OpenAI provider pointed at a fake server
+func newTestAzureProvider(t *testing.T, handler http.HandlerFunc) *AzureOpenAIProvider {
+	t.Helper()
+
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	cfg := config.Default()
+	cfg.AI.Provider = "azure-openai"
+	cfg.AI.Model = "release-notes-gpt4o"
+	cfg.AI.Custom["azure_endpoint"] = server.URL
+	cfg.AI.Custom["azure_api_version"] = "2024-06-01"
+	cfg.AI.Retry.Attempts = 3
+
+	provider, err := NewAzureOpenAIProvider("test-key", cfg)
+	if err != nil {
+		t.Fatalf("NewAzureOpenAIProvider() error = %v", err)
+	}
+	return provider
+}
+
+func TestAzureDeploymentURL(t *testing.T) {
+	cfg := config.Default()
+	cfg.AI.Custom["azure_resource"] = "contoso"
+
+	provider, _ := NewAzureOpenAIProvider("key", cfg)
+	got := provider.deploymentURL(&Request{Model: "gpt-4o-mini"})
+	want := "https://contoso.openai.azure.com/openai/deployments/gpt-4o-mini/chat/completions?api-version=" + azureDefaultAPIVersion
+	if got != want {
+		t.Errorf("deploymentURL() = %s, want %s", got, want)
+	}
+
+	cfg.AI.Custom["azure_deployment"] = "notes-prod"
+	got = provider.deploymentURL(&Request{Model: "gpt-4o-mini"})
+	want = "https://contoso.openai.azure.com/openai/deployments/notes-prod/chat/completions?api-version=" + azureDefaultAPIVersion
+	if got != want {
+		t.Errorf("deploymentURL() with override = %s, want %s", got, want)
+	}
+}
+
+func TestAzureGenerate(t *testing.T) {
+	provider := newTestAzureProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/openai/deployments/release-notes-gpt4o/chat/completions" {
+			t.Errorf("Unexpected path: %s", r.URL.Path)
+		}
+		if r.URL.Query().Get("api-version") != "2024-06-01" {
+			t.Errorf("Unexpected api-version: %s", r.URL.Query().Get("api-version"))
+		}
+		if r.Header.Get("api-key") != "test-key" {
+			t.Errorf("Expected api-key header, got %q", r.Header.Get("api-key"))
+		}
+		w.Write([]byte(`{"id":"chatcmpl-1","model":"gpt-4o-2024-08-06","choices":[{"index":0,"message":{"role":"assistant","content":"### Fixed\n- Crash"},"finish_reason":"stop"}],"usage":{"prompt_tokens":100,"completion_tokens":20,"total_tokens":120}}`))
+	})
+
+	resp, err := provider.Generate(context.Background(), provider.NewRequest("prompt"))
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+
+	if resp.Content != "### Fixed\n- Crash" {
+		t.Errorf("Unexpected content: %q", resp.Content)
+	}
+	if resp.TokensUsed != 120 {
+		t.Errorf("Expected 120 tokens, got %d", resp.TokensUsed)
+	}
+	if resp.Provider != "azure-openai" {
+		t.Errorf("Expected azure-openai provider, got %s", resp.Provider)
+	}
+	if resp.CostEstimate <= 0 {
+		t.Error("Expected a cost estimate for gpt-4o")
+	}
+}
+
+func TestAzureContentFilter(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		body   string
+	}{
+		{
+			name:   "prompt filtered",
+			status: http.StatusBadRequest,
+			body:   `{"error":{"code":"content_filter","message":"The response was filtered due to the prompt triggering Azure OpenAI's content management policy.","innererror":{"code":"ResponsibleAIPolicyViolation"}}}`,
+		},
+		{
+			name:   "completion filtered",
+			status: http.StatusOK,
+			body:   `{"choices":[{"index":0,"message":{"role":"assistant","content":""},"finish_reason":"content_filter"}]}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			provider := newTestAzureProvider(t, func(w http.ResponseWriter, r *http.Request) {
+				calls++
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			})
+
+			_, err := provider.Generate(context.Background(), provider.NewRequest("prompt"))
+			var filterErr *ContentFilterError
+			if !errors.As(err, &filterErr) {
+				t.Fatalf("Expected ContentFilterError, got %v", err)
+			}
+			if calls != 1 {
+				t.Errorf("Content filter errors should not be retried, got %d calls", calls)
+			}
+		})
+	}
+}
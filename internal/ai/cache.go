@@ -0,0 +1,225 @@
+package ai
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/1broseidon/promptext-notes/internal/config"
+)
+
+// ResponseCache stores responses on disk, one JSON file per request key, so
+// re-running a release job doesn't pay for the same prompts again
+type ResponseCache struct {
+	dir string
+	ttl time.Duration
+	now func() time.Time
+}
+
+// cacheEntry is the file format of a cached response
+type cacheEntry struct {
+	Created  time.Time `json:"created"`
+	Provider string    `json:"provider"`
+	Model    string    `json:"model"`
+	Response *Response `json:"response"`
+}
+
+// NewResponseCache creates a cache in dir whose entries expire after ttl
+// (0 = never)
+func NewResponseCache(dir string, ttl time.Duration) *ResponseCache {
+	return &ResponseCache{dir: dir, ttl: ttl, now: time.Now}
+}
+
+// CacheDir returns ai.cache.dir, or promptext-notes in the user's cache
+// directory when it isn't set
+func CacheDir(cfg *config.Config) (string, error) {
+	if cfg != nil && cfg.AI.Cache.Dir != "" {
+		return cfg.AI.Cache.Dir, nil
+	}
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to find the user cache directory (set ai.cache.dir): %w", err)
+	}
+	return filepath.Join(dir, "promptext-notes"), nil
+}
+
+// Dir returns the cache directory
+func (c *ResponseCache) Dir() string {
+	return c.dir
+}
+
+// CacheEndpoint describes where a provider sends its requests and the
+// options it adds to them: ai.custom (URLs, Azure resource and deployment,
+// Ollama options) and the exec command. It is part of the cache key so two
+// endpoints serving the same model name don't share responses.
+func CacheEndpoint(cfg *config.Config) string {
+	if cfg == nil {
+		return ""
+	}
+	data, err := json.Marshal(struct {
+		Custom  map[string]string
+		Command []string
+	}{cfg.AI.Custom, cfg.AI.Exec.Command})
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
+
+// CacheKey hashes everything that determines a response: the provider, its
+// endpoint (see CacheEndpoint) and the whole request (model, parameters,
+// schema, system prompt and prompt)
+func CacheKey(provider, endpoint string, req *Request) string {
+	data, err := json.Marshal(struct {
+		Provider string
+		Endpoint string
+		Request  *Request
+	}{provider, endpoint, req})
+	if err != nil {
+		// A Request always marshals; fall back to a key that never matches
+		return ""
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// Get returns the cached response for key, or nil when there is none or it
+// has expired
+func (c *ResponseCache) Get(key string) *Response {
+	if key == "" {
+		return nil
+	}
+	data, err := os.ReadFile(c.path(key))
+	if err != nil {
+		return nil
+	}
+
+	var entry cacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil || entry.Response == nil {
+		return nil
+	}
+	if c.ttl > 0 && c.now().Sub(entry.Created) > c.ttl {
+		return nil
+	}
+	return entry.Response
+}
+
+// Put stores a response under key; the file is written atomically so an
+// interrupted run never leaves a partial entry
+func (c *ResponseCache) Put(key string, resp *Response) error {
+	if key == "" {
+		return nil
+	}
+	if err := os.MkdirAll(c.dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create cache directory: %w", err)
+	}
+
+	data, err := json.Marshal(cacheEntry{
+		Created:  c.now(),
+		Provider: resp.Provider,
+		Model:    resp.Model,
+		Response: resp,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode cached response: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(c.dir, key+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to write cached response: %w", err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write cached response: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write cached response: %w", err)
+	}
+	return os.Rename(tmp.Name(), c.path(key))
+}
+
+// Clear removes every cached response and returns how many there were
+func (c *ResponseCache) Clear() (int, error) {
+	entries, err := os.ReadDir(c.dir)
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to read cache directory: %w", err)
+	}
+
+	removed := 0
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || (!strings.HasSuffix(name, ".json") && !strings.HasSuffix(name, ".tmp")) {
+			continue
+		}
+		if err := os.Remove(filepath.Join(c.dir, name)); err != nil {
+			return removed, fmt.Errorf("failed to remove cached response: %w", err)
+		}
+		if strings.HasSuffix(name, ".json") {
+			removed++
+		}
+	}
+	return removed, nil
+}
+
+// path returns the file of a cache key
+func (c *ResponseCache) path(key string) string {
+	return filepath.Join(c.dir, key+".json")
+}
+
+// cachedProvider wraps a Provider to serve repeated requests from the cache
+type cachedProvider struct {
+	Provider
+	cache    *ResponseCache
+	endpoint string
+	refresh  bool // Don't read the cache, only store new responses
+}
+
+// WithCache wraps a provider so responses are stored in the cache and
+// identical requests are answered from it, marked Cached and at zero cost.
+// Mode is ai.cache.mode: on, refresh (store only) or off (no caching), and
+// endpoint is the provider's CacheEndpoint.
+func WithCache(provider Provider, cache *ResponseCache, mode, endpoint string) Provider {
+	if cache == nil || mode == "off" {
+		return provider
+	}
+	return &cachedProvider{Provider: provider, cache: cache, endpoint: endpoint, refresh: mode == "refresh"}
+}
+
+// Has reports whether the request will be answered from the cache
+func (c *cachedProvider) Has(req *Request) bool {
+	return !c.refresh && c.cache.Get(CacheKey(c.Name(), c.endpoint, req)) != nil
+}
+
+// Generate returns the cached response for the request or calls the provider
+// and caches its response. Errors are never cached.
+func (c *cachedProvider) Generate(ctx context.Context, req *Request) (*Response, error) {
+	key := CacheKey(c.Name(), c.endpoint, req)
+	if !c.refresh {
+		if cached := c.cache.Get(key); cached != nil {
+			resp := *cached
+			resp.CostEstimate = 0
+			resp.Cached = true
+			return &resp, nil
+		}
+	}
+
+	resp, err := c.Provider.Generate(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	// A cache that can't be written only costs the next run money
+	_ = c.cache.Put(key, resp)
+	return resp, nil
+}
@@ -0,0 +1,142 @@
+package ai
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/1broseidon/promptext-notes/internal/config"
+)
+
+func TestCachedProviderServesRepeatedRequests(t *testing.T) {
+	stub := &stubProvider{name: "openai", resp: &Response{
+		Content: "notes", Provider: "openai", Model: "gpt-4o", TokensUsed: 1100, CostEstimate: 0.02,
+	}}
+	provider := WithCache(stub, NewResponseCache(t.TempDir(), time.Hour), "on", "")
+
+	first, err := provider.Generate(context.Background(), provider.NewRequest("prompt"))
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	if first.Cached || first.CostEstimate != 0.02 {
+		t.Errorf("Expected the first response from the provider, got %+v", first)
+	}
+
+	second, err := provider.Generate(context.Background(), provider.NewRequest("prompt"))
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	if stub.calls != 1 {
+		t.Errorf("Expected 1 provider call, got %d", stub.calls)
+	}
+	if !second.Cached || second.CostEstimate != 0 || second.Content != "notes" || second.TokensUsed != 1100 {
+		t.Errorf("Expected a cached response at zero cost, got %+v", second)
+	}
+
+	if _, err := provider.Generate(context.Background(), provider.NewRequest("other prompt")); err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	if stub.calls != 2 {
+		t.Errorf("Expected a different prompt to miss the cache, got %d calls", stub.calls)
+	}
+}
+
+func TestCachedProviderExpiresAndRefreshes(t *testing.T) {
+	dir := t.TempDir()
+	stub := &stubProvider{name: "openai", resp: &Response{Content: "notes", Model: "gpt-4o"}}
+	cache := NewResponseCache(dir, time.Hour)
+	provider := WithCache(stub, cache, "on", "")
+
+	for i := 0; i < 2; i++ {
+		if _, err := provider.Generate(context.Background(), provider.NewRequest("prompt")); err != nil {
+			t.Fatalf("Generate() error = %v", err)
+		}
+	}
+	if stub.calls != 1 {
+		t.Fatalf("Expected 1 provider call, got %d", stub.calls)
+	}
+
+	cache.now = func() time.Time { return time.Now().Add(2 * time.Hour) }
+	if _, err := provider.Generate(context.Background(), provider.NewRequest("prompt")); err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	if stub.calls != 2 {
+		t.Errorf("Expected an expired entry to call the provider, got %d calls", stub.calls)
+	}
+
+	refresh := WithCache(stub, NewResponseCache(dir, time.Hour), "refresh", "")
+	resp, err := refresh.Generate(context.Background(), refresh.NewRequest("prompt"))
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	if resp.Cached || stub.calls != 3 {
+		t.Errorf("Expected refresh mode to skip the cache, got cached=%v after %d calls", resp.Cached, stub.calls)
+	}
+}
+
+func TestCacheKey(t *testing.T) {
+	base := CacheKey("openai", "", &Request{Prompt: "prompt", Model: "gpt-4o", MaxTokens: 100})
+	if base != CacheKey("openai", "", &Request{Prompt: "prompt", Model: "gpt-4o", MaxTokens: 100}) {
+		t.Error("Expected identical requests to have the same key")
+	}
+
+	for name, key := range map[string]string{
+		"provider":   CacheKey("groq", "", &Request{Prompt: "prompt", Model: "gpt-4o", MaxTokens: 100}),
+		"model":      CacheKey("openai", "", &Request{Prompt: "prompt", Model: "gpt-4o-mini", MaxTokens: 100}),
+		"max tokens": CacheKey("openai", "", &Request{Prompt: "prompt", Model: "gpt-4o", MaxTokens: 200}),
+		"system":     CacheKey("openai", "", &Request{Prompt: "prompt", Model: "gpt-4o", MaxTokens: 100, SystemPrompt: "system"}),
+		"endpoint":   CacheKey("openai", `{"Custom":{"base_url":"http://proxy"}}`, &Request{Prompt: "prompt", Model: "gpt-4o", MaxTokens: 100}),
+	} {
+		if key == base {
+			t.Errorf("Expected a different %s to change the key", name)
+		}
+	}
+}
+
+func TestCacheEndpoint(t *testing.T) {
+	endpoint := func(custom map[string]string, command ...string) string {
+		cfg := config.Default()
+		cfg.AI.Custom = custom
+		cfg.AI.Exec.Command = command
+		return CacheEndpoint(cfg)
+	}
+
+	base := endpoint(map[string]string{"ollama_url": "http://gpu-1:11434", "ollama_num_ctx": "8192"})
+	if base != endpoint(map[string]string{"ollama_num_ctx": "8192", "ollama_url": "http://gpu-1:11434"}) {
+		t.Error("Expected the same settings to describe the same endpoint")
+	}
+
+	for name, other := range map[string]string{
+		"ollama url":       endpoint(map[string]string{"ollama_url": "http://gpu-2:11434", "ollama_num_ctx": "8192"}),
+		"ollama options":   endpoint(map[string]string{"ollama_url": "http://gpu-1:11434", "ollama_num_ctx": "32768"}),
+		"azure deployment": endpoint(map[string]string{"azure_resource": "contoso", "azure_deployment": "notes-prod"}),
+		"exec command":     endpoint(nil, "./gateway.sh"),
+	} {
+		if other == base {
+			t.Errorf("Expected a different %s to change the endpoint", name)
+		}
+	}
+	if endpoint(nil, "./gateway.sh") == endpoint(nil, "./other-gateway.sh") {
+		t.Error("Expected a different exec command to change the endpoint")
+	}
+}
+
+func TestResponseCacheClear(t *testing.T) {
+	cache := NewResponseCache(t.TempDir(), 0)
+	for _, prompt := range []string{"one", "two"} {
+		if err := cache.Put(CacheKey("openai", "", &Request{Prompt: prompt}), &Response{Content: prompt}); err != nil {
+			t.Fatalf("Put() error = %v", err)
+		}
+	}
+
+	removed, err := cache.Clear()
+	if err != nil {
+		t.Fatalf("Clear() error = %v", err)
+	}
+	if removed != 2 {
+		t.Errorf("Expected 2 removed entries, got %d", removed)
+	}
+	if cache.Get(CacheKey("openai", "", &Request{Prompt: "one"})) != nil {
+		t.Error("Expected the cache to be empty after Clear()")
+	}
+}
@@ -0,0 +1,78 @@
+package ai
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/1broseidon/promptext-notes/internal/config"
+)
+
+// contextWindows holds model context windows in tokens per provider. Keys are
+// model name patterns matched like the pricing table.
+var contextWindows = map[string]map[string]int{
+	"anthropic": {
+		"claude": 200_000,
+	},
+	"openai": {
+		"gpt-3.5-turbo": 16_385,
+		"gpt-4-turbo":   128_000,
+		"gpt-4o":        128_000,
+		"gpt-4.1":       1_047_576,
+		"gpt-5":         400_000,
+		"o1":            200_000,
+		"o3":            200_000,
+		"o4-mini":       200_000,
+	},
+	"gemini": {
+		"gemini":         1_048_576,
+		"gemini-1.5-pro": 2_097_152,
+	},
+	"cerebras": {
+		"*": 65_536,
+	},
+	"groq": {
+		"llama-3.3-70b-versatile": 131_072,
+		"*":                       32_768,
+	},
+}
+
+// ContextWindow returns the context window in tokens for a provider's model.
+// ai.context_window in the config overrides the table. The second return
+// value is false when the window is unknown.
+func ContextWindow(cfg *config.Config, provider, model string) (int, bool) {
+	if cfg != nil && cfg.AI.ContextWindow > 0 {
+		return cfg.AI.ContextWindow, true
+	}
+
+	// Ollama sizes num_ctx to fit the prompt unless it is pinned explicitly
+	if provider == "ollama" {
+		if cfg != nil {
+			if numCtx, err := strconv.Atoi(cfg.AI.Custom["ollama_num_ctx"]); err == nil && numCtx > 0 {
+				return numCtx, true
+			}
+		}
+		return 0, false
+	}
+
+	if window, ok := matchModel(contextWindows[provider], model); ok {
+		return window, true
+	}
+
+	if alias, ok := pricingAliases[provider]; ok {
+		if window, ok := matchModel(contextWindows[alias], model); ok {
+			return window, true
+		}
+	}
+
+	// OpenRouter style "vendor/model" names use the vendor's table
+	if vendor, name, found := strings.Cut(model, "/"); found {
+		if alias, ok := pricingAliases[vendor]; ok {
+			vendor = alias
+		}
+		if window, ok := matchModel(contextWindows[vendor], name); ok {
+			return window, true
+		}
+	}
+
+	return 0, false
+}
@@ -0,0 +1,167 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/1broseidon/promptext-notes/internal/config"
+)
+
+// ErrBudgetExceeded is returned when a request would exceed ai.max_cost_usd
+var ErrBudgetExceeded = errors.New("cost budget exceeded")
+
+// StageUsage holds aggregated usage for one workflow stage
+type StageUsage struct {
+	Stage        string
+	Provider     string
+	Model        string
+	Requests     int
+	InputTokens  int
+	OutputTokens int
+	TokensUsed   int
+	Cost         float64
+	Unpriced     bool // At least one response had no known price
+	Cached       int  // Requests answered from the response cache
+}
+
+// CostTracker aggregates usage per stage and enforces a total cost budget.
+// It is safe for concurrent use.
+type CostTracker struct {
+	mu         sync.Mutex
+	maxCostUSD float64
+	stages     []*StageUsage
+}
+
+// NewCostTracker creates a tracker; a maxCostUSD of 0 disables the budget
+func NewCostTracker(maxCostUSD float64) *CostTracker {
+	return &CostTracker{maxCostUSD: maxCostUSD}
+}
+
+// CheckBudget returns ErrBudgetExceeded if spending the estimated amount on
+// top of what was already spent would exceed the budget
+func (t *CostTracker) CheckBudget(estimatedCost float64) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.maxCostUSD <= 0 {
+		return nil
+	}
+
+	spent := t.totalLocked()
+	if spent+estimatedCost > t.maxCostUSD {
+		return fmt.Errorf("%w: estimated input cost $%.4f plus $%.4f already spent exceeds max_cost_usd $%.4f",
+			ErrBudgetExceeded, estimatedCost, spent, t.maxCostUSD)
+	}
+
+	return nil
+}
+
+// Record adds a response's usage to the given stage
+func (t *CostTracker) Record(stage string, resp *Response, priced bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var usage *StageUsage
+	for _, s := range t.stages {
+		if s.Stage == stage {
+			usage = s
+			break
+		}
+	}
+	if usage == nil {
+		usage = &StageUsage{Stage: stage, Provider: resp.Provider, Model: resp.Model}
+		t.stages = append(t.stages, usage)
+	}
+
+	usage.Requests++
+	usage.InputTokens += resp.InputTokens
+	usage.OutputTokens += resp.OutputTokens
+	usage.TokensUsed += resp.TokensUsed
+	usage.Cost += resp.CostEstimate
+	if resp.Cached {
+		usage.Cached++
+	} else if !priced {
+		usage.Unpriced = true
+	}
+}
+
+// Stages returns a copy of the per-stage usage in the order stages first ran
+func (t *CostTracker) Stages() []StageUsage {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	stages := make([]StageUsage, len(t.stages))
+	for i, s := range t.stages {
+		stages[i] = *s
+	}
+	return stages
+}
+
+// TotalCost returns the cost across all stages
+func (t *CostTracker) TotalCost() float64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.totalLocked()
+}
+
+// totalLocked sums stage costs; the caller must hold the lock
+func (t *CostTracker) totalLocked() float64 {
+	total := 0.0
+	for _, s := range t.stages {
+		total += s.Cost
+	}
+	return total
+}
+
+// meteredProvider wraps a Provider to enforce the budget and record usage
+type meteredProvider struct {
+	Provider
+	tracker *CostTracker
+	pricing *PricingTable
+	stage   string
+}
+
+// WithCostTracking wraps a provider so every request is checked against the
+// tracker's budget before sending and its usage is recorded under stage
+func WithCostTracking(provider Provider, tracker *CostTracker, stage string, cfg *config.Config) Provider {
+	if tracker == nil {
+		return provider
+	}
+
+	return &meteredProvider{
+		Provider: provider,
+		tracker:  tracker,
+		pricing:  NewPricingTable(cfg),
+		stage:    stage,
+	}
+}
+
+// Generate checks the budget with the estimated input cost (unless the
+// response is cached), then records usage
+func (m *meteredProvider) Generate(ctx context.Context, req *Request) (*Response, error) {
+	// Cached responses are free, so only requests that will be sent count
+	if cache, ok := m.Provider.(*cachedProvider); !ok || !cache.Has(req) {
+		inputTokens := CountTokens(req.SystemPrompt) + CountTokens(req.Prompt)
+		inputCost, _ := m.pricing.Cost(m.Name(), req.Model, inputTokens, 0)
+
+		if err := m.tracker.CheckBudget(inputCost); err != nil {
+			return nil, err
+		}
+	}
+
+	resp, err := m.Provider.Generate(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	model := resp.Model
+	if model == "" {
+		model = req.Model
+	}
+	_, priced := m.pricing.Lookup(m.Name(), model)
+	m.tracker.Record(m.stage, resp, priced)
+
+	return resp, nil
+}
@@ -0,0 +1,77 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/1broseidon/promptext-notes/internal/config"
+)
+
+// stubProvider returns a fixed response for every request
+type stubProvider struct {
+	name  string
+	resp  *Response
+	calls int
+}
+
+func (p *stubProvider) Generate(ctx context.Context, req *Request) (*Response, error) {
+	p.calls++
+	return p.resp, nil
+}
+
+func (p *stubProvider) Name() string          { return p.name }
+func (p *stubProvider) ValidateConfig() error { return nil }
+func (p *stubProvider) NewRequest(prompt string) *Request {
+	return &Request{Prompt: prompt, Model: p.resp.Model}
+}
+
+func TestCostTrackerAggregatesStages(t *testing.T) {
+	tracker := NewCostTracker(0)
+	cfg := config.Default()
+
+	discovery := WithCostTracking(&stubProvider{name: "openai", resp: &Response{
+		Provider: "openai", Model: "gpt-4o", InputTokens: 1000, OutputTokens: 100, TokensUsed: 1100, CostEstimate: 0.02,
+	}}, tracker, "discovery", cfg)
+	polish := WithCostTracking(&stubProvider{name: "anthropic", resp: &Response{
+		Provider: "anthropic", Model: "claude-haiku-4-5", InputTokens: 500, OutputTokens: 50, TokensUsed: 550, CostEstimate: 0.01,
+	}}, tracker, "polish", cfg)
+
+	for _, p := range []Provider{discovery, discovery, polish} {
+		if _, err := p.Generate(context.Background(), p.NewRequest("prompt")); err != nil {
+			t.Fatalf("Generate() error = %v", err)
+		}
+	}
+
+	stages := tracker.Stages()
+	if len(stages) != 2 {
+		t.Fatalf("Expected 2 stages, got %d", len(stages))
+	}
+	if stages[0].Stage != "discovery" || stages[0].Requests != 2 || stages[0].TokensUsed != 2200 {
+		t.Errorf("Unexpected discovery usage: %+v", stages[0])
+	}
+	if stages[1].Stage != "polish" || stages[1].Unpriced {
+		t.Errorf("Unexpected polish usage: %+v", stages[1])
+	}
+	if total := tracker.TotalCost(); total < 0.0499 || total > 0.0501 {
+		t.Errorf("Expected total cost $0.05, got $%.4f", total)
+	}
+}
+
+func TestCostTrackerBudget(t *testing.T) {
+	tracker := NewCostTracker(0.01)
+	stub := &stubProvider{name: "openai", resp: &Response{Model: "gpt-4o"}}
+	provider := WithCostTracking(stub, tracker, "discovery", config.Default())
+
+	// ~20k tokens of input at $2.50/M is above the $0.01 budget
+	req := &Request{Prompt: strings.Repeat("release notes ", 10_000), Model: "gpt-4o"}
+
+	_, err := provider.Generate(context.Background(), req)
+	if !errors.Is(err, ErrBudgetExceeded) {
+		t.Fatalf("Expected ErrBudgetExceeded, got %v", err)
+	}
+	if stub.calls != 0 {
+		t.Error("Request should not be sent when the budget would be exceeded")
+	}
+}
@@ -0,0 +1,147 @@
+package ai
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/1broseidon/promptext-notes/internal/config"
+)
+
+// maxStderr caps how much of the command's stderr is included in errors
+const maxStderr = 2000
+
+// ExecProvider implements the Provider interface by running an external
+// command: the Request is written to its stdin as JSON and a Response is read
+// as JSON from its stdout
+type ExecProvider struct {
+	command []string
+	config  *config.Config
+	pricing *PricingTable
+}
+
+// NewExecProvider creates a provider that runs ai.exec.command
+func NewExecProvider(cfg *config.Config) (*ExecProvider, error) {
+	if len(cfg.AI.Exec.Command) == 0 || cfg.AI.Exec.Command[0] == "" {
+		return nil, fmt.Errorf("exec command is required")
+	}
+
+	return &ExecProvider{
+		command: cfg.AI.Exec.Command,
+		config:  cfg,
+		pricing: NewPricingTable(cfg),
+	}, nil
+}
+
+// Name returns the provider name
+func (p *ExecProvider) Name() string {
+	return "exec"
+}
+
+// ValidateConfig checks if the configuration is valid
+func (p *ExecProvider) ValidateConfig() error {
+	if _, err := exec.LookPath(p.command[0]); err != nil {
+		return fmt.Errorf("exec command not found: %w", err)
+	}
+
+	return nil
+}
+
+// NewRequest creates a request from a prompt using provider's configured defaults
+func (p *ExecProvider) NewRequest(prompt string) *Request {
+	return RequestFromConfig(p.config, prompt)
+}
+
+// Generate runs the command with the request and returns its response
+func (p *ExecProvider) Generate(ctx context.Context, req *Request) (*Response, error) {
+	if err := p.ValidateConfig(); err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+
+	var response *Response
+	var generateErr error
+
+	// Use retry with backoff
+	err := RetryWithBackoff(ctx, p.config, func(ctx context.Context) error {
+		response, generateErr = p.generateOnce(ctx, req)
+		return generateErr
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
+
+// generateOnce runs the command once; it is killed when the context is done
+// or ai.timeout passes
+func (p *ExecProvider) generateOnce(ctx context.Context, req *Request) (*Response, error) {
+	input, err := json.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	if p.config.AI.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.config.AI.Timeout)
+		defer cancel()
+	}
+
+	cmd := exec.CommandContext(ctx, p.command[0], p.command[1:]...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	// Don't wait on children of a killed script that still hold its output open
+	cmd.WaitDelay = time.Second
+
+	if err := cmd.Run(); err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			err = ctxErr
+		}
+		return nil, fmt.Errorf("exec command %s failed: %w%s", p.command[0], err, stderrSuffix(stderr.String()))
+	}
+
+	var resp Response
+	if err := json.Unmarshal(stdout.Bytes(), &resp); err != nil {
+		return nil, fmt.Errorf("failed to parse exec command response: %w%s", err, stderrSuffix(stderr.String()))
+	}
+	if resp.Content == "" {
+		return nil, errors.New("exec command returned no content")
+	}
+
+	// Fill in what the command didn't report
+	resp.Provider = "exec"
+	if resp.Model == "" {
+		resp.Model = req.Model
+	}
+	if resp.TokensUsed == 0 {
+		resp.TokensUsed = resp.InputTokens + resp.OutputTokens
+	}
+	if resp.CostEstimate == 0 {
+		resp.CostEstimate, _ = p.pricing.Cost("exec", resp.Model, resp.InputTokens, resp.OutputTokens)
+	}
+	if resp.StopReason == "" {
+		resp.StopReason = StopReasonUnknown
+	}
+
+	return &resp, nil
+}
+
+// stderrSuffix formats the command's stderr for an error message
+func stderrSuffix(stderr string) string {
+	stderr = strings.TrimSpace(stderr)
+	if stderr == "" {
+		return ""
+	}
+	if len(stderr) > maxStderr {
+		stderr = "..." + stderr[len(stderr)-maxStderr:]
+	}
+	return ": " + stderr
+}
@@ -0,0 +1,113 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/1broseidon/promptext-notes/internal/config"
+)
+
+// TestExecHelperProcess is the command run by the exec provider tests, not a
+// real test
+func TestExecHelperProcess(t *testing.T) {
+	if os.Getenv("PROMPTEXT_EXEC_HELPER") != "1" {
+		return
+	}
+	defer os.Exit(0)
+
+	mode := os.Args[len(os.Args)-1]
+	var req Request
+	if err := json.NewDecoder(os.Stdin).Decode(&req); err != nil {
+		fmt.Fprintf(os.Stderr, "bad request: %v", err)
+		os.Exit(1)
+	}
+
+	switch mode {
+	case "fail":
+		fmt.Fprint(os.Stderr, "gateway: authentication failed")
+		os.Exit(3)
+	case "sleep":
+		time.Sleep(10 * time.Second)
+	}
+
+	json.NewEncoder(os.Stdout).Encode(Response{
+		Content:      "### Added\n- " + req.Prompt,
+		InputTokens:  req.MaxTokens,
+		OutputTokens: 5,
+		StopReason:   StopReasonComplete,
+		Metadata:     map[string]interface{}{"system": req.SystemPrompt},
+	})
+}
+
+// newTestExecProvider runs this test binary as the exec command in mode
+func newTestExecProvider(t *testing.T, mode string) *ExecProvider {
+	t.Helper()
+	t.Setenv("PROMPTEXT_EXEC_HELPER", "1")
+
+	cfg := config.Default()
+	cfg.AI.Provider = "exec"
+	cfg.AI.Model = "internal-gateway"
+	cfg.AI.MaxTokens = 100
+	cfg.AI.Retry.Attempts = 1
+	cfg.AI.Exec.Command = []string{os.Args[0], "-test.run=TestExecHelperProcess", "--", mode}
+
+	provider, err := NewExecProvider(cfg)
+	if err != nil {
+		t.Fatalf("NewExecProvider() error = %v", err)
+	}
+	return provider
+}
+
+func TestExecGenerate(t *testing.T) {
+	provider := newTestExecProvider(t, "ok")
+
+	req := provider.NewRequest("Export command")
+	req.SystemPrompt = "You are a technical writer"
+	resp, err := provider.Generate(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+
+	if resp.Content != "### Added\n- Export command" {
+		t.Errorf("Unexpected content: %q", resp.Content)
+	}
+	if resp.Metadata["system"] != "You are a technical writer" {
+		t.Errorf("Expected the system prompt on stdin, got %v", resp.Metadata["system"])
+	}
+	if resp.Provider != "exec" || resp.Model != "internal-gateway" {
+		t.Errorf("Expected provider exec and the request model, got %s/%s", resp.Provider, resp.Model)
+	}
+	if resp.InputTokens != 100 || resp.TokensUsed != 105 {
+		t.Errorf("Expected 100 input and 105 total tokens, got %d and %d", resp.InputTokens, resp.TokensUsed)
+	}
+}
+
+func TestExecGenerateSurfacesStderr(t *testing.T) {
+	provider := newTestExecProvider(t, "fail")
+
+	_, err := provider.Generate(context.Background(), provider.NewRequest("prompt"))
+	if err == nil || !strings.Contains(err.Error(), "gateway: authentication failed") {
+		t.Errorf("Expected the command's stderr in the error, got %v", err)
+	}
+}
+
+func TestExecGenerateHonorsContext(t *testing.T) {
+	provider := newTestExecProvider(t, "sleep")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := provider.Generate(ctx, provider.NewRequest("prompt"))
+	if err == nil || !strings.Contains(err.Error(), context.DeadlineExceeded.Error()) {
+		t.Errorf("Expected a deadline error, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Expected the command to be killed at the deadline, took %s", elapsed)
+	}
+}
@@ -0,0 +1,340 @@
+package ai
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/1broseidon/promptext-notes/internal/config"
+)
+
+// ErrFixtureNotFound is returned by the replay provider for a request that
+// was never recorded
+var ErrFixtureNotFound = errors.New("no recorded response for request")
+
+// scrubbed replaces credentials in recorded fixtures
+const scrubbed = "REDACTED"
+
+// sensitiveHeaders and sensitiveParams carry API keys in provider requests
+var (
+	sensitiveHeaders = []string{"Authorization", "X-Api-Key", "Api-Key", "X-Goog-Api-Key", "Cookie", "Set-Cookie"}
+	sensitiveParams  = []string{"key", "api_key", "api-key"}
+)
+
+// Fixture is one recorded HTTP exchange with a provider
+type Fixture struct {
+	Request  FixtureRequest  `json:"request"`
+	Response FixtureResponse `json:"response"`
+}
+
+// FixtureRequest is a recorded request with its credentials scrubbed
+type FixtureRequest struct {
+	Method string          `json:"method"`
+	URL    string          `json:"url"`
+	Header http.Header     `json:"header,omitempty"`
+	Body   json.RawMessage `json:"body,omitempty"`
+}
+
+// FixtureResponse is a recorded response
+type FixtureResponse struct {
+	Status int             `json:"status"`
+	Header http.Header     `json:"header,omitempty"`
+	Body   json.RawMessage `json:"body,omitempty"`
+}
+
+// Key hashes the method, URL and body of the request; JSON bodies are
+// compacted with sorted keys first, so hand-edited fixtures still match
+func (r *FixtureRequest) Key() string {
+	sum := sha256.Sum256([]byte(r.Method + " " + r.URL + "\n" + string(canonicalJSON(r.Body))))
+	return hex.EncodeToString(sum[:])
+}
+
+// newFixtureRequest records an HTTP request, scrubbing API keys from its
+// headers and query
+func newFixtureRequest(req *http.Request, body []byte) FixtureRequest {
+	u := *req.URL
+	query := u.Query()
+	for _, param := range sensitiveParams {
+		if query.Has(param) {
+			query.Set(param, scrubbed)
+		}
+	}
+	u.RawQuery = query.Encode()
+
+	return FixtureRequest{
+		Method: req.Method,
+		URL:    u.String(),
+		Header: scrubHeader(req.Header),
+		Body:   encodeBody(body),
+	}
+}
+
+// scrubHeader copies a header with credential values replaced
+func scrubHeader(header http.Header) http.Header {
+	clean := header.Clone()
+	for _, name := range sensitiveHeaders {
+		if clean.Get(name) != "" {
+			clean.Set(name, scrubbed)
+		}
+	}
+	return clean
+}
+
+// encodeBody stores JSON bodies as JSON and anything else as a JSON string
+func encodeBody(body []byte) json.RawMessage {
+	if len(body) == 0 {
+		return nil
+	}
+	if json.Valid(body) {
+		return json.RawMessage(body)
+	}
+	quoted, _ := json.Marshal(string(body))
+	return quoted
+}
+
+// decodeBody reverses encodeBody
+func decodeBody(body json.RawMessage) []byte {
+	var text string
+	if len(body) > 0 && body[0] == '"' && json.Unmarshal(body, &text) == nil {
+		return []byte(text)
+	}
+	return body
+}
+
+// canonicalJSON compacts a JSON value with sorted object keys
+func canonicalJSON(body json.RawMessage) []byte {
+	var value interface{}
+	if err := json.Unmarshal(body, &value); err != nil {
+		return body
+	}
+	canonical, err := json.Marshal(value)
+	if err != nil {
+		return body
+	}
+	return canonical
+}
+
+// newHTTPClient creates a provider's HTTP client: requests time out after
+// ai.timeout, and are served from fixtures for the replay provider or
+// recorded to fixtures when ai.replay.record is set
+func newHTTPClient(cfg *config.Config) *http.Client {
+	client := &http.Client{Timeout: cfg.AI.Timeout}
+	switch {
+	case cfg.AI.Provider == "replay":
+		client.Transport = NewReplayTransport(cfg.AI.Replay.Dir)
+	case cfg.AI.Replay.Record:
+		client.Transport = NewRecordingTransport(http.DefaultTransport, cfg.AI.Replay.Dir)
+	}
+	return client
+}
+
+// RecordingTransport is an http.RoundTripper that writes every exchange to
+// a fixture file in its directory
+type RecordingTransport struct {
+	base http.RoundTripper
+	dir  string
+	mu   sync.Mutex
+}
+
+// NewRecordingTransport records the exchanges of base to fixtures in dir
+func NewRecordingTransport(base http.RoundTripper, dir string) *RecordingTransport {
+	return &RecordingTransport{base: base, dir: dir}
+}
+
+// RoundTrip sends the request with the base transport and records it
+func (t *RecordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		if body, err = io.ReadAll(req.Body); err != nil {
+			return nil, err
+		}
+		req.Body.Close()
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	respBody, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(respBody))
+
+	fixture := Fixture{
+		Request: newFixtureRequest(req, body),
+		Response: FixtureResponse{
+			Status: resp.StatusCode,
+			Header: scrubHeader(resp.Header),
+			Body:   encodeBody(respBody),
+		},
+	}
+	if err := t.write(&fixture); err != nil {
+		return nil, fmt.Errorf("failed to record fixture: %w", err)
+	}
+	return resp, nil
+}
+
+// write stores a fixture as <host>-<key>.json
+func (t *RecordingTransport) write(fixture *Fixture) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if err := os.MkdirAll(t.dir, 0o755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(fixture, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	host := "request"
+	if u, err := url.Parse(fixture.Request.URL); err == nil && u.Host != "" {
+		host = strings.NewReplacer(":", "_").Replace(u.Host)
+	}
+	name := fmt.Sprintf("%s-%s.json", host, fixture.Request.Key()[:16])
+	return os.WriteFile(filepath.Join(t.dir, name), append(data, '\n'), 0o644)
+}
+
+// ReplayTransport is an http.RoundTripper that answers requests from the
+// fixtures in its directory and fails on requests it has no fixture for
+type ReplayTransport struct {
+	dir      string
+	once     sync.Once
+	fixtures map[string]*Fixture
+	err      error
+}
+
+// NewReplayTransport serves the fixtures in dir; files are read on the first
+// request and matched by request key, whatever their names
+func NewReplayTransport(dir string) *ReplayTransport {
+	return &ReplayTransport{dir: dir}
+}
+
+// RoundTrip returns the recorded response of the request
+func (t *ReplayTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.once.Do(t.load)
+	if t.err != nil {
+		return nil, t.err
+	}
+
+	var body []byte
+	if req.Body != nil {
+		var err error
+		if body, err = io.ReadAll(req.Body); err != nil {
+			return nil, err
+		}
+		req.Body.Close()
+	}
+
+	recorded := newFixtureRequest(req, body)
+	fixture, ok := t.fixtures[recorded.Key()]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s %s in %s", ErrFixtureNotFound, recorded.Method, recorded.URL, t.dir)
+	}
+
+	header := fixture.Response.Header.Clone()
+	if header == nil {
+		header = http.Header{}
+	}
+	respBody := decodeBody(fixture.Response.Body)
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", fixture.Response.Status, http.StatusText(fixture.Response.Status)),
+		StatusCode:    fixture.Response.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(respBody)),
+		ContentLength: int64(len(respBody)),
+		Request:       req,
+	}, nil
+}
+
+// load indexes the fixtures of the directory by request key
+func (t *ReplayTransport) load() {
+	paths, err := filepath.Glob(filepath.Join(t.dir, "*.json"))
+	if err != nil {
+		t.err = fmt.Errorf("failed to read fixtures: %w", err)
+		return
+	}
+	sort.Strings(paths)
+
+	t.fixtures = make(map[string]*Fixture, len(paths))
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.err = fmt.Errorf("failed to read fixture: %w", err)
+			return
+		}
+		var fixture Fixture
+		if err := json.Unmarshal(data, &fixture); err != nil {
+			t.err = fmt.Errorf("failed to parse fixture %s: %w", path, err)
+			return
+		}
+		t.fixtures[fixture.Request.Key()] = &fixture
+	}
+}
+
+// replayProvider serves a provider's recorded responses: it is that provider
+// with its HTTP requests answered from fixtures
+type replayProvider struct {
+	Provider
+}
+
+// NewReplayProvider creates the replay provider for ai.replay.provider, the
+// provider the fixtures in ai.replay.dir were recorded with. No API key is
+// needed and requests without a fixture fail with ErrFixtureNotFound.
+func NewReplayProvider(cfg *config.Config) (Provider, error) {
+	replayCfg := *cfg
+	replayCfg.AI.Provider = "replay"
+
+	var provider Provider
+	var err error
+	switch cfg.AI.Replay.Provider {
+	case "anthropic":
+		provider, err = NewAnthropicProvider(scrubbed, &replayCfg)
+	case "openai":
+		provider, err = NewOpenAIProvider(scrubbed, &replayCfg)
+	case "cerebras":
+		provider, err = NewCerebrasProvider(scrubbed, &replayCfg)
+	case "groq":
+		provider, err = NewGroqProvider(scrubbed, &replayCfg)
+	case "openrouter":
+		provider, err = NewOpenRouterProvider(scrubbed, &replayCfg)
+	case "gemini":
+		provider, err = NewGeminiProvider(scrubbed, &replayCfg)
+	case "azure-openai":
+		provider, err = NewAzureOpenAIProvider(scrubbed, &replayCfg)
+	case "ollama":
+		provider, err = NewOllamaProvider(&replayCfg)
+	case "":
+		return nil, fmt.Errorf("the replay provider requires replay.provider")
+	default:
+		return nil, fmt.Errorf("unsupported replay provider: %s", cfg.AI.Replay.Provider)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &replayProvider{Provider: provider}, nil
+}
+
+// Name returns the provider name
+func (p *replayProvider) Name() string {
+	return "replay"
+}
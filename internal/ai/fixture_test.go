@@ -0,0 +1,144 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/1broseidon/promptext-notes/internal/config"
+)
+
+// replayConfig configures the replay provider to serve the fixtures in
+// testdata/fixtures recorded with the given provider and its default model
+func replayConfig(provider string) *config.Config {
+	cfg := config.Default()
+	cfg.AI.Provider = "replay"
+	cfg.AI.Model = map[string]string{
+		"anthropic":    "claude-haiku-4-5",
+		"openai":       "gpt-4o-mini",
+		"cerebras":     "zai-glm-4.6",
+		"groq":         "llama-3.3-70b-versatile",
+		"openrouter":   "anthropic/claude-sonnet-4.5",
+		"gemini":       "gemini-2.5-flash",
+		"azure-openai": "gpt-4o-mini",
+		"ollama":       "llama3.2",
+	}[provider]
+	cfg.AI.APIKeyEnv = ""
+	cfg.AI.Custom["azure_resource"] = "contoso"
+	cfg.AI.Retry.Attempts = 1
+	cfg.AI.Cache.Mode = "off"
+	cfg.AI.Replay.Provider = provider
+	cfg.AI.Replay.Dir = "testdata/fixtures"
+	return cfg
+}
+
+// fixtureRequest is the request the fixtures were recorded with
+func fixtureRequest(provider Provider) *Request {
+	req := provider.NewRequest("Write release notes for:\n- feat: add export command")
+	req.SystemPrompt = "You are a technical writer"
+	return req
+}
+
+func TestReplayProviderWireFormats(t *testing.T) {
+	for _, name := range []string{"anthropic", "openai", "cerebras", "groq", "openrouter", "gemini", "azure-openai", "ollama"} {
+		t.Run(name, func(t *testing.T) {
+			provider, err := NewProvider(replayConfig(name))
+			if err != nil {
+				t.Fatalf("NewProvider() error = %v", err)
+			}
+			if provider.Name() != "replay" {
+				t.Errorf("Expected provider name replay, got %s", provider.Name())
+			}
+
+			resp, err := provider.Generate(context.Background(), fixtureRequest(provider))
+			if err != nil {
+				t.Fatalf("Generate() error = %v", err)
+			}
+
+			if resp.Content != "### Added\n- **Export** - New export command" {
+				t.Errorf("Unexpected content: %q", resp.Content)
+			}
+			if resp.Provider != name {
+				t.Errorf("Expected provider %s, got %s", name, resp.Provider)
+			}
+			if resp.InputTokens != 42 || resp.OutputTokens != 12 || resp.TokensUsed != 54 {
+				t.Errorf("Unexpected usage: %d in / %d out / %d total", resp.InputTokens, resp.OutputTokens, resp.TokensUsed)
+			}
+			if resp.StopReason != StopReasonComplete {
+				t.Errorf("Expected stop reason %s, got %s", StopReasonComplete, resp.StopReason)
+			}
+		})
+	}
+}
+
+func TestReplayProviderMiss(t *testing.T) {
+	provider, err := NewProvider(replayConfig("openai"))
+	if err != nil {
+		t.Fatalf("NewProvider() error = %v", err)
+	}
+
+	req := fixtureRequest(provider)
+	req.Prompt += "\n- fix: crash on start"
+	if _, err := provider.Generate(context.Background(), req); !errors.Is(err, ErrFixtureNotFound) {
+		t.Errorf("Expected ErrFixtureNotFound, got %v", err)
+	}
+}
+
+func TestRecordingScrubsAPIKeys(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"candidates":[{"content":{"parts":[{"text":"### Fixed\n- Crash on start"}]},"finishReason":"STOP"}],"usageMetadata":{"promptTokenCount":10,"candidatesTokenCount":5,"totalTokenCount":15}}`))
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	cfg := config.Default()
+	cfg.AI.Provider = "gemini"
+	cfg.AI.Model = "gemini-2.5-flash"
+	cfg.AI.Custom["gemini_url"] = server.URL
+	cfg.AI.Retry.Attempts = 1
+	cfg.AI.Replay.Record = true
+	cfg.AI.Replay.Dir = dir
+
+	recorder, err := NewGeminiProvider("secret-gemini-key", cfg)
+	if err != nil {
+		t.Fatalf("NewGeminiProvider() error = %v", err)
+	}
+	if _, err := recorder.Generate(context.Background(), recorder.NewRequest("prompt")); err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*.json"))
+	if len(files) != 1 {
+		t.Fatalf("Expected 1 fixture, got %d", len(files))
+	}
+	data, err := os.ReadFile(files[0])
+	if err != nil {
+		t.Fatalf("Failed to read fixture: %v", err)
+	}
+	if strings.Contains(string(data), "secret-gemini-key") || !strings.Contains(string(data), scrubbed) {
+		t.Errorf("Expected the API key to be scrubbed, got:\n%s", data)
+	}
+
+	cfg.AI.Provider = "replay"
+	cfg.AI.Replay.Record = false
+	cfg.AI.Replay.Provider = "gemini"
+	replay, err := NewReplayProvider(cfg)
+	if err != nil {
+		t.Fatalf("NewReplayProvider() error = %v", err)
+	}
+	resp, err := replay.Generate(context.Background(), replay.NewRequest("prompt"))
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	if resp.Content != "### Fixed\n- Crash on start" || requests != 1 {
+		t.Errorf("Expected the recorded response without a new request, got %q after %d request(s)", resp.Content, requests)
+	}
+}
@@ -0,0 +1,299 @@
+package ai
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/1broseidon/promptext-notes/internal/config"
+)
+
+const geminiAPIURL = "https://generativelanguage.googleapis.com/v1beta"
+
+// GeminiProvider implements the Provider interface for Google Gemini
+type GeminiProvider struct {
+	apiKey     string
+	config     *config.Config
+	httpClient *http.Client
+	pricing    *PricingTable
+	baseURL    string
+}
+
+// geminiRequest represents the Gemini generateContent request format
+type geminiRequest struct {
+	Contents          []geminiContent        `json:"contents"`
+	SystemInstruction *geminiContent         `json:"systemInstruction,omitempty"`
+	GenerationConfig  geminiGenerationConfig `json:"generationConfig"`
+}
+
+// geminiContent represents a message made of parts
+type geminiContent struct {
+	Role  string       `json:"role,omitempty"`
+	Parts []geminiPart `json:"parts"`
+}
+
+// geminiPart represents a single part of a message
+type geminiPart struct {
+	Text    string `json:"text"`
+	Thought bool   `json:"thought,omitempty"`
+}
+
+// geminiGenerationConfig represents generation parameters
+type geminiGenerationConfig struct {
+	Temperature     float64 `json:"temperature"`
+	MaxOutputTokens int     `json:"maxOutputTokens,omitempty"`
+
+	// ResponseMimeType is application/json for JSON mode; the schema itself is
+	// given in the prompt, since responseSchema only takes an OpenAPI subset
+	ResponseMimeType string `json:"responseMimeType,omitempty"`
+}
+
+// geminiResponse represents the Gemini generateContent response format
+type geminiResponse struct {
+	Candidates     []geminiCandidate    `json:"candidates"`
+	PromptFeedback geminiPromptFeedback `json:"promptFeedback"`
+	UsageMetadata  geminiUsage          `json:"usageMetadata"`
+	ModelVersion   string               `json:"modelVersion"`
+	ResponseID     string               `json:"responseId"`
+}
+
+// geminiCandidate represents a generated candidate
+type geminiCandidate struct {
+	Content      geminiContent `json:"content"`
+	FinishReason string        `json:"finishReason"`
+}
+
+// geminiPromptFeedback reports whether the prompt itself was blocked
+type geminiPromptFeedback struct {
+	BlockReason string `json:"blockReason"`
+}
+
+// geminiUsage represents token usage information
+type geminiUsage struct {
+	PromptTokenCount     int `json:"promptTokenCount"`
+	CandidatesTokenCount int `json:"candidatesTokenCount"`
+	ThoughtsTokenCount   int `json:"thoughtsTokenCount"`
+	TotalTokenCount      int `json:"totalTokenCount"`
+}
+
+// geminiError represents an error response from Gemini
+type geminiError struct {
+	Error struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+		Status  string `json:"status"`
+	} `json:"error"`
+}
+
+// NewGeminiProvider creates a new Google Gemini provider
+func NewGeminiProvider(apiKey string, cfg *config.Config) (*GeminiProvider, error) {
+	if apiKey == "" {
+		return nil, fmt.Errorf("gemini API key is required")
+	}
+
+	// Get base URL from config or use default
+	baseURL := geminiAPIURL
+	if url, ok := cfg.AI.Custom["gemini_url"]; ok {
+		baseURL = url
+	}
+
+	return &GeminiProvider{
+		apiKey:     apiKey,
+		config:     cfg,
+		baseURL:    baseURL,
+		pricing:    NewPricingTable(cfg),
+		httpClient: newHTTPClient(cfg),
+	}, nil
+}
+
+// Name returns the provider name
+func (p *GeminiProvider) Name() string {
+	return "gemini"
+}
+
+// ValidateConfig checks if the configuration is valid
+func (p *GeminiProvider) ValidateConfig() error {
+	if p.apiKey == "" {
+		return fmt.Errorf("gemini API key is not set")
+	}
+
+	if p.config.AI.Model == "" {
+		return fmt.Errorf("gemini model is not specified")
+	}
+
+	return nil
+}
+
+// NewRequest creates a request from a prompt using provider's configured defaults
+func (p *GeminiProvider) NewRequest(prompt string) *Request {
+	return &Request{
+		Prompt:      prompt,
+		Model:       p.config.AI.Model,
+		MaxTokens:   p.config.AI.MaxTokens,
+		Temperature: p.config.AI.Temperature,
+	}
+}
+
+// Generate sends a request to Gemini and returns the response
+func (p *GeminiProvider) Generate(ctx context.Context, req *Request) (*Response, error) {
+	if err := p.ValidateConfig(); err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+
+	var response *Response
+	var generateErr error
+
+	// Use retry with backoff
+	err := RetryWithBackoff(ctx, p.config, func(ctx context.Context) error {
+		response, generateErr = p.generateOnce(ctx, req)
+		return generateErr
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
+
+// generateOnce performs a single generation attempt
+func (p *GeminiProvider) generateOnce(ctx context.Context, req *Request) (*Response, error) {
+	// Build request payload
+	apiReq := geminiRequest{
+		Contents: []geminiContent{
+			{
+				Role:  "user",
+				Parts: []geminiPart{{Text: req.Prompt}},
+			},
+		},
+		GenerationConfig: geminiGenerationConfig{
+			Temperature:     req.Temperature,
+			MaxOutputTokens: req.MaxTokens,
+		},
+	}
+
+	if req.JSONSchema != nil {
+		apiReq.GenerationConfig.ResponseMimeType = "application/json"
+	}
+
+	// Add system instruction if provided
+	if req.SystemPrompt != "" {
+		apiReq.SystemInstruction = &geminiContent{
+			Parts: []geminiPart{{Text: req.SystemPrompt}},
+		}
+	}
+
+	// Marshal request to JSON
+	jsonData, err := json.Marshal(apiReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	// Create HTTP request (model name passed through as-is from config)
+	url := fmt.Sprintf("%s/models/%s:generateContent", p.baseURL, req.Model)
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	// Set headers
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("x-goog-api-key", p.apiKey)
+
+	// Send request
+	httpResp, err := p.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer httpResp.Body.Close()
+
+	// Read response body
+	body, err := io.ReadAll(httpResp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	// Handle non-200 responses
+	if httpResp.StatusCode != http.StatusOK {
+		var apiErr geminiError
+		if err := json.Unmarshal(body, &apiErr); err != nil || apiErr.Error.Message == "" {
+			return nil, fmt.Errorf("gemini API error (status %d): %s", httpResp.StatusCode, string(body))
+		}
+		return nil, fmt.Errorf("gemini API error: %s", apiErr.Error.Message)
+	}
+
+	// Parse response
+	var apiResp geminiResponse
+	if err := json.Unmarshal(body, &apiResp); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	// The whole prompt was blocked by safety settings
+	if apiResp.PromptFeedback.BlockReason != "" {
+		return nil, &ContentFilterError{Provider: "gemini", Reason: "prompt blocked: " + apiResp.PromptFeedback.BlockReason}
+	}
+
+	if len(apiResp.Candidates) == 0 {
+		return nil, fmt.Errorf("no candidates in response")
+	}
+
+	candidate := apiResp.Candidates[0]
+	stopReason := normalizeGeminiFinishReason(candidate.FinishReason)
+	if stopReason == StopReasonContentFilter {
+		return nil, &ContentFilterError{Provider: "gemini", Reason: "response blocked: " + candidate.FinishReason}
+	}
+
+	// Extract text from parts (thought summaries are intentionally skipped)
+	var content strings.Builder
+	for _, part := range candidate.Content.Parts {
+		if !part.Thought {
+			content.WriteString(part.Text)
+		}
+	}
+
+	// Thinking tokens are billed as output tokens
+	usage := apiResp.UsageMetadata
+	outputTokens := usage.CandidatesTokenCount + usage.ThoughtsTokenCount
+	costEstimate, _ := p.pricing.Cost("gemini", req.Model, usage.PromptTokenCount, outputTokens)
+
+	model := apiResp.ModelVersion
+	if model == "" {
+		model = req.Model
+	}
+
+	return &Response{
+		Content:      content.String(),
+		TokensUsed:   usage.TotalTokenCount,
+		InputTokens:  usage.PromptTokenCount,
+		OutputTokens: outputTokens,
+		Model:        model,
+		Provider:     "gemini",
+		CostEstimate: costEstimate,
+		StopReason:   stopReason,
+		Metadata: map[string]interface{}{
+			"prompt_tokens":     usage.PromptTokenCount,
+			"completion_tokens": usage.CandidatesTokenCount,
+			"thoughts_tokens":   usage.ThoughtsTokenCount,
+			"finish_reason":     candidate.FinishReason,
+			"id":                apiResp.ResponseID,
+		},
+	}, nil
+}
+
+// normalizeGeminiFinishReason maps Gemini's finishReason to a StopReason
+func normalizeGeminiFinishReason(reason string) string {
+	switch reason {
+	case "STOP":
+		return StopReasonComplete
+	case "MAX_TOKENS":
+		return StopReasonMaxTokens
+	case "SAFETY", "RECITATION", "BLOCKLIST", "PROHIBITED_CONTENT", "SPII", "IMAGE_SAFETY":
+		return StopReasonContentFilter
+	default:
+		return StopReasonUnknown
+	}
+}
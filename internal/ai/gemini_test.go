@@ -0,0 +1,156 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/1broseidon/promptext-notes/internal/config"
+)
+
+// newTestGeminiProvider creates a Gemini provider pointed at a fake server
+func newTestGeminiProvider(t *testing.T, handler http.HandlerFunc) *GeminiProvider {
+	t.Helper()
+
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	cfg := config.Default()
+	cfg.AI.Provider = "gemini"
+	cfg.AI.Model = "gemini-2.5-flash"
+	cfg.AI.Custom["gemini_url"] = server.URL
+	cfg.AI.Retry.Attempts = 1
+
+	provider, err := NewGeminiProvider("test-key", cfg)
+	if err != nil {
+		t.Fatalf("NewGeminiProvider() error = %v", err)
+	}
+	return provider
+}
+
+func TestGeminiGenerate(t *testing.T) {
+	var got geminiRequest
+	provider := newTestGeminiProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/models/gemini-2.5-flash:generateContent" {
+			t.Errorf("Unexpected path: %s", r.URL.Path)
+		}
+		if r.Header.Get("x-goog-api-key") != "test-key" {
+			t.Errorf("Expected API key header, got %q", r.Header.Get("x-goog-api-key"))
+		}
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Fatalf("Failed to decode request: %v", err)
+		}
+		w.Write([]byte(`{
+			"candidates": [{
+				"content": {"role": "model", "parts": [
+					{"text": "Reviewing the diff...", "thought": true},
+					{"text": "### Added\n- Gemini provider"}
+				]},
+				"finishReason": "STOP"
+			}],
+			"usageMetadata": {"promptTokenCount": 1000000, "candidatesTokenCount": 100000, "thoughtsTokenCount": 100000, "totalTokenCount": 1200000},
+			"modelVersion": "gemini-2.5-flash",
+			"responseId": "resp-1"
+		}`))
+	})
+
+	req := provider.NewRequest("Generate release notes")
+	req.SystemPrompt = "You are a technical writer"
+
+	resp, err := provider.Generate(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+
+	if got.SystemInstruction == nil || got.SystemInstruction.Parts[0].Text != "You are a technical writer" {
+		t.Errorf("Expected system instruction, got %+v", got.SystemInstruction)
+	}
+	if got.GenerationConfig.MaxOutputTokens != provider.config.AI.MaxTokens {
+		t.Errorf("Expected maxOutputTokens %d, got %d", provider.config.AI.MaxTokens, got.GenerationConfig.MaxOutputTokens)
+	}
+	if got.GenerationConfig.Temperature != provider.config.AI.Temperature {
+		t.Errorf("Expected temperature %.2f, got %.2f", provider.config.AI.Temperature, got.GenerationConfig.Temperature)
+	}
+
+	if resp.Content != "### Added\n- Gemini provider" {
+		t.Errorf("Unexpected content: %q", resp.Content)
+	}
+	if resp.TokensUsed != 1200000 {
+		t.Errorf("Expected 1200000 tokens, got %d", resp.TokensUsed)
+	}
+	if resp.StopReason != StopReasonComplete {
+		t.Errorf("Expected complete stop reason, got %s", resp.StopReason)
+	}
+
+	// 1M input at $0.30 + 200k output (incl. thoughts) at $2.50
+	if math.Abs(resp.CostEstimate-0.80) > 1e-9 {
+		t.Errorf("Expected cost $0.80, got $%.4f", resp.CostEstimate)
+	}
+}
+
+func TestGeminiSafetyBlock(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{
+		{
+			name: "blocked prompt",
+			body: `{"promptFeedback": {"blockReason": "SAFETY"}}`,
+			want: "prompt blocked: SAFETY",
+		},
+		{
+			name: "blocked candidate",
+			body: `{"candidates": [{"content": {"parts": []}, "finishReason": "SAFETY"}]}`,
+			want: "response blocked: SAFETY",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := newTestGeminiProvider(t, func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte(tt.body))
+			})
+
+			_, err := provider.Generate(context.Background(), provider.NewRequest("prompt"))
+			var filterErr *ContentFilterError
+			if !errors.As(err, &filterErr) {
+				t.Fatalf("Expected ContentFilterError, got %v", err)
+			}
+			if filterErr.Reason != tt.want {
+				t.Errorf("Expected reason %q, got %q", tt.want, filterErr.Reason)
+			}
+		})
+	}
+}
+
+func TestGeminiAPIError(t *testing.T) {
+	provider := newTestGeminiProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error": {"code": 400, "message": "API key not valid", "status": "INVALID_ARGUMENT"}}`))
+	})
+
+	_, err := provider.Generate(context.Background(), provider.NewRequest("prompt"))
+	if err == nil || !strings.Contains(err.Error(), "API key not valid") {
+		t.Errorf("Expected API error message, got %v", err)
+	}
+}
+
+func TestGeminiTruncated(t *testing.T) {
+	provider := newTestGeminiProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"candidates": [{"content": {"parts": [{"text": "### Added\n- Par"}]}, "finishReason": "MAX_TOKENS"}]}`))
+	})
+
+	resp, err := provider.Generate(context.Background(), provider.NewRequest("prompt"))
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	if !resp.Truncated() {
+		t.Error("Expected MAX_TOKENS to be reported as truncated")
+	}
+}
@@ -18,6 +18,7 @@ type GroqProvider struct {
 	apiKey     string
 	config     *config.Config
 	httpClient *http.Client
+	pricing    *PricingTable
 }
 
 // NewGroqProvider creates a new Groq provider
@@ -27,11 +28,10 @@ func NewGroqProvider(apiKey string, cfg *config.Config) (*GroqProvider, error) {
 	}
 
 	return &GroqProvider{
-		apiKey: apiKey,
-		config: cfg,
-		httpClient: &http.Client{
-			Timeout: cfg.AI.Timeout,
-		},
+		apiKey:     apiKey,
+		config:     cfg,
+		pricing:    NewPricingTable(cfg),
+		httpClient: newHTTPClient(cfg),
 	}, nil
 }
 
@@ -110,6 +110,8 @@ func (p *GroqProvider) generateOnce(ctx context.Context, req *Request) (*Respons
 		Messages:    messages,
 		MaxTokens:   req.MaxTokens,
 		Temperature: req.Temperature,
+		// Groq supports json_schema on a few models only; json_object works on all
+		ResponseFormat: openaiResponseFormatFor(req, false),
 	}
 
 	// Marshal request to JSON
@@ -161,14 +163,21 @@ func (p *GroqProvider) generateOnce(ctx context.Context, req *Request) (*Respons
 		return nil, fmt.Errorf("no choices in response")
 	}
 
-	content := apiResp.Choices[0].Message.Content
+	// Drop any inline reasoning so only the answer reaches the changelog
+	content := stripReasoning(apiResp.Choices[0].Message.Content)
+
+	// Calculate cost estimate from the pricing table
+	costEstimate, _ := p.pricing.Cost("groq", apiResp.Model, apiResp.Usage.PromptTokens, apiResp.Usage.CompletionTokens)
 
 	return &Response{
 		Content:      content,
 		TokensUsed:   apiResp.Usage.TotalTokens,
+		InputTokens:  apiResp.Usage.PromptTokens,
+		OutputTokens: apiResp.Usage.CompletionTokens,
 		Model:        apiResp.Model,
 		Provider:     "groq",
-		CostEstimate: 0.0, // Groq offers free tier
+		CostEstimate: costEstimate,
+		StopReason:   normalizeFinishReason(apiResp.Choices[0].FinishReason),
 		Metadata: map[string]interface{}{
 			"prompt_tokens":     apiResp.Usage.PromptTokens,
 			"completion_tokens": apiResp.Usage.CompletionTokens,
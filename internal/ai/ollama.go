@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"strconv"
 
 	"github.com/1broseidon/promptext-notes/internal/config"
 )
@@ -15,22 +16,50 @@ import (
 type OllamaProvider struct {
 	config     *config.Config
 	httpClient *http.Client
+	pricing    *PricingTable
 	baseURL    string
 }
 
-// ollamaRequest represents the Ollama API request format
+// ollamaRequest represents the Ollama chat API request format
 type ollamaRequest struct {
-	Model  string `json:"model"`
-	Prompt string `json:"prompt"`
-	Stream bool   `json:"stream"`
+	Model     string          `json:"model"`
+	Messages  []ollamaMessage `json:"messages"`
+	Stream    bool            `json:"stream"`
+	Options   ollamaOptions   `json:"options"`
+	KeepAlive string          `json:"keep_alive,omitempty"`
+
+	// Format constrains the output to a JSON schema
+	Format map[string]interface{} `json:"format,omitempty"`
 }
 
-// ollamaResponse represents the Ollama API response format
+// ollamaMessage represents a message in the conversation
+type ollamaMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// ollamaOptions represents model generation options
+type ollamaOptions struct {
+	Temperature float64 `json:"temperature"`
+	NumPredict  int     `json:"num_predict,omitempty"`
+	NumCtx      int     `json:"num_ctx,omitempty"`
+}
+
+// ollamaResponse represents the Ollama chat API response format
 type ollamaResponse struct {
-	Model     string `json:"model"`
-	CreatedAt string `json:"created_at"`
-	Response  string `json:"response"`
-	Done      bool   `json:"done"`
+	Model           string        `json:"model"`
+	CreatedAt       string        `json:"created_at"`
+	Message         ollamaMessage `json:"message"`
+	Done            bool          `json:"done"`
+	DoneReason      string        `json:"done_reason"`
+	PromptEvalCount int           `json:"prompt_eval_count"`
+	EvalCount       int           `json:"eval_count"`
+	TotalDuration   int64         `json:"total_duration"`
+}
+
+// ollamaError represents an error response from Ollama
+type ollamaError struct {
+	Error string `json:"error"`
 }
 
 // NewOllamaProvider creates a new Ollama provider
@@ -42,11 +71,10 @@ func NewOllamaProvider(cfg *config.Config) (*OllamaProvider, error) {
 	}
 
 	return &OllamaProvider{
-		config:  cfg,
-		baseURL: baseURL,
-		httpClient: &http.Client{
-			Timeout: cfg.AI.Timeout,
-		},
+		config:     cfg,
+		baseURL:    baseURL,
+		pricing:    NewPricingTable(cfg),
+		httpClient: newHTTPClient(cfg),
 	}, nil
 }
 
@@ -98,17 +126,42 @@ func (p *OllamaProvider) Generate(ctx context.Context, req *Request) (*Response,
 
 // generateOnce performs a single generation attempt
 func (p *OllamaProvider) generateOnce(ctx context.Context, req *Request) (*Response, error) {
-	// Combine system prompt and user prompt
-	prompt := req.Prompt
+	// Build messages array
+	messages := []ollamaMessage{}
+
+	// Add system prompt if provided
 	if req.SystemPrompt != "" {
-		prompt = fmt.Sprintf("System: %s\n\nUser: %s", req.SystemPrompt, req.Prompt)
+		messages = append(messages, ollamaMessage{
+			Role:    "system",
+			Content: req.SystemPrompt,
+		})
 	}
 
+	// Add user prompt
+	messages = append(messages, ollamaMessage{
+		Role:    "user",
+		Content: req.Prompt,
+	})
+
 	// Build request payload
 	apiReq := ollamaRequest{
-		Model:  req.Model,
-		Prompt: prompt,
-		Stream: false,
+		Model:    req.Model,
+		Messages: messages,
+		Stream:   false,
+		Options: ollamaOptions{
+			Temperature: req.Temperature,
+			NumPredict:  req.MaxTokens,
+			NumCtx:      p.contextWindow(req),
+		},
+	}
+
+	if req.JSONSchema != nil {
+		apiReq.Format = req.JSONSchema.Schema
+	}
+
+	// Keep the model loaded between runs (e.g. "10m", "-1" to keep forever)
+	if keepAlive, ok := p.config.AI.Custom["ollama_keep_alive"]; ok {
+		apiReq.KeepAlive = keepAlive
 	}
 
 	// Marshal request to JSON
@@ -118,7 +171,7 @@ func (p *OllamaProvider) generateOnce(ctx context.Context, req *Request) (*Respo
 	}
 
 	// Create HTTP request
-	url := fmt.Sprintf("%s/api/generate", p.baseURL)
+	url := fmt.Sprintf("%s/api/chat", p.baseURL)
 	httpReq, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonData))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
@@ -142,7 +195,11 @@ func (p *OllamaProvider) generateOnce(ctx context.Context, req *Request) (*Respo
 
 	// Handle non-200 responses
 	if httpResp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("ollama API error (status %d): %s", httpResp.StatusCode, string(body))
+		var apiErr ollamaError
+		if err := json.Unmarshal(body, &apiErr); err != nil || apiErr.Error == "" {
+			return nil, fmt.Errorf("ollama API error (status %d): %s", httpResp.StatusCode, string(body))
+		}
+		return nil, fmt.Errorf("ollama API error: %s", apiErr.Error)
 	}
 
 	// Parse response
@@ -151,14 +208,48 @@ func (p *OllamaProvider) generateOnce(ctx context.Context, req *Request) (*Respo
 		return nil, fmt.Errorf("failed to parse response: %w", err)
 	}
 
+	// Local models are free unless priced in ai.pricing
+	costEstimate, _ := p.pricing.Cost("ollama", apiResp.Model, apiResp.PromptEvalCount, apiResp.EvalCount)
+
 	return &Response{
-		Content:      apiResp.Response,
-		TokensUsed:   0, // Ollama doesn't provide token counts in this simple format
+		Content:      stripReasoning(apiResp.Message.Content),
+		TokensUsed:   apiResp.PromptEvalCount + apiResp.EvalCount,
+		InputTokens:  apiResp.PromptEvalCount,
+		OutputTokens: apiResp.EvalCount,
 		Model:        apiResp.Model,
 		Provider:     "ollama",
-		CostEstimate: 0.0, // Local model, no cost
+		CostEstimate: costEstimate,
+		StopReason:   normalizeFinishReason(apiResp.DoneReason),
 		Metadata: map[string]interface{}{
-			"created_at": apiResp.CreatedAt,
+			"prompt_tokens":     apiResp.PromptEvalCount,
+			"completion_tokens": apiResp.EvalCount,
+			"done_reason":       apiResp.DoneReason,
+			"total_duration_ns": apiResp.TotalDuration,
+			"num_ctx":           apiReq.Options.NumCtx,
+			"created_at":        apiResp.CreatedAt,
 		},
 	}, nil
 }
+
+// contextWindow returns the num_ctx to request. An explicit ollama_num_ctx wins;
+// otherwise the window is sized to fit the prompt plus the output budget so
+// Ollama's small default context does not silently truncate the prompt.
+func (p *OllamaProvider) contextWindow(req *Request) int {
+	if value, ok := p.config.AI.Custom["ollama_num_ctx"]; ok {
+		if numCtx, err := strconv.Atoi(value); err == nil && numCtx > 0 {
+			return numCtx
+		}
+	}
+
+	// Rough estimate of ~4 characters per token
+	promptTokens := (len(req.SystemPrompt) + len(req.Prompt)) / 4
+	needed := promptTokens + req.MaxTokens
+
+	// Round up to a multiple of 2048, with a floor of 8192
+	numCtx := ((needed + 2047) / 2048) * 2048
+	if numCtx < 8192 {
+		numCtx = 8192
+	}
+
+	return numCtx
+}
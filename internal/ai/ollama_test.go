@@ -0,0 +1,81 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/1broseidon/promptext-notes/internal/config"
+)
+
+func TestOllamaGenerateUsesChatAPI(t *testing.T) {
+	var got ollamaRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/chat" {
+			t.Errorf("Expected /api/chat, got %s", r.URL.Path)
+		}
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Fatalf("Failed to decode request: %v", err)
+		}
+		w.Write([]byte(`{"model":"llama3.2","message":{"role":"assistant","content":"### Added\n- Item"},"done":true,"done_reason":"length","prompt_eval_count":120,"eval_count":30}`))
+	}))
+	defer server.Close()
+
+	cfg := config.Default()
+	cfg.AI.Provider = "ollama"
+	cfg.AI.Model = "llama3.2"
+	cfg.AI.Custom["ollama_url"] = server.URL
+	cfg.AI.Custom["ollama_keep_alive"] = "10m"
+	cfg.AI.Retry.Attempts = 1
+
+	provider, err := NewOllamaProvider(cfg)
+	if err != nil {
+		t.Fatalf("NewOllamaProvider() error = %v", err)
+	}
+
+	req := provider.NewRequest("Generate release notes")
+	req.SystemPrompt = "You are a technical writer"
+
+	resp, err := provider.Generate(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+
+	if len(got.Messages) != 2 || got.Messages[0].Role != "system" || got.Messages[1].Role != "user" {
+		t.Errorf("Expected system and user messages, got %+v", got.Messages)
+	}
+	if got.Options.NumPredict != cfg.AI.MaxTokens {
+		t.Errorf("Expected num_predict %d, got %d", cfg.AI.MaxTokens, got.Options.NumPredict)
+	}
+	if got.Options.Temperature != cfg.AI.Temperature {
+		t.Errorf("Expected temperature %.2f, got %.2f", cfg.AI.Temperature, got.Options.Temperature)
+	}
+	if got.Options.NumCtx < cfg.AI.MaxTokens {
+		t.Errorf("Expected num_ctx to fit max_tokens, got %d", got.Options.NumCtx)
+	}
+	if got.KeepAlive != "10m" {
+		t.Errorf("Expected keep_alive 10m, got %q", got.KeepAlive)
+	}
+
+	if resp.Content != "### Added\n- Item" {
+		t.Errorf("Unexpected content: %q", resp.Content)
+	}
+	if resp.TokensUsed != 150 {
+		t.Errorf("Expected 150 tokens, got %d", resp.TokensUsed)
+	}
+	if !resp.Truncated() {
+		t.Error("Expected done_reason length to be reported as truncated")
+	}
+}
+
+func TestOllamaContextWindowOverride(t *testing.T) {
+	cfg := config.Default()
+	cfg.AI.Custom["ollama_num_ctx"] = "32768"
+
+	provider, _ := NewOllamaProvider(cfg)
+	if got := provider.contextWindow(&Request{Prompt: "short", MaxTokens: 100}); got != 32768 {
+		t.Errorf("Expected num_ctx 32768, got %d", got)
+	}
+}
@@ -19,14 +19,19 @@ type OpenAIProvider struct {
 	apiKey     string
 	config     *config.Config
 	httpClient *http.Client
+	pricing    *PricingTable
 }
 
 // openaiRequest represents the OpenAI API request format
 type openaiRequest struct {
-	Model       string          `json:"model"`
-	Messages    []openaiMessage `json:"messages"`
-	MaxTokens   int             `json:"max_tokens,omitempty"`
-	Temperature float64         `json:"temperature,omitempty"`
+	Model               string          `json:"model"`
+	Messages            []openaiMessage `json:"messages"`
+	MaxTokens           int             `json:"max_tokens,omitempty"`
+	MaxCompletionTokens int             `json:"max_completion_tokens,omitempty"`
+	Temperature         float64         `json:"temperature,omitempty"`
+	ReasoningEffort     string          `json:"reasoning_effort,omitempty"`
+
+	ResponseFormat *openaiResponseFormat `json:"response_format,omitempty"`
 }
 
 // openaiMessage represents a message in the conversation
@@ -54,9 +59,12 @@ type openaiChoice struct {
 
 // openaiUsage represents token usage information
 type openaiUsage struct {
-	PromptTokens     int `json:"prompt_tokens"`
-	CompletionTokens int `json:"completion_tokens"`
-	TotalTokens      int `json:"total_tokens"`
+	PromptTokens            int `json:"prompt_tokens"`
+	CompletionTokens        int `json:"completion_tokens"`
+	TotalTokens             int `json:"total_tokens"`
+	CompletionTokensDetails struct {
+		ReasoningTokens int `json:"reasoning_tokens"`
+	} `json:"completion_tokens_details"`
 }
 
 // openaiError represents an error response from OpenAI
@@ -75,11 +83,10 @@ func NewOpenAIProvider(apiKey string, cfg *config.Config) (*OpenAIProvider, erro
 	}
 
 	return &OpenAIProvider{
-		apiKey: apiKey,
-		config: cfg,
-		httpClient: &http.Client{
-			Timeout: cfg.AI.Timeout,
-		},
+		apiKey:     apiKey,
+		config:     cfg,
+		pricing:    NewPricingTable(cfg),
+		httpClient: newHTTPClient(cfg),
 	}, nil
 }
 
@@ -104,10 +111,11 @@ func (p *OpenAIProvider) ValidateConfig() error {
 // NewRequest creates a request from a prompt using provider's configured defaults
 func (p *OpenAIProvider) NewRequest(prompt string) *Request {
 	return &Request{
-		Prompt:      prompt,
-		Model:       p.config.AI.Model,
-		MaxTokens:   p.config.AI.MaxTokens,
-		Temperature: p.config.AI.Temperature,
+		Prompt:          prompt,
+		Model:           p.config.AI.Model,
+		MaxTokens:       p.config.AI.MaxTokens,
+		Temperature:     p.config.AI.Temperature,
+		ReasoningEffort: p.config.AI.ReasoningEffort,
 	}
 }
 
@@ -154,10 +162,18 @@ func (p *OpenAIProvider) generateOnce(ctx context.Context, req *Request) (*Respo
 
 	// Build request payload
 	apiReq := openaiRequest{
-		Model:       req.Model,
-		Messages:    messages,
-		MaxTokens:   req.MaxTokens,
-		Temperature: req.Temperature,
+		Model:          req.Model,
+		Messages:       messages,
+		ResponseFormat: openaiResponseFormatFor(req, true),
+	}
+
+	// Reasoning models take max_completion_tokens and reject temperature
+	if isOpenAIReasoningModel(req.Model) {
+		apiReq.MaxCompletionTokens = req.MaxTokens
+		apiReq.ReasoningEffort = req.ReasoningEffort
+	} else {
+		apiReq.MaxTokens = req.MaxTokens
+		apiReq.Temperature = req.Temperature
 	}
 
 	// Marshal request to JSON
@@ -209,53 +225,37 @@ func (p *OpenAIProvider) generateOnce(ctx context.Context, req *Request) (*Respo
 		return nil, fmt.Errorf("no choices in response")
 	}
 
-	content := apiResp.Choices[0].Message.Content
+	// Drop any inline reasoning so only the answer reaches the changelog
+	content := stripReasoning(apiResp.Choices[0].Message.Content)
 
 	// Calculate cost estimate
-	costEstimate := p.estimateCost(apiResp.Model, apiResp.Usage.PromptTokens, apiResp.Usage.CompletionTokens)
+	costEstimate, _ := p.pricing.Cost("openai", apiResp.Model, apiResp.Usage.PromptTokens, apiResp.Usage.CompletionTokens)
 
 	return &Response{
 		Content:      content,
 		TokensUsed:   apiResp.Usage.TotalTokens,
+		InputTokens:  apiResp.Usage.PromptTokens,
+		OutputTokens: apiResp.Usage.CompletionTokens,
 		Model:        apiResp.Model,
 		Provider:     "openai",
 		CostEstimate: costEstimate,
+		StopReason:   normalizeFinishReason(apiResp.Choices[0].FinishReason),
 		Metadata: map[string]interface{}{
 			"prompt_tokens":     apiResp.Usage.PromptTokens,
 			"completion_tokens": apiResp.Usage.CompletionTokens,
+			"reasoning_tokens":  apiResp.Usage.CompletionTokensDetails.ReasoningTokens,
 			"finish_reason":     apiResp.Choices[0].FinishReason,
 			"id":                apiResp.ID,
 		},
 	}, nil
 }
 
-// estimateCost calculates approximate cost based on token usage
-func (p *OpenAIProvider) estimateCost(model string, inputTokens, outputTokens int) float64 {
-	// Pricing as of January 2025 (per million tokens)
-	var inputCost, outputCost float64
-
-	switch {
-	case strings.Contains(model, "gpt-4o-mini"):
-		inputCost = 0.150
-		outputCost = 0.600
-	case strings.Contains(model, "gpt-4o"):
-		inputCost = 2.50
-		outputCost = 10.00
-	case strings.Contains(model, "gpt-4-turbo"):
-		inputCost = 10.00
-		outputCost = 30.00
-	case strings.Contains(model, "gpt-3.5-turbo"):
-		inputCost = 0.50
-		outputCost = 1.50
-	default:
-		// Unknown model, use gpt-4o-mini pricing as baseline
-		inputCost = 0.150
-		outputCost = 0.600
+// isOpenAIReasoningModel reports whether the model is an o-series or GPT-5 reasoning model
+func isOpenAIReasoningModel(model string) bool {
+	for _, prefix := range []string{"o1", "o3", "o4", "gpt-5"} {
+		if strings.HasPrefix(model, prefix) {
+			return true
+		}
 	}
-
-	// Calculate cost in dollars
-	cost := (float64(inputTokens) * inputCost / 1_000_000) +
-		(float64(outputTokens) * outputCost / 1_000_000)
-
-	return cost
+	return false
 }
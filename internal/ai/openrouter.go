@@ -18,6 +18,7 @@ type OpenRouterProvider struct {
 	apiKey     string
 	config     *config.Config
 	httpClient *http.Client
+	pricing    *PricingTable
 }
 
 // NewOpenRouterProvider creates a new OpenRouter provider
@@ -27,11 +28,10 @@ func NewOpenRouterProvider(apiKey string, cfg *config.Config) (*OpenRouterProvid
 	}
 
 	return &OpenRouterProvider{
-		apiKey: apiKey,
-		config: cfg,
-		httpClient: &http.Client{
-			Timeout: cfg.AI.Timeout,
-		},
+		apiKey:     apiKey,
+		config:     cfg,
+		pricing:    NewPricingTable(cfg),
+		httpClient: newHTTPClient(cfg),
 	}, nil
 }
 
@@ -106,10 +106,11 @@ func (p *OpenRouterProvider) generateOnce(ctx context.Context, req *Request) (*R
 
 	// Build request payload (OpenAI-compatible format)
 	apiReq := openaiRequest{
-		Model:       req.Model,
-		Messages:    messages,
-		MaxTokens:   req.MaxTokens,
-		Temperature: req.Temperature,
+		Model:          req.Model,
+		Messages:       messages,
+		MaxTokens:      req.MaxTokens,
+		Temperature:    req.Temperature,
+		ResponseFormat: openaiResponseFormatFor(req, true),
 	}
 
 	// Marshal request to JSON
@@ -172,14 +173,21 @@ func (p *OpenRouterProvider) generateOnce(ctx context.Context, req *Request) (*R
 		return nil, fmt.Errorf("no choices in response")
 	}
 
-	content := apiResp.Choices[0].Message.Content
+	// Drop any inline reasoning so only the answer reaches the changelog
+	content := stripReasoning(apiResp.Choices[0].Message.Content)
+
+	// Calculate cost estimate from the pricing table
+	costEstimate, _ := p.pricing.Cost("openrouter", apiResp.Model, apiResp.Usage.PromptTokens, apiResp.Usage.CompletionTokens)
 
 	return &Response{
 		Content:      content,
 		TokensUsed:   apiResp.Usage.TotalTokens,
+		InputTokens:  apiResp.Usage.PromptTokens,
+		OutputTokens: apiResp.Usage.CompletionTokens,
 		Model:        apiResp.Model,
 		Provider:     "openrouter",
-		CostEstimate: 0.0, // OpenRouter has various pricing, calculate if needed
+		CostEstimate: costEstimate,
+		StopReason:   normalizeFinishReason(apiResp.Choices[0].FinishReason),
 		Metadata: map[string]interface{}{
 			"prompt_tokens":     apiResp.Usage.PromptTokens,
 			"completion_tokens": apiResp.Usage.CompletionTokens,
@@ -0,0 +1,123 @@
+package ai
+
+import (
+	_ "embed"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/1broseidon/promptext-notes/internal/config"
+	"gopkg.in/yaml.v3"
+)
+
+//go:embed pricing.yaml
+var bundledPricingYAML []byte
+
+var (
+	bundledPricing     map[string]map[string]config.ModelPricing
+	bundledPricingErr  error
+	bundledPricingOnce sync.Once
+)
+
+// pricingAliases maps providers that bill like another provider's models
+var pricingAliases = map[string]string{
+	"azure-openai": "openai",
+	"google":       "gemini", // OpenRouter vendor prefix
+}
+
+// PricingTable resolves model prices from the bundled table and config overrides
+type PricingTable struct {
+	overrides map[string]config.ModelPricing
+	providers map[string]map[string]config.ModelPricing
+}
+
+// loadBundledPricing parses the embedded pricing.yaml once
+func loadBundledPricing() (map[string]map[string]config.ModelPricing, error) {
+	bundledPricingOnce.Do(func() {
+		if err := yaml.Unmarshal(bundledPricingYAML, &bundledPricing); err != nil {
+			bundledPricingErr = fmt.Errorf("failed to parse bundled pricing: %w", err)
+		}
+	})
+	return bundledPricing, bundledPricingErr
+}
+
+// NewPricingTable creates a pricing table from the bundled prices, with
+// ai.pricing entries from the config taking precedence
+func NewPricingTable(cfg *config.Config) *PricingTable {
+	// The bundled file is covered by tests, a parse error leaves only overrides
+	providers, _ := loadBundledPricing()
+
+	table := &PricingTable{providers: providers}
+	if cfg != nil {
+		table.overrides = cfg.AI.Pricing
+	}
+	return table
+}
+
+// Lookup returns the price for a provider's model. The second return value is
+// false when no price is known for the model.
+func (t *PricingTable) Lookup(provider, model string) (config.ModelPricing, bool) {
+	// Config overrides apply regardless of provider
+	if price, ok := matchModel(t.overrides, model); ok {
+		return price, true
+	}
+
+	if price, ok := matchModel(t.providers[provider], model); ok {
+		return price, true
+	}
+
+	if alias, ok := pricingAliases[provider]; ok {
+		if price, ok := matchModel(t.providers[alias], model); ok {
+			return price, true
+		}
+	}
+
+	// OpenRouter style "vendor/model" names use the vendor's prices
+	if vendor, name, found := strings.Cut(model, "/"); found {
+		if alias, ok := pricingAliases[vendor]; ok {
+			vendor = alias
+		}
+		if price, ok := matchModel(t.providers[vendor], name); ok {
+			return price, true
+		}
+	}
+
+	return config.ModelPricing{}, false
+}
+
+// Cost calculates the cost in USD for the given token usage. The second
+// return value is false when the model has no known price.
+func (t *PricingTable) Cost(provider, model string, inputTokens, outputTokens int) (float64, bool) {
+	price, ok := t.Lookup(provider, model)
+	if !ok {
+		return 0, false
+	}
+
+	cost := (float64(inputTokens) * price.Input / 1_000_000) +
+		(float64(outputTokens) * price.Output / 1_000_000)
+
+	return cost, true
+}
+
+// matchModel finds the entry whose pattern is the longest substring of the
+// model name, falling back to the "*" wildcard
+func matchModel[T any](entries map[string]T, model string) (T, bool) {
+	if entry, ok := entries[model]; ok {
+		return entry, true
+	}
+
+	bestLen := 0
+	var best T
+	for pattern, entry := range entries {
+		if pattern != "*" && len(pattern) > bestLen && strings.Contains(model, pattern) {
+			best = entry
+			bestLen = len(pattern)
+		}
+	}
+	if bestLen > 0 {
+		return best, true
+	}
+
+	entry, ok := entries["*"]
+	return entry, ok
+}
@@ -0,0 +1,130 @@
+package ai
+
+import (
+	"math"
+	"testing"
+
+	"github.com/1broseidon/promptext-notes/internal/config"
+)
+
+func TestBundledPricingParses(t *testing.T) {
+	providers, err := loadBundledPricing()
+	if err != nil {
+		t.Fatalf("loadBundledPricing() error = %v", err)
+	}
+
+	for _, provider := range []string{"anthropic", "openai", "gemini", "cerebras", "groq", "ollama"} {
+		if len(providers[provider]) == 0 {
+			t.Errorf("Expected bundled prices for %s", provider)
+		}
+	}
+}
+
+func TestPricingLookup(t *testing.T) {
+	table := NewPricingTable(config.Default())
+
+	tests := []struct {
+		name       string
+		provider   string
+		model      string
+		wantInput  float64
+		wantOutput float64
+		wantOK     bool
+	}{
+		{"longest pattern wins", "openai", "gpt-4o-mini-2024-07-18", 0.15, 0.60, true},
+		{"dated model", "openai", "gpt-4o-2024-08-06", 2.50, 10.00, true},
+		{"anthropic family", "anthropic", "claude-sonnet-4-5-20250929", 3.00, 15.00, true},
+		{"azure uses openai prices", "azure-openai", "gpt-4o", 2.50, 10.00, true},
+		{"openrouter vendor prices", "openrouter", "anthropic/claude-haiku-4-5", 1.00, 5.00, true},
+		{"openrouter google maps to gemini", "openrouter", "google/gemini-2.5-flash", 0.30, 2.50, true},
+		{"free tier wildcard", "cerebras", "zai-glm-4.6", 0, 0, true},
+		{"unknown model", "openai", "davinci-002", 0, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			price, ok := table.Lookup(tt.provider, tt.model)
+			if ok != tt.wantOK {
+				t.Fatalf("Lookup() ok = %v, want %v", ok, tt.wantOK)
+			}
+			if price.Input != tt.wantInput || price.Output != tt.wantOutput {
+				t.Errorf("Lookup() = %+v, want input %.3f output %.3f", price, tt.wantInput, tt.wantOutput)
+			}
+		})
+	}
+}
+
+func TestPricingOverride(t *testing.T) {
+	cfg := config.Default()
+	cfg.AI.Pricing = map[string]config.ModelPricing{
+		"llama-3.3-70b": {Input: 0.85, Output: 1.20},
+	}
+	table := NewPricingTable(cfg)
+
+	cost, ok := table.Cost("cerebras", "llama-3.3-70b", 1_000_000, 1_000_000)
+	if !ok {
+		t.Fatal("Expected override price to be found")
+	}
+	if math.Abs(cost-2.05) > 1e-9 {
+		t.Errorf("Expected cost $2.05, got $%.4f", cost)
+	}
+}
+
+func TestContextWindow(t *testing.T) {
+	cfg := config.Default()
+
+	tests := []struct {
+		name     string
+		provider string
+		model    string
+		want     int
+		wantOK   bool
+	}{
+		{"anthropic", "anthropic", "claude-haiku-4-5", 200_000, true},
+		{"openai longest match", "openai", "gpt-4o-mini", 128_000, true},
+		{"azure uses openai", "azure-openai", "gpt-4.1-mini", 1_047_576, true},
+		{"openrouter vendor", "openrouter", "google/gemini-2.5-flash", 1_048_576, true},
+		{"groq wildcard", "groq", "mixtral-8x7b", 32_768, true},
+		{"ollama sizes itself", "ollama", "llama3.2", 0, false},
+		{"unknown model", "openrouter", "mystery/model", 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := ContextWindow(cfg, tt.provider, tt.model)
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("ContextWindow() = %d, %v, want %d, %v", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+
+	cfg.AI.Custom["ollama_num_ctx"] = "32768"
+	if got, ok := ContextWindow(cfg, "ollama", "llama3.2"); got != 32768 || !ok {
+		t.Errorf("Expected pinned ollama_num_ctx, got %d, %v", got, ok)
+	}
+
+	cfg.AI.ContextWindow = 50_000
+	if got, _ := ContextWindow(cfg, "anthropic", "claude-sonnet-4-5"); got != 50_000 {
+		t.Errorf("Expected context_window override, got %d", got)
+	}
+}
+
+func TestCountTokens(t *testing.T) {
+	if CountTokens("") != 0 {
+		t.Error("Expected 0 tokens for empty text")
+	}
+	if got := CountTokens("Release notes for version 1.0"); got <= 0 || got > 20 {
+		t.Errorf("Unexpected token count: %d", got)
+	}
+}
+
+func TestCountTokensUsesEmbeddedEncoding(t *testing.T) {
+	// cl100k_base splits this into "hello" and " world"; the ~4 characters
+	// per token fallback would count 3
+	if got := CountTokens("hello world"); got != 2 {
+		t.Errorf("Expected 2 cl100k_base tokens, got %d", got)
+	}
+	if tokenEncoding == nil {
+		t.Error("Expected the embedded cl100k_base encoding to load, got the character fallback")
+	}
+}
@@ -2,7 +2,10 @@ package ai
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"regexp"
+	"strings"
 
 	"github.com/1broseidon/promptext-notes/internal/config"
 )
@@ -25,73 +28,185 @@ type Provider interface {
 // Request represents an AI generation request
 type Request struct {
 	// Prompt is the main content to send to the AI
-	Prompt string
+	Prompt string `json:"prompt"`
 
 	// SystemPrompt is an optional system prompt (supported by some providers)
-	SystemPrompt string
+	SystemPrompt string `json:"system_prompt,omitempty"`
 
 	// Model specifies which model to use (provider-specific)
-	Model string
+	Model string `json:"model,omitempty"`
 
 	// MaxTokens is the maximum number of tokens to generate
-	MaxTokens int
+	MaxTokens int `json:"max_tokens,omitempty"`
 
 	// Temperature controls randomness (0.0 to 1.0)
-	Temperature float64
+	Temperature float64 `json:"temperature"`
+
+	// ReasoningEffort is the reasoning effort for reasoning models (low, medium, high)
+	ReasoningEffort string `json:"reasoning_effort,omitempty"`
+
+	// ThinkingBudget is the token budget for extended thinking (Anthropic)
+	ThinkingBudget int `json:"thinking_budget,omitempty"`
+
+	// JSONSchema asks for JSON output matching a schema, using the provider's
+	// structured output mode where it has one (optional)
+	JSONSchema *JSONSchema `json:"json_schema,omitempty"`
 }
 
 // Response represents an AI generation response
 type Response struct {
 	// Content is the generated text
-	Content string
+	Content string `json:"content"`
 
 	// TokensUsed is the number of tokens consumed (if available)
-	TokensUsed int
+	TokensUsed int `json:"tokens_used,omitempty"`
+
+	// InputTokens is the number of prompt tokens (if available)
+	InputTokens int `json:"input_tokens,omitempty"`
+
+	// OutputTokens is the number of generated tokens, including reasoning (if available)
+	OutputTokens int `json:"output_tokens,omitempty"`
 
 	// Model is the actual model used
-	Model string
+	Model string `json:"model,omitempty"`
 
 	// Provider is the provider name
-	Provider string
+	Provider string `json:"provider,omitempty"`
 
 	// CostEstimate is an optional cost estimate in USD
-	CostEstimate float64
+	CostEstimate float64 `json:"cost_estimate,omitempty"`
+
+	// StopReason is the normalized reason generation stopped (see StopReason* constants)
+	StopReason string `json:"stop_reason,omitempty"`
+
+	// Cached is true when the response was served from the response cache
+	Cached bool `json:"-"`
 
 	// Metadata contains provider-specific information
-	Metadata map[string]interface{}
+	Metadata map[string]interface{} `json:"metadata,omitempty"`
 }
 
-// NewProvider creates a new AI provider based on the configuration
+// Normalized stop reasons reported in Response.StopReason
+const (
+	// StopReasonComplete means the model finished its answer naturally
+	StopReasonComplete = "complete"
+
+	// StopReasonMaxTokens means the output was cut off by the token limit
+	StopReasonMaxTokens = "max_tokens"
+
+	// StopReasonContentFilter means the provider blocked or stopped the output
+	StopReasonContentFilter = "content_filter"
+
+	// StopReasonUnknown means the provider did not report a recognizable reason
+	StopReasonUnknown = "unknown"
+)
+
+// ErrTruncated is returned when a response was cut off by the token limit
+var ErrTruncated = errors.New("AI response was truncated at max_tokens")
+
+// ContentFilterError is returned when a provider's content filter blocked the
+// prompt or the generated response. It is not retried.
+type ContentFilterError struct {
+	// Provider is the provider name
+	Provider string
+
+	// Reason is the provider-specific filter reason or message
+	Reason string
+}
+
+// Error implements the error interface
+func (e *ContentFilterError) Error() string {
+	return fmt.Sprintf("%s content filter blocked the request: %s", e.Provider, e.Reason)
+}
+
+// Truncated reports whether the response was cut off by the token limit
+func (r *Response) Truncated() bool {
+	return r.StopReason == StopReasonMaxTokens
+}
+
+// normalizeFinishReason maps an OpenAI-compatible finish_reason to a StopReason
+func normalizeFinishReason(reason string) string {
+	switch reason {
+	case "stop", "tool_calls", "function_call":
+		return StopReasonComplete
+	case "length":
+		return StopReasonMaxTokens
+	case "content_filter":
+		return StopReasonContentFilter
+	default:
+		return StopReasonUnknown
+	}
+}
+
+// NewProvider creates a new AI provider based on the configuration, wrapped
+// in the response cache configured by ai.cache
 func NewProvider(cfg *config.Config) (Provider, error) {
 	apiKey, err := cfg.GetAPIKey()
-	if err != nil && cfg.AI.Provider != "ollama" {
+	if err != nil && cfg.AI.Provider != "ollama" && cfg.AI.Provider != "replay" {
 		return nil, fmt.Errorf("failed to get API key: %w", err)
 	}
 
+	var provider Provider
 	switch cfg.AI.Provider {
 	case "anthropic":
-		return NewAnthropicProvider(apiKey, cfg)
+		provider, err = NewAnthropicProvider(apiKey, cfg)
 	case "openai":
-		return NewOpenAIProvider(apiKey, cfg)
+		provider, err = NewOpenAIProvider(apiKey, cfg)
 	case "cerebras":
-		return NewCerebrasProvider(apiKey, cfg)
+		provider, err = NewCerebrasProvider(apiKey, cfg)
 	case "groq":
-		return NewGroqProvider(apiKey, cfg)
+		provider, err = NewGroqProvider(apiKey, cfg)
 	case "openrouter":
-		return NewOpenRouterProvider(apiKey, cfg)
+		provider, err = NewOpenRouterProvider(apiKey, cfg)
+	case "gemini":
+		provider, err = NewGeminiProvider(apiKey, cfg)
+	case "azure-openai":
+		provider, err = NewAzureOpenAIProvider(apiKey, cfg)
 	case "ollama":
-		return NewOllamaProvider(cfg)
+		provider, err = NewOllamaProvider(cfg)
+	case "exec":
+		provider, err = NewExecProvider(cfg)
+	case "replay":
+		// Fixtures are already offline and deterministic, so they aren't cached
+		return NewReplayProvider(cfg)
 	default:
 		return nil, fmt.Errorf("unsupported AI provider: %s", cfg.AI.Provider)
 	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Responses are cached unless ai.cache.mode is off (or unset)
+	if cfg.AI.Cache.Mode == "" || cfg.AI.Cache.Mode == "off" {
+		return provider, nil
+	}
+	dir, err := CacheDir(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return WithCache(provider, NewResponseCache(dir, cfg.GetCacheTTL()), cfg.AI.Cache.Mode, CacheEndpoint(cfg)), nil
 }
 
 // RequestFromConfig creates a Request from configuration and prompt
 func RequestFromConfig(cfg *config.Config, prompt string) *Request {
 	return &Request{
-		Prompt:      prompt,
-		Model:       cfg.AI.Model,
-		MaxTokens:   cfg.AI.MaxTokens,
-		Temperature: cfg.AI.Temperature,
+		Prompt:          prompt,
+		Model:           cfg.AI.Model,
+		MaxTokens:       cfg.AI.MaxTokens,
+		Temperature:     cfg.AI.Temperature,
+		ReasoningEffort: cfg.AI.ReasoningEffort,
+		ThinkingBudget:  cfg.AI.ThinkingBudget,
+	}
+}
+
+// thinkBlockPattern matches inline reasoning emitted by some open models
+var thinkBlockPattern = regexp.MustCompile(`(?s)<think>.*?</think>`)
+
+// stripReasoning removes inline <think>...</think> reasoning so it never ends up
+// in the changelog text
+func stripReasoning(content string) string {
+	if !strings.Contains(content, "<think>") {
+		return content
 	}
+	return strings.TrimSpace(thinkBlockPattern.ReplaceAllString(content, ""))
 }
@@ -0,0 +1,71 @@
+package ai
+
+import "testing"
+
+func TestIsOpenAIReasoningModel(t *testing.T) {
+	tests := []struct {
+		model string
+		want  bool
+	}{
+		{"o1", true},
+		{"o3-mini", true},
+		{"o4-mini", true},
+		{"gpt-5-mini", true},
+		{"gpt-4o", false},
+		{"gpt-4o-mini", false},
+		{"gpt-3.5-turbo", false},
+	}
+
+	for _, tt := range tests {
+		if got := isOpenAIReasoningModel(tt.model); got != tt.want {
+			t.Errorf("isOpenAIReasoningModel(%q) = %v, want %v", tt.model, got, tt.want)
+		}
+	}
+}
+
+func TestThinkingBudget(t *testing.T) {
+	tests := []struct {
+		name string
+		req  *Request
+		want int
+	}{
+		{"explicit budget wins", &Request{ThinkingBudget: 2048, ReasoningEffort: "high"}, 2048},
+		{"derived from effort", &Request{ReasoningEffort: "medium"}, 4096},
+		{"disabled", &Request{}, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := thinkingBudget(tt.req); got != tt.want {
+				t.Errorf("thinkingBudget() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStripReasoning(t *testing.T) {
+	input := "<think>\nLet me look at the diff first.\n</think>\n\n### Added\n- New flag"
+	if got := stripReasoning(input); got != "### Added\n- New flag" {
+		t.Errorf("stripReasoning() = %q", got)
+	}
+
+	plain := "### Fixed\n- Crash on empty diff"
+	if got := stripReasoning(plain); got != plain {
+		t.Errorf("stripReasoning() changed content without reasoning: %q", got)
+	}
+}
+
+func TestNormalizeStopReasons(t *testing.T) {
+	if normalizeFinishReason("length") != StopReasonMaxTokens {
+		t.Error("Expected length to map to max_tokens")
+	}
+	if normalizeFinishReason("stop") != StopReasonComplete {
+		t.Error("Expected stop to map to complete")
+	}
+	if normalizeAnthropicStopReason("max_tokens") != StopReasonMaxTokens {
+		t.Error("Expected anthropic max_tokens to map to max_tokens")
+	}
+	if normalizeAnthropicStopReason("end_turn") != StopReasonComplete {
+		t.Error("Expected end_turn to map to complete")
+	}
+}
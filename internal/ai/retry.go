@@ -2,6 +2,7 @@ package ai
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
@@ -24,6 +25,17 @@ func RetryWithBackoff(ctx context.Context, cfg *config.Config, fn RetryableFunc)
 
 		lastErr = err
 
+		// Content filter blocks are deterministic, retrying won't help
+		var filterErr *ContentFilterError
+		if errors.As(err, &filterErr) {
+			return err
+		}
+
+		// Neither is a missing fixture in replay mode
+		if errors.Is(err, ErrFixtureNotFound) {
+			return err
+		}
+
 		// Don't sleep after the last attempt
 		if attempt == cfg.AI.Retry.Attempts {
 			break
@@ -0,0 +1,45 @@
+package ai
+
+// JSONSchema describes the JSON a request asks for
+type JSONSchema struct {
+	// Name identifies the schema, e.g. "release_notes"
+	Name string `json:"name"`
+
+	// Schema is the JSON Schema of the output. Providers with strict modes
+	// require every object to list all its properties as required and to
+	// set additionalProperties to false.
+	Schema map[string]interface{} `json:"schema"`
+}
+
+// openaiResponseFormat is the response_format of OpenAI-compatible APIs
+type openaiResponseFormat struct {
+	Type       string            `json:"type"`
+	JSONSchema *openaiJSONSchema `json:"json_schema,omitempty"`
+}
+
+// openaiJSONSchema is a schema for the json_schema response format
+type openaiJSONSchema struct {
+	Name   string                 `json:"name"`
+	Schema map[string]interface{} `json:"schema"`
+	Strict bool                   `json:"strict"`
+}
+
+// openaiResponseFormatFor returns the response format for a request: a strict
+// json_schema, or plain json_object mode for APIs without schema support.
+// Requests without a schema get no response format.
+func openaiResponseFormatFor(req *Request, schemaSupported bool) *openaiResponseFormat {
+	if req.JSONSchema == nil {
+		return nil
+	}
+	if !schemaSupported {
+		return &openaiResponseFormat{Type: "json_object"}
+	}
+	return &openaiResponseFormat{
+		Type: "json_schema",
+		JSONSchema: &openaiJSONSchema{
+			Name:   req.JSONSchema.Name,
+			Schema: req.JSONSchema.Schema,
+			Strict: true,
+		},
+	}
+}
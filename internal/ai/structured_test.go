@@ -0,0 +1,67 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+)
+
+var testSchema = &JSONSchema{
+	Name:   "release_notes",
+	Schema: map[string]interface{}{"type": "object"},
+}
+
+func TestOpenAIResponseFormatFor(t *testing.T) {
+	if got := openaiResponseFormatFor(&Request{}, true); got != nil {
+		t.Errorf("Expected no response format without a schema, got %+v", got)
+	}
+
+	req := &Request{JSONSchema: testSchema}
+	got := openaiResponseFormatFor(req, true)
+	if got.Type != "json_schema" || got.JSONSchema.Name != "release_notes" || !got.JSONSchema.Strict {
+		t.Errorf("Expected a strict json_schema format, got %+v", got)
+	}
+
+	if got := openaiResponseFormatFor(req, false); got.Type != "json_object" || got.JSONSchema != nil {
+		t.Errorf("Expected json_object mode, got %+v", got)
+	}
+}
+
+func TestGeminiJSONMode(t *testing.T) {
+	var got geminiRequest
+	provider := newTestGeminiProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Fatalf("Failed to decode request: %v", err)
+		}
+		w.Write([]byte(`{"candidates": [{"content": {"parts": [{"text": "{\"sections\": []}"}]}, "finishReason": "STOP"}]}`))
+	})
+
+	req := provider.NewRequest("Generate release notes")
+	req.JSONSchema = testSchema
+	resp, err := provider.Generate(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+
+	if got.GenerationConfig.ResponseMimeType != "application/json" {
+		t.Errorf("Expected JSON mode, got %q", got.GenerationConfig.ResponseMimeType)
+	}
+	if resp.Content != `{"sections": []}` {
+		t.Errorf("Unexpected content %q", resp.Content)
+	}
+}
+
+func TestAnthropicToolUseContent(t *testing.T) {
+	var resp anthropicResponse
+	body := `{"content": [{"type": "tool_use", "name": "release_notes", "input": {"sections": []}}], "stop_reason": "tool_use"}`
+	if err := json.Unmarshal([]byte(body), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if string(resp.Content[0].Input) != `{"sections": []}` {
+		t.Errorf("Expected the tool input as raw JSON, got %s", resp.Content[0].Input)
+	}
+	if normalizeAnthropicStopReason(resp.StopReason) != StopReasonComplete {
+		t.Error("A forced tool call should count as complete")
+	}
+}
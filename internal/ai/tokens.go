@@ -0,0 +1,39 @@
+package ai
+
+import (
+	"sync"
+
+	"github.com/pkoukk/tiktoken-go"
+	tiktokenloader "github.com/pkoukk/tiktoken-go-loader"
+)
+
+var (
+	tokenEncoding     *tiktoken.Tiktoken
+	tokenEncodingOnce sync.Once
+)
+
+// CountTokens counts tokens locally using tiktoken's cl100k_base encoding.
+// Counts are exact for OpenAI models and a close approximation for others.
+// The encoding is embedded in the binary, so counting never downloads it;
+// should it fail to load, counting falls back to ~4 characters per token.
+func CountTokens(text string) int {
+	if text == "" {
+		return 0
+	}
+
+	tokenEncodingOnce.Do(func() {
+		// The default loader downloads the BPE file on first use, without a
+		// timeout, which hangs or silently degrades offline runs
+		tiktoken.SetBpeLoader(tiktokenloader.NewOfflineLoader())
+		enc, err := tiktoken.GetEncoding("cl100k_base")
+		if err == nil {
+			tokenEncoding = enc
+		}
+	})
+
+	if tokenEncoding == nil {
+		return (len(text) + 3) / 4
+	}
+
+	return len(tokenEncoding.Encode(text, nil, nil))
+}
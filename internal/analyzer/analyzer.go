@@ -25,6 +25,14 @@ type CommitCategories struct {
 	Chores   []string
 	Changes  []string
 	Breaking []string
+
+	// APIChanges are breaking changes detected from the exported Go API rather
+	// than from commit messages; they are not counted as commits
+	APIChanges []string
+
+	// Dependencies are dependency and Go toolchain changes detected from
+	// manifest files; they are not counted as commits
+	Dependencies []string
 }
 
 // CategorizeCommits categorizes commit messages based on conventional commit format.
@@ -0,0 +1,426 @@
+package apidiff
+
+import (
+	"fmt"
+	"go/ast"
+	"go/build/constraint"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/1broseidon/promptext-notes/internal/git"
+)
+
+// Change is an incompatible change to a package's exported API
+type Change struct {
+	Package string // Package directory, "." for the repository root
+	Name    string // API element, e.g. "func Get", "method (*Store).Put", "field Config.Timeout"
+	Before  string // Signature at the since ref
+	After   string // Signature at HEAD, empty when removed
+}
+
+// Removed reports whether the element no longer exists
+func (c Change) Removed() bool {
+	return c.After == ""
+}
+
+// Added reports whether the element is new, e.g. a method that every
+// implementation of an interface now has to provide
+func (c Change) Added() bool {
+	return c.Before == ""
+}
+
+// String describes the change as a release notes item
+func (c Change) String() string {
+	if c.Added() {
+		return fmt.Sprintf("`%s`: %s added to the interface (`%s`), existing implementations must add it", c.Package, c.Name, c.After)
+	}
+	if c.Removed() {
+		return fmt.Sprintf("`%s`: %s removed (was `%s`)", c.Package, c.Name, c.Before)
+	}
+	return fmt.Sprintf("`%s`: %s changed from `%s` to `%s`", c.Package, c.Name, c.Before, c.After)
+}
+
+// Surface maps each exported API element of a package to its signature.
+// Elements declared in files with build constraints (build tags or GOOS and
+// GOARCH file name suffixes) are named with the constraint, e.g.
+// "func Open [linux]", so each platform's API is compared with itself.
+type Surface map[string]string
+
+// Detect compares the exported API of every public Go package with changed
+// files between the since ref and HEAD. Packages under internal/, vendored
+// and testdata directories and main packages are not importable and are
+// skipped. Removals and changes are reported, and of additions only methods
+// added to existing interfaces, which break their implementations.
+func Detect(since string, changedFiles []string) ([]Change, error) {
+	var changes []Change
+	for _, dir := range packageDirs(changedFiles) {
+		before, err := loadSurface(since, dir)
+		if err != nil {
+			return nil, err
+		}
+		after, err := loadSurface("HEAD", dir)
+		if err != nil {
+			return nil, err
+		}
+		changes = append(changes, Compare(dir, before, after)...)
+	}
+	return changes, nil
+}
+
+// Compare lists the elements of before that were removed or changed in after,
+// and the methods added to interfaces that before already had
+func Compare(pkg string, before, after Surface) []Change {
+	names := make([]string, 0, len(before)+len(after))
+	for name := range before {
+		names = append(names, name)
+	}
+	for name := range after {
+		if _, ok := before[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	var changes []Change
+	for _, name := range names {
+		sig, inBefore := before[name]
+		if !inBefore {
+			if interfaceName, ok := interfaceOf(name); ok && strings.HasPrefix(before[interfaceName], "interface") {
+				changes = append(changes, Change{Package: pkg, Name: name, After: after[name]})
+			}
+			continue
+		}
+		if afterSig, ok := after[name]; !ok || afterSig != sig {
+			changes = append(changes, Change{Package: pkg, Name: name, Before: sig, After: afterSig})
+		}
+	}
+	return changes
+}
+
+// interfaceOf returns the type element a method or embedded element belongs
+// to, e.g. "type Getter [linux]" for "method Getter.Get [linux]"
+func interfaceOf(name string) (string, bool) {
+	kind, rest, ok := strings.Cut(name, " ")
+	if !ok || (kind != "method" && kind != "embedded") {
+		return "", false
+	}
+	typeName, member, ok := strings.Cut(rest, ".")
+	if !ok {
+		return "", false
+	}
+	suffix := ""
+	if i := strings.Index(member, " ["); i >= 0 {
+		suffix = member[i:]
+	}
+	return "type " + typeName + suffix, true
+}
+
+// ParseSurface collects the exported API of a package from its source files.
+// Test files and files that don't parse are ignored; main packages have no API.
+func ParseSurface(sources map[string]string) Surface {
+	names := make([]string, 0, len(sources))
+	for name := range sources {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	surface := make(Surface)
+	fset := token.NewFileSet()
+	for _, name := range names {
+		if strings.HasSuffix(name, "_test.go") {
+			continue
+		}
+		file, err := parser.ParseFile(fset, name, sources[name], parser.SkipObjectResolution|parser.ParseComments)
+		if err != nil || file.Name.Name == "main" {
+			continue
+		}
+
+		decls := make(Surface)
+		addDecls(decls, file)
+		suffix := ""
+		if c := buildConstraint(name, file); c != "" {
+			suffix = " [" + c + "]"
+		}
+		for element, sig := range decls {
+			surface[element+suffix] = sig
+		}
+	}
+	return surface
+}
+
+// buildConstraint returns the build constraints of a file: its //go:build
+// (or // +build) line and its GOOS and GOARCH file name suffixes
+func buildConstraint(name string, file *ast.File) string {
+	var goBuild string
+	var plusBuild []string
+	for _, group := range file.Comments {
+		if group.Pos() > file.Package {
+			break
+		}
+		for _, comment := range group.List {
+			expr, err := constraint.Parse(comment.Text)
+			if err != nil {
+				continue
+			}
+			if constraint.IsGoBuild(comment.Text) {
+				goBuild = expr.String()
+			} else {
+				plusBuild = append(plusBuild, expr.String())
+			}
+		}
+	}
+
+	// A //go:build line replaces any // +build lines
+	var parts []string
+	if goBuild != "" {
+		parts = append(parts, goBuild)
+	} else {
+		parts = append(parts, plusBuild...)
+	}
+
+	// name_GOOS.go, name_GOARCH.go and name_GOOS_GOARCH.go
+	elems := strings.Split(strings.TrimSuffix(path.Base(name), ".go"), "_")
+	if n := len(elems); n >= 3 && knownOS[elems[n-2]] && knownArch[elems[n-1]] {
+		parts = append(parts, elems[n-2], elems[n-1])
+	} else if n >= 2 && (knownOS[elems[n-1]] || knownArch[elems[n-1]]) {
+		parts = append(parts, elems[n-1])
+	}
+	return strings.Join(parts, " && ")
+}
+
+// knownOS and knownArch are the GOOS and GOARCH values file name suffixes
+// constrain a file to
+var (
+	knownOS = setOf("aix", "android", "darwin", "dragonfly", "freebsd", "hurd", "illumos", "ios", "js",
+		"linux", "nacl", "netbsd", "openbsd", "plan9", "solaris", "wasip1", "windows", "zos")
+	knownArch = setOf("386", "amd64", "amd64p32", "arm", "armbe", "arm64", "arm64be", "loong64",
+		"mips", "mipsle", "mips64", "mips64le", "mips64p32", "mips64p32le", "ppc", "ppc64", "ppc64le",
+		"riscv", "riscv64", "s390", "s390x", "sparc", "sparc64", "wasm")
+)
+
+// setOf builds a set from values
+func setOf(values ...string) map[string]bool {
+	set := make(map[string]bool, len(values))
+	for _, value := range values {
+		set[value] = true
+	}
+	return set
+}
+
+// packageDirs returns the sorted directories of changed Go files that can be
+// imported by other modules
+func packageDirs(changedFiles []string) []string {
+	seen := make(map[string]bool)
+	var dirs []string
+	for _, file := range changedFiles {
+		if path.Ext(file) != ".go" || strings.HasSuffix(file, "_test.go") {
+			continue
+		}
+		dir := path.Dir(file)
+		if seen[dir] || !importable(dir) {
+			continue
+		}
+		seen[dir] = true
+		dirs = append(dirs, dir)
+	}
+	sort.Strings(dirs)
+	return dirs
+}
+
+// importable reports whether packages in dir can be imported from outside the module
+func importable(dir string) bool {
+	for _, part := range strings.Split(dir, "/") {
+		switch part {
+		case "internal", "vendor", "testdata":
+			return false
+		}
+	}
+	return true
+}
+
+// loadSurface reads a package's Go files at ref with git and parses its API
+func loadSurface(ref, dir string) (Surface, error) {
+	files, err := git.ListFiles(ref, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	sources := make(map[string]string)
+	for _, file := range files {
+		if path.Ext(file) != ".go" {
+			continue
+		}
+		source, err := git.ShowFile(ref, file)
+		if err != nil {
+			return nil, err
+		}
+		sources[file] = source
+	}
+	return ParseSurface(sources), nil
+}
+
+// addDecls adds a file's exported declarations to the surface
+func addDecls(surface Surface, file *ast.File) {
+	for _, decl := range file.Decls {
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			if !d.Name.IsExported() {
+				continue
+			}
+			if d.Recv == nil || len(d.Recv.List) == 0 {
+				surface["func "+d.Name.Name] = funcSignature(d.Type)
+				continue
+			}
+			recv := receiverType(d.Recv.List[0].Type)
+			if !ast.IsExported(strings.TrimPrefix(recv, "*")) {
+				continue
+			}
+			// Moving a method between value and pointer receivers changes the method set
+			surface[fmt.Sprintf("method %s.%s", strings.TrimPrefix(recv, "*"), d.Name.Name)] =
+				fmt.Sprintf("(%s) %s", recv, funcSignature(d.Type))
+
+		case *ast.GenDecl:
+			for _, spec := range d.Specs {
+				switch s := spec.(type) {
+				case *ast.TypeSpec:
+					addType(surface, s)
+				case *ast.ValueSpec:
+					for _, name := range s.Names {
+						if !name.IsExported() {
+							continue
+						}
+						// Values may change freely; only the declared type is API
+						sig := d.Tok.String()
+						if s.Type != nil {
+							sig += " " + types.ExprString(s.Type)
+						}
+						surface[d.Tok.String()+" "+name.Name] = sig
+					}
+				}
+			}
+		}
+	}
+}
+
+// addType adds an exported type, and the exported fields of structs and
+// methods of interfaces as their own elements
+func addType(surface Surface, spec *ast.TypeSpec) {
+	if !spec.Name.IsExported() {
+		return
+	}
+	name := spec.Name.Name
+	params := typeParams(spec.TypeParams)
+
+	switch t := spec.Type.(type) {
+	case *ast.StructType:
+		surface["type "+name] = "struct" + params
+		for _, field := range t.Fields.List {
+			for _, fieldName := range fieldNames(field) {
+				if ast.IsExported(fieldName) {
+					surface[fmt.Sprintf("field %s.%s", name, fieldName)] = types.ExprString(field.Type)
+				}
+			}
+		}
+
+	case *ast.InterfaceType:
+		surface["type "+name] = "interface" + params
+		for _, method := range t.Methods.List {
+			if len(method.Names) == 0 {
+				surface[fmt.Sprintf("embedded %s.%s", name, types.ExprString(method.Type))] = "embedded"
+				continue
+			}
+			for _, methodName := range method.Names {
+				if funcType, ok := method.Type.(*ast.FuncType); ok && methodName.IsExported() {
+					surface[fmt.Sprintf("method %s.%s", name, methodName.Name)] = funcSignature(funcType)
+				}
+			}
+		}
+
+	default:
+		sig := "type" + params + " " + types.ExprString(spec.Type)
+		if spec.Assign.IsValid() {
+			sig = "type" + params + " = " + types.ExprString(spec.Type)
+		}
+		surface["type "+name] = sig
+	}
+}
+
+// funcSignature renders a function type without parameter names, which
+// callers don't depend on
+func funcSignature(ft *ast.FuncType) string {
+	unnamed := &ast.FuncType{
+		Params:  stripNames(ft.Params),
+		Results: stripNames(ft.Results),
+	}
+	// ExprString leaves out type parameters
+	return "func" + typeParams(ft.TypeParams) + strings.TrimPrefix(types.ExprString(unnamed), "func")
+}
+
+// stripNames repeats each field's type once per name, without the names
+func stripNames(fields *ast.FieldList) *ast.FieldList {
+	if fields == nil {
+		return nil
+	}
+	stripped := &ast.FieldList{}
+	for _, field := range fields.List {
+		count := len(field.Names)
+		if count == 0 {
+			count = 1
+		}
+		for i := 0; i < count; i++ {
+			stripped.List = append(stripped.List, &ast.Field{Type: field.Type})
+		}
+	}
+	return stripped
+}
+
+// typeParams renders a type parameter list such as "[K comparable, V any]"
+func typeParams(fields *ast.FieldList) string {
+	if fields == nil || len(fields.List) == 0 {
+		return ""
+	}
+	var params []string
+	for _, field := range fields.List {
+		var names []string
+		for _, name := range field.Names {
+			names = append(names, name.Name)
+		}
+		params = append(params, strings.Join(names, ", ")+" "+types.ExprString(field.Type))
+	}
+	return "[" + strings.Join(params, ", ") + "]"
+}
+
+// fieldNames returns a struct field's names; embedded fields are named by their type
+func fieldNames(field *ast.Field) []string {
+	if len(field.Names) > 0 {
+		var names []string
+		for _, name := range field.Names {
+			names = append(names, name.Name)
+		}
+		return names
+	}
+	embedded := strings.TrimPrefix(receiverType(field.Type), "*")
+	if i := strings.LastIndex(embedded, "."); i >= 0 {
+		embedded = embedded[i+1:]
+	}
+	return []string{embedded}
+}
+
+// receiverType renders a receiver or embedded type without type arguments,
+// e.g. "*Store" for "*Store[T]"
+func receiverType(expr ast.Expr) string {
+	switch t := expr.(type) {
+	case *ast.StarExpr:
+		return "*" + receiverType(t.X)
+	case *ast.IndexExpr:
+		return receiverType(t.X)
+	case *ast.IndexListExpr:
+		return receiverType(t.X)
+	default:
+		return types.ExprString(expr)
+	}
+}
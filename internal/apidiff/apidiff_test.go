@@ -0,0 +1,205 @@
+package apidiff
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const beforeSource = `package store
+
+// Store holds values
+type Store struct {
+	Name  string
+	Limit int
+	cache map[string]string
+}
+
+type Getter interface {
+	Get(key string) (string, error)
+}
+
+type ID int
+
+const Version = "1"
+
+var ErrNotFound error
+
+func New(name string) *Store { return nil }
+
+func (s *Store) Get(key string) (string, error) { return "", nil }
+
+func (s Store) Len() int { return 0 }
+
+func Legacy() {}
+
+func helper() {}
+`
+
+const afterSource = `package store
+
+// Store holds values
+type Store struct {
+	Name  string
+	Limit int64
+	cache map[string]any
+}
+
+type Getter interface {
+	Get(ctx string, key string) (string, error)
+}
+
+type ID string
+
+const Version = "2"
+
+var ErrNotFound error
+
+func New(title string) *Store { return nil }
+
+func (s *Store) Get(key string) (string, error) { return "", nil }
+
+func (s *Store) Len() int { return 0 }
+
+func Added() {}
+
+func helper(x int) {}
+`
+
+func TestCompare(t *testing.T) {
+	before := ParseSurface(map[string]string{"store/store.go": beforeSource})
+	after := ParseSurface(map[string]string{"store/store.go": afterSource})
+
+	var got []string
+	for _, change := range Compare("store", before, after) {
+		got = append(got, change.String())
+	}
+
+	want := []string{
+		"`store`: field Store.Limit changed from `int` to `int64`",
+		"`store`: func Legacy removed (was `func()`)",
+		"`store`: method Getter.Get changed from `func(string) (string, error)` to `func(string, string) (string, error)`",
+		"`store`: method Store.Len changed from `(Store) func() int` to `(*Store) func() int`",
+		"`store`: type ID changed from `type int` to `type string`",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Compare() =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestParseSurface(t *testing.T) {
+	surface := ParseSurface(map[string]string{
+		"p/p.go": `package p
+
+type List[T any] struct {
+	Items []T
+	io.Reader
+}
+
+type Alias = List[int]
+
+func Map[T, U any](in []T, f func(T) U) []U { return nil }
+
+func (l *List[T]) Push(v T) {}
+`,
+		"p/p_test.go": "package p\n\nfunc TestOnly() {}\n",
+		"p/broken.go": "package p\nfunc {",
+	})
+
+	want := Surface{
+		"type List":         "struct[T any]",
+		"field List.Items":  "[]T",
+		"field List.Reader": "io.Reader",
+		"type Alias":        "type = List[int]",
+		"func Map":          "func[T, U any]([]T, func(T) U) []U",
+		"method List.Push":  "(*List) func(T)",
+	}
+	if !reflect.DeepEqual(surface, want) {
+		t.Errorf("ParseSurface() = %v, want %v", surface, want)
+	}
+
+	if main := ParseSurface(map[string]string{"main.go": "package main\n\nfunc Run() {}\n"}); len(main) != 0 {
+		t.Errorf("main packages have no API, got %v", main)
+	}
+}
+
+func TestParseSurfaceBuildConstraints(t *testing.T) {
+	sources := map[string]string{
+		"fsutil/open_linux.go":   "package fsutil\n\nfunc Open(path string) (int, error) { return 0, nil }\n",
+		"fsutil/open_windows.go": "package fsutil\n\nfunc Open(path string) (uintptr, error) { return 0, nil }\n",
+		"fsutil/mmap_unix.go":    "//go:build darwin || linux\n\npackage fsutil\n\nfunc Map(fd int) []byte { return nil }\n",
+		"fsutil/fsutil.go":       "package fsutil\n\nfunc Close(fd int) error { return nil }\n",
+	}
+
+	want := Surface{
+		"func Open [linux]":          "func(string) (int, error)",
+		"func Open [windows]":        "func(string) (uintptr, error)",
+		"func Map [darwin || linux]": "func(int) []byte",
+		"func Close":                 "func(int) error",
+	}
+	for i := 0; i < 20; i++ {
+		surface := ParseSurface(sources)
+		if !reflect.DeepEqual(surface, want) {
+			t.Fatalf("ParseSurface() = %v, want %v", surface, want)
+		}
+		if changes := Compare("fsutil", surface, ParseSurface(sources)); len(changes) != 0 {
+			t.Fatalf("Expected no changes between identical platform files, got %v", changes)
+		}
+	}
+}
+
+func TestCompareInterfaceAdditions(t *testing.T) {
+	before := ParseSurface(map[string]string{"store/store.go": `package store
+
+type Getter interface {
+	Get(key string) (string, error)
+}
+
+type Store struct{}
+`})
+	after := ParseSurface(map[string]string{"store/store.go": `package store
+
+type Getter interface {
+	Get(key string) (string, error)
+	Close() error
+}
+
+type Lister interface {
+	List() []string
+}
+
+type Store struct{}
+
+func (s *Store) Close() error { return nil }
+`})
+
+	var got []string
+	for _, change := range Compare("store", before, after) {
+		got = append(got, change.String())
+	}
+
+	want := []string{
+		"`store`: method Getter.Close added to the interface (`func() error`), existing implementations must add it",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Compare() =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestPackageDirs(t *testing.T) {
+	changed := []string{
+		"pkg/store/store.go",
+		"pkg/store/store_test.go",
+		"pkg/store/memory.go",
+		"internal/cache/cache.go",
+		"pkg/internal/util.go",
+		"vendor/github.com/x/y/y.go",
+		"api.go",
+		"README.md",
+	}
+
+	want := []string{".", "pkg/store"}
+	if got := packageDirs(changed); !reflect.DeepEqual(got, want) {
+		t.Errorf("packageDirs() = %v, want %v", got, want)
+	}
+}
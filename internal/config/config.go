@@ -3,6 +3,7 @@ package config
 import (
 	"fmt"
 	"os"
+	"regexp"
 	"time"
 
 	"gopkg.in/yaml.v3"
@@ -27,6 +28,135 @@ type AIConfig struct {
 	Retry       RetryConfig       `yaml:"retry"`
 	Custom      map[string]string `yaml:"custom"`
 	Polish      PolishConfig      `yaml:"polish"`
+
+	OnTruncation     string `yaml:"on_truncation"`     // What to do when output hits max_tokens: continue or fail
+	MaxContinuations *int   `yaml:"max_continuations"` // Continuation requests before failing (on_truncation: continue, 0 = none)
+
+	ReasoningEffort string `yaml:"reasoning_effort"` // Reasoning effort for reasoning models: low, medium, high (optional)
+	ThinkingBudget  int    `yaml:"thinking_budget"`  // Extended thinking token budget for Anthropic models (optional)
+
+	Pricing    map[string]ModelPricing `yaml:"pricing"`      // Per-model price overrides (model name pattern -> prices)
+	MaxCostUSD float64                 `yaml:"max_cost_usd"` // Abort before a request would exceed this total cost (0 = no limit)
+
+	ContextWindow     int    `yaml:"context_window"`      // Model context window in tokens (0 = use the built-in table)
+	OnContextOverflow string `yaml:"on_context_overflow"` // What to do when the prompt doesn't fit: reduce or fail
+
+	DiffTokens     int `yaml:"diff_tokens"`     // Token budget for the diff in the prompt; less relevant files become stat lines
+	ContextTokens  int `yaml:"context_tokens"`  // Token budget for code context from changed and related files
+	OverviewTokens int `yaml:"overview_tokens"` // Token budget for the project overview when no changed file is included
+
+	MapReduce MapReduceConfig `yaml:"map_reduce"`
+
+	Redaction RedactionConfig `yaml:"redaction"`
+
+	PromptTemplate string `yaml:"prompt_template"` // Path to a text/template discovery prompt (optional, default: built-in)
+
+	OutputCheck string `yaml:"output_check"` // What to do with URLs or code in the output not found in the inputs: reject, warn, off
+
+	OutputFormat string `yaml:"output_format"` // What the model returns: markdown, or json validated against a schema and rendered by the generator
+
+	Grounding GroundingConfig `yaml:"grounding"`
+
+	Pipeline []StageConfig `yaml:"pipeline"` // Generation stages in order (default: discovery, then polish if enabled)
+
+	Cache CacheConfig `yaml:"cache"`
+
+	Replay ReplayConfig `yaml:"replay"`
+
+	Exec ExecConfig `yaml:"exec"`
+
+	Ensemble EnsembleConfig `yaml:"ensemble"`
+}
+
+// EnsembleConfig defines ensemble mode: the discovery prompt is sent to
+// several provider/model pairs concurrently, then a judge model picks the
+// best result or the results are merged by item overlap
+type EnsembleConfig struct {
+	Mode       string            `yaml:"mode"`       // off, merge (deterministic), judge
+	Candidates []CandidateConfig `yaml:"candidates"` // Provider/model pairs to run
+	Judge      CandidateConfig   `yaml:"judge"`      // judge mode: model picking the best (default: ai.provider and ai.model)
+}
+
+// CandidateConfig is a provider/model pair of the ensemble. The model and API
+// key env default like a pipeline stage's.
+type CandidateConfig struct {
+	Provider  string `yaml:"provider"`
+	Model     string `yaml:"model"`
+	APIKeyEnv string `yaml:"api_key_env"`
+}
+
+// ExecConfig defines the exec provider, an external command that reads an AI
+// request as JSON on stdin and writes the response as JSON to stdout
+type ExecConfig struct {
+	Command []string `yaml:"command"` // Program and arguments (not run through a shell)
+}
+
+// ReplayConfig defines recorded HTTP fixtures: record writes every provider
+// request and response to Dir, and the replay provider serves them back
+type ReplayConfig struct {
+	Dir      string `yaml:"dir"`      // Fixture directory
+	Record   bool   `yaml:"record"`   // Write each request/response pair to Dir
+	Provider string `yaml:"provider"` // Provider the fixtures were recorded with (replay provider)
+}
+
+// CacheConfig defines the on-disk cache of AI responses
+type CacheConfig struct {
+	Mode string         `yaml:"mode"` // on, refresh (don't read, only store), off
+	Dir  string         `yaml:"dir"`  // Cache directory (default: promptext-notes in the user cache directory)
+	TTL  *time.Duration `yaml:"ttl"`  // How long responses are reused (0 = never expire)
+}
+
+// StageConfig defines one stage of the generation pipeline. Stages run in
+// order and each one's prompt gets the output of the stage before it.
+type StageConfig struct {
+	Name           string   `yaml:"name"`            // Shown in progress and cost reports (defaults to the preset)
+	Preset         string   `yaml:"preset"`          // discovery, polish, or empty for a custom stage
+	Provider       string   `yaml:"provider"`        // Defaults to ai.provider
+	Model          string   `yaml:"model"`           // Defaults to ai.model, or the provider's default model
+	APIKeyEnv      string   `yaml:"api_key_env"`     // Auto-detected from provider
+	PromptTemplate string   `yaml:"prompt_template"` // Path to a text/template prompt (required for custom stages)
+	Temperature    *float64 `yaml:"temperature"`     // Defaults to ai.temperature (polish: polish_temperature)
+	MaxTokens      int      `yaml:"max_tokens"`      // Defaults to ai.max_tokens (polish: polish_max_tokens)
+	CheckItems     bool     `yaml:"check_items"`     // Keep the sections and items of the input (always on for polish)
+}
+
+// GroundingConfig defines the check that each generated item is supported by
+// the commits, changed files or diff hunks
+type GroundingConfig struct {
+	Mode     string      `yaml:"mode"`      // What to do with unsupported items: warn (report only), mark, remove, off
+	MinScore *float64    `yaml:"min_score"` // Share of an item's keywords one commit, file or hunk must contain (0-1)
+	Judge    JudgeConfig `yaml:"judge"`
+}
+
+// JudgeConfig defines the optional model asked about items without lexical support
+type JudgeConfig struct {
+	Enabled   bool   `yaml:"enabled"`
+	Provider  string `yaml:"provider"`    // Defaults to ai.provider
+	Model     string `yaml:"model"`       // Defaults to ai.model
+	APIKeyEnv string `yaml:"api_key_env"` // Auto-detected from provider
+	MaxTokens int    `yaml:"max_tokens"`
+}
+
+// RedactionConfig defines how secrets are removed before anything is sent to a provider
+type RedactionConfig struct {
+	Mode             string   `yaml:"mode"`              // redact (replace with placeholders), abort, or off
+	Patterns         []string `yaml:"patterns"`          // Extra regular expressions to redact
+	EntropyThreshold float64  `yaml:"entropy_threshold"` // Bits per character from which assigned values count as secrets
+}
+
+// MapReduceConfig defines chunked summarization for releases too large for one prompt
+type MapReduceConfig struct {
+	Mode        string `yaml:"mode"`         // off, auto (when the prompt overflows or min_commits is reached), always
+	MinCommits  int    `yaml:"min_commits"`  // auto: also use map-reduce from this many commits (0 = only on overflow)
+	ChunkTokens int    `yaml:"chunk_tokens"` // Target size of each chunk's diff and commits
+	Concurrency int    `yaml:"concurrency"`  // Max chunk summaries requested in parallel
+	GroupDepth  int    `yaml:"group_depth"`  // Directory levels used to group files into components
+}
+
+// ModelPricing defines model prices in USD per million tokens
+type ModelPricing struct {
+	Input  float64 `yaml:"input"`
+	Output float64 `yaml:"output"`
 }
 
 // PolishConfig defines 2-stage polish workflow configuration
@@ -35,7 +165,8 @@ type PolishConfig struct {
 	PolishModel       string  `yaml:"polish_model"`       // Model for stage 2 (stage 1 uses ai.model)
 	PolishProvider    string  `yaml:"polish_provider"`    // Optional: different provider for polish (defaults to ai.provider)
 	PolishAPIKeyEnv   string  `yaml:"polish_api_key_env"` // Optional: API key env var (auto-detected from provider)
-	PolishPrompt      string  `yaml:"polish_prompt"`      // Custom polish prompt (optional)
+	PolishPrompt      string  `yaml:"polish_prompt"`      // Custom polish prompt with a single %s for the draft (optional, legacy)
+	PromptTemplate    string  `yaml:"prompt_template"`    // Path to a text/template polish prompt (optional)
 	PolishMaxTokens   int     `yaml:"polish_max_tokens"`  // Max tokens for polish stage
 	PolishTemperature float64 `yaml:"polish_temperature"` // Temperature for polish stage
 }
@@ -122,6 +253,10 @@ func GetDefaultMetaExclusions() []string {
 
 // Default returns a configuration with sensible defaults
 func Default() *Config {
+	maxContinuations := 2
+	minScore := 0.5
+	cacheTTL := 24 * time.Hour
+
 	return &Config{
 		Version: "1",
 		AI: AIConfig{
@@ -146,6 +281,42 @@ func Default() *Config {
 				PolishMaxTokens:   4000,
 				PolishTemperature: 0.3,
 			},
+			OnTruncation:      "continue",
+			MaxContinuations:  &maxContinuations,
+			OnContextOverflow: "reduce",
+			DiffTokens:        24000,
+			ContextTokens:     8000,
+			OverviewTokens:    4000,
+			MapReduce: MapReduceConfig{
+				Mode:        "auto",
+				MinCommits:  0,
+				ChunkTokens: 30000,
+				Concurrency: 4,
+				GroupDepth:  2,
+			},
+			Redaction: RedactionConfig{
+				Mode:             "redact",
+				EntropyThreshold: 3.5,
+			},
+			OutputCheck:  "reject",
+			OutputFormat: "markdown",
+			Cache: CacheConfig{
+				Mode: "on",
+				TTL:  &cacheTTL,
+			},
+			Replay: ReplayConfig{
+				Dir: ".promptext-notes/fixtures",
+			},
+			Ensemble: EnsembleConfig{
+				Mode: "off",
+			},
+			Grounding: GroundingConfig{
+				Mode:     "warn",
+				MinScore: &minScore,
+				Judge: JudgeConfig{
+					MaxTokens: 1000,
+				},
+			},
 		},
 		Output: OutputConfig{
 			Format: "keepachangelog",
@@ -154,6 +325,7 @@ func Default() *Config {
 				"added",
 				"changed",
 				"fixed",
+				"dependencies",
 				"docs",
 			},
 		},
@@ -215,6 +387,69 @@ func applyDefaults(config *Config) {
 	if config.AI.Custom == nil {
 		config.AI.Custom = make(map[string]string)
 	}
+	if config.AI.OnTruncation == "" {
+		config.AI.OnTruncation = defaults.AI.OnTruncation
+	}
+	if config.AI.MaxContinuations == nil {
+		config.AI.MaxContinuations = defaults.AI.MaxContinuations
+	}
+	if config.AI.OnContextOverflow == "" {
+		config.AI.OnContextOverflow = defaults.AI.OnContextOverflow
+	}
+	if config.AI.DiffTokens == 0 {
+		config.AI.DiffTokens = defaults.AI.DiffTokens
+	}
+	if config.AI.ContextTokens == 0 {
+		config.AI.ContextTokens = defaults.AI.ContextTokens
+	}
+	if config.AI.OverviewTokens == 0 {
+		config.AI.OverviewTokens = defaults.AI.OverviewTokens
+	}
+	if config.AI.MapReduce.Mode == "" {
+		config.AI.MapReduce.Mode = defaults.AI.MapReduce.Mode
+	}
+	if config.AI.MapReduce.ChunkTokens == 0 {
+		config.AI.MapReduce.ChunkTokens = defaults.AI.MapReduce.ChunkTokens
+	}
+	if config.AI.MapReduce.Concurrency == 0 {
+		config.AI.MapReduce.Concurrency = defaults.AI.MapReduce.Concurrency
+	}
+	if config.AI.MapReduce.GroupDepth == 0 {
+		config.AI.MapReduce.GroupDepth = defaults.AI.MapReduce.GroupDepth
+	}
+	if config.AI.Redaction.Mode == "" {
+		config.AI.Redaction.Mode = defaults.AI.Redaction.Mode
+	}
+	if config.AI.Redaction.EntropyThreshold == 0 {
+		config.AI.Redaction.EntropyThreshold = defaults.AI.Redaction.EntropyThreshold
+	}
+	if config.AI.OutputCheck == "" {
+		config.AI.OutputCheck = defaults.AI.OutputCheck
+	}
+	if config.AI.OutputFormat == "" {
+		config.AI.OutputFormat = defaults.AI.OutputFormat
+	}
+	if config.AI.Ensemble.Mode == "" {
+		config.AI.Ensemble.Mode = defaults.AI.Ensemble.Mode
+	}
+	if config.AI.Replay.Dir == "" {
+		config.AI.Replay.Dir = defaults.AI.Replay.Dir
+	}
+	if config.AI.Cache.Mode == "" {
+		config.AI.Cache.Mode = defaults.AI.Cache.Mode
+	}
+	if config.AI.Cache.TTL == nil {
+		config.AI.Cache.TTL = defaults.AI.Cache.TTL
+	}
+	if config.AI.Grounding.Mode == "" {
+		config.AI.Grounding.Mode = defaults.AI.Grounding.Mode
+	}
+	if config.AI.Grounding.MinScore == nil {
+		config.AI.Grounding.MinScore = defaults.AI.Grounding.MinScore
+	}
+	if config.AI.Grounding.Judge.MaxTokens == 0 {
+		config.AI.Grounding.Judge.MaxTokens = defaults.AI.Grounding.Judge.MaxTokens
+	}
 
 	// Set default API key env var based on provider
 	if config.AI.APIKeyEnv == "" {
@@ -291,6 +526,10 @@ func GetDefaultAPIKeyEnv(provider string) string {
 		return "GROQ_API_KEY"
 	case "openrouter":
 		return "OPENROUTER_API_KEY"
+	case "gemini":
+		return "GEMINI_API_KEY"
+	case "azure-openai":
+		return "AZURE_OPENAI_API_KEY"
 	case "ollama":
 		return "" // No API key needed for local Ollama
 	default:
@@ -316,6 +555,10 @@ func getDefaultModel(provider string) string {
 		return "llama-3.3-70b-versatile"
 	case "openrouter":
 		return "anthropic/claude-sonnet-4.5" // Best polish model
+	case "gemini":
+		return "gemini-2.5-flash"
+	case "azure-openai":
+		return "gpt-4o-mini" // Deployment name, usually matches the model
 	case "ollama":
 		return "llama3.2"
 	default:
@@ -340,16 +583,20 @@ func (c *Config) GetAPIKey() (string, error) {
 // Validate checks if the configuration is valid
 func (c *Config) Validate() error {
 	validProviders := map[string]bool{
-		"anthropic":  true,
-		"openai":     true,
-		"cerebras":   true,
-		"groq":       true,
-		"openrouter": true,
-		"ollama":     true,
+		"anthropic":    true,
+		"openai":       true,
+		"cerebras":     true,
+		"groq":         true,
+		"openrouter":   true,
+		"gemini":       true,
+		"azure-openai": true,
+		"ollama":       true,
+		"exec":         true,
+		"replay":       true,
 	}
 
 	if !validProviders[c.AI.Provider] {
-		return fmt.Errorf("invalid AI provider: %s (supported: anthropic, openai, cerebras, groq, openrouter, ollama)", c.AI.Provider)
+		return fmt.Errorf("invalid AI provider: %s (supported: anthropic, openai, cerebras, groq, openrouter, gemini, azure-openai, ollama, exec, replay)", c.AI.Provider)
 	}
 
 	if c.AI.MaxTokens <= 0 {
@@ -370,17 +617,209 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("invalid backoff strategy: %s (supported: exponential, linear, constant)", c.AI.Retry.Backoff)
 	}
 
+	validTruncationModes := map[string]bool{
+		"continue": true,
+		"fail":     true,
+	}
+
+	if !validTruncationModes[c.AI.OnTruncation] {
+		return fmt.Errorf("invalid on_truncation mode: %s (supported: continue, fail)", c.AI.OnTruncation)
+	}
+
+	if c.AI.MaxContinuations != nil && *c.AI.MaxContinuations < 0 {
+		return fmt.Errorf("max_continuations must not be negative, got: %d", *c.AI.MaxContinuations)
+	}
+
+	validReasoningEfforts := map[string]bool{
+		"":       true,
+		"low":    true,
+		"medium": true,
+		"high":   true,
+	}
+
+	if !validReasoningEfforts[c.AI.ReasoningEffort] {
+		return fmt.Errorf("invalid reasoning_effort: %s (supported: low, medium, high)", c.AI.ReasoningEffort)
+	}
+
+	if c.AI.ThinkingBudget != 0 && c.AI.ThinkingBudget < 1024 {
+		return fmt.Errorf("thinking_budget must be at least 1024 tokens, got: %d", c.AI.ThinkingBudget)
+	}
+
+	validOverflowModes := map[string]bool{
+		"reduce": true,
+		"fail":   true,
+	}
+
+	if !validOverflowModes[c.AI.OnContextOverflow] {
+		return fmt.Errorf("invalid on_context_overflow mode: %s (supported: reduce, fail)", c.AI.OnContextOverflow)
+	}
+
+	if c.AI.ContextWindow < 0 {
+		return fmt.Errorf("context_window must not be negative, got: %d", c.AI.ContextWindow)
+	}
+
+	if c.AI.DiffTokens < 0 {
+		return fmt.Errorf("diff_tokens must not be negative, got: %d", c.AI.DiffTokens)
+	}
+
+	if c.AI.ContextTokens < 0 {
+		return fmt.Errorf("context_tokens must not be negative, got: %d", c.AI.ContextTokens)
+	}
+
+	if c.AI.OverviewTokens < 0 {
+		return fmt.Errorf("overview_tokens must not be negative, got: %d", c.AI.OverviewTokens)
+	}
+
+	validMapReduceModes := map[string]bool{
+		"off":    true,
+		"auto":   true,
+		"always": true,
+	}
+
+	if !validMapReduceModes[c.AI.MapReduce.Mode] {
+		return fmt.Errorf("invalid map_reduce mode: %s (supported: off, auto, always)", c.AI.MapReduce.Mode)
+	}
+
+	if c.AI.MapReduce.Mode != "off" {
+		if c.AI.MapReduce.ChunkTokens < 1000 {
+			return fmt.Errorf("map_reduce chunk_tokens must be at least 1000, got: %d", c.AI.MapReduce.ChunkTokens)
+		}
+		if c.AI.MapReduce.Concurrency <= 0 {
+			return fmt.Errorf("map_reduce concurrency must be positive, got: %d", c.AI.MapReduce.Concurrency)
+		}
+		if c.AI.MapReduce.GroupDepth <= 0 {
+			return fmt.Errorf("map_reduce group_depth must be positive, got: %d", c.AI.MapReduce.GroupDepth)
+		}
+		if c.AI.MapReduce.MinCommits < 0 {
+			return fmt.Errorf("map_reduce min_commits must not be negative, got: %d", c.AI.MapReduce.MinCommits)
+		}
+	}
+
+	validRedactionModes := map[string]bool{
+		"redact": true,
+		"abort":  true,
+		"off":    true,
+	}
+
+	if !validRedactionModes[c.AI.Redaction.Mode] {
+		return fmt.Errorf("invalid redaction mode: %s (supported: redact, abort, off)", c.AI.Redaction.Mode)
+	}
+
+	if c.AI.Redaction.EntropyThreshold < 0 {
+		return fmt.Errorf("redaction entropy_threshold must not be negative, got: %.2f", c.AI.Redaction.EntropyThreshold)
+	}
+
+	for _, pattern := range c.AI.Redaction.Patterns {
+		if _, err := regexp.Compile(pattern); err != nil {
+			return fmt.Errorf("invalid redaction pattern %q: %v", pattern, err)
+		}
+	}
+
+	validOutputCheckModes := map[string]bool{
+		"reject": true,
+		"warn":   true,
+		"off":    true,
+	}
+
+	if !validOutputCheckModes[c.AI.OutputCheck] {
+		return fmt.Errorf("invalid output_check mode: %s (supported: reject, warn, off)", c.AI.OutputCheck)
+	}
+
+	validOutputFormats := map[string]bool{
+		"markdown": true,
+		"json":     true,
+	}
+
+	if !validOutputFormats[c.AI.OutputFormat] {
+		return fmt.Errorf("invalid output_format: %s (supported: markdown, json)", c.AI.OutputFormat)
+	}
+
+	if err := c.validateEnsemble(validProviders); err != nil {
+		return err
+	}
+
+	if c.AI.Provider == "exec" && len(c.AI.Exec.Command) == 0 {
+		return fmt.Errorf("the exec provider requires exec.command")
+	}
+
+	if c.AI.Replay.Provider == "replay" || (c.AI.Replay.Provider != "" && !validProviders[c.AI.Replay.Provider]) {
+		return fmt.Errorf("invalid replay provider: %s (supported: anthropic, openai, cerebras, groq, openrouter, gemini, azure-openai, ollama)", c.AI.Replay.Provider)
+	}
+
+	if c.AI.Provider == "replay" && c.AI.Replay.Provider == "" {
+		return fmt.Errorf("the replay provider requires replay.provider (the provider the fixtures were recorded with)")
+	}
+
+	validCacheModes := map[string]bool{
+		"on":      true,
+		"refresh": true,
+		"off":     true,
+	}
+
+	if !validCacheModes[c.AI.Cache.Mode] {
+		return fmt.Errorf("invalid cache mode: %s (supported: on, refresh, off)", c.AI.Cache.Mode)
+	}
+
+	if ttl := c.GetCacheTTL(); ttl < 0 {
+		return fmt.Errorf("cache ttl must not be negative, got: %s", ttl)
+	}
+
+	validGroundingModes := map[string]bool{
+		"warn":   true,
+		"mark":   true,
+		"remove": true,
+		"off":    true,
+	}
+
+	if !validGroundingModes[c.AI.Grounding.Mode] {
+		return fmt.Errorf("invalid grounding mode: %s (supported: warn, mark, remove, off)", c.AI.Grounding.Mode)
+	}
+
+	if minScore := c.GetGroundingMinScore(); minScore < 0 || minScore > 1 {
+		return fmt.Errorf("grounding min_score must be between 0 and 1, got: %.2f", minScore)
+	}
+
+	if c.AI.Grounding.Judge.Enabled && !validProviders[c.GetJudgeProvider()] {
+		return fmt.Errorf("invalid grounding judge provider: %s (supported: anthropic, openai, cerebras, groq, openrouter, gemini, azure-openai, ollama, exec, replay)", c.GetJudgeProvider())
+	}
+
+	if c.AI.MaxCostUSD < 0 {
+		return fmt.Errorf("max_cost_usd must not be negative, got: %.2f", c.AI.MaxCostUSD)
+	}
+
+	for model, price := range c.AI.Pricing {
+		if price.Input < 0 || price.Output < 0 {
+			return fmt.Errorf("pricing for %s must not be negative", model)
+		}
+	}
+
+	if err := c.validatePipeline(validProviders); err != nil {
+		return err
+	}
+
 	// Validate polish config if enabled
 	if c.AI.Polish.Enabled {
 		polishProvider := c.GetPolishProvider()
 		if !validProviders[polishProvider] {
-			return fmt.Errorf("invalid polish provider: %s (supported: anthropic, openai, cerebras, groq, openrouter, ollama)", polishProvider)
+			return fmt.Errorf("invalid polish provider: %s (supported: anthropic, openai, cerebras, groq, openrouter, gemini, azure-openai, ollama, exec, replay)", polishProvider)
+		}
+		if c.AI.Polish.PolishPrompt != "" && c.AI.Polish.PromptTemplate != "" {
+			return fmt.Errorf("polish_prompt and polish prompt_template are mutually exclusive")
 		}
 	}
 
 	return nil
 }
 
+// GetMaxContinuations returns ai.max_continuations, or the default when it
+// isn't set
+func (c *Config) GetMaxContinuations() int {
+	if c.AI.MaxContinuations == nil {
+		return *Default().AI.MaxContinuations
+	}
+	return *c.AI.MaxContinuations
+}
+
 // GetPolishProvider returns the effective polish provider (defaults to main provider)
 func (c *Config) GetPolishProvider() string {
 	if c.AI.Polish.PolishProvider != "" {
@@ -427,3 +866,252 @@ func (c *Config) GetPolishAPIKey() (string, error) {
 
 	return key, nil
 }
+
+// GetJudgeProvider returns the effective grounding judge provider (defaults to main provider)
+func (c *Config) GetJudgeProvider() string {
+	if c.AI.Grounding.Judge.Provider != "" {
+		return c.AI.Grounding.Judge.Provider
+	}
+	return c.AI.Provider
+}
+
+// GetJudgeModel returns the effective grounding judge model (defaults to main model)
+func (c *Config) GetJudgeModel() string {
+	if c.AI.Grounding.Judge.Model != "" {
+		return c.AI.Grounding.Judge.Model
+	}
+	if c.AI.Grounding.Judge.Provider != "" && c.AI.Grounding.Judge.Provider != c.AI.Provider {
+		return getDefaultModel(c.AI.Grounding.Judge.Provider)
+	}
+	return c.AI.Model
+}
+
+// GetJudgeAPIKeyEnv returns the API key env var for the grounding judge provider
+func (c *Config) GetJudgeAPIKeyEnv() string {
+	if c.AI.Grounding.Judge.APIKeyEnv != "" {
+		return c.AI.Grounding.Judge.APIKeyEnv
+	}
+	if c.GetJudgeProvider() == c.AI.Provider {
+		return c.AI.APIKeyEnv
+	}
+	return GetDefaultAPIKeyEnv(c.GetJudgeProvider())
+}
+
+// GetCacheTTL returns ai.cache.ttl, or the default when it isn't set
+func (c *Config) GetCacheTTL() time.Duration {
+	if c.AI.Cache.TTL == nil {
+		return *Default().AI.Cache.TTL
+	}
+	return *c.AI.Cache.TTL
+}
+
+// GetGroundingMinScore returns ai.grounding.min_score, or the default when it
+// isn't set
+func (c *Config) GetGroundingMinScore() float64 {
+	if c.AI.Grounding.MinScore == nil {
+		return *Default().AI.Grounding.MinScore
+	}
+	return *c.AI.Grounding.MinScore
+}
+
+// CustomFor returns the ai.custom settings of a stage, judge or ensemble
+// candidate using model. custom.azure_deployment names the deployment of
+// ai.model, so it is left out for other models, whose deployment is their
+// model name.
+func (c *Config) CustomFor(model string) map[string]string {
+	if _, ok := c.AI.Custom["azure_deployment"]; !ok || model == c.AI.Model {
+		return c.AI.Custom
+	}
+
+	custom := make(map[string]string, len(c.AI.Custom))
+	for key, value := range c.AI.Custom {
+		if key != "azure_deployment" {
+			custom[key] = value
+		}
+	}
+	return custom
+}
+
+// EnsembleCandidates returns the ensemble's provider/model pairs with their
+// defaults filled in
+func (c *Config) EnsembleCandidates() []CandidateConfig {
+	candidates := make([]CandidateConfig, len(c.AI.Ensemble.Candidates))
+	for i, candidate := range c.AI.Ensemble.Candidates {
+		candidates[i] = c.resolveCandidate(candidate)
+	}
+	return candidates
+}
+
+// EnsembleJudge returns the ensemble judge with its defaults filled in
+func (c *Config) EnsembleJudge() CandidateConfig {
+	return c.resolveCandidate(c.AI.Ensemble.Judge)
+}
+
+// resolveCandidate fills in a candidate's defaults the way resolveStage does
+func (c *Config) resolveCandidate(candidate CandidateConfig) CandidateConfig {
+	stage := c.resolveStage(StageConfig{
+		Provider:  candidate.Provider,
+		Model:     candidate.Model,
+		APIKeyEnv: candidate.APIKeyEnv,
+	})
+	return CandidateConfig{Provider: stage.Provider, Model: stage.Model, APIKeyEnv: stage.APIKeyEnv}
+}
+
+// Stages returns the generation pipeline with defaults filled in. Without
+// ai.pipeline it is discovery, then polish when ai.polish is enabled; polish
+// is also added to a pipeline without a polish stage when enabled, so
+// --polish keeps working. It goes right after discovery, so later stages
+// (a translation, say) get the polished text rather than polish rewriting
+// their output; without a discovery stage it runs last.
+func (c *Config) Stages() []StageConfig {
+	stages := c.AI.Pipeline
+	if len(stages) == 0 {
+		stages = []StageConfig{{Preset: "discovery"}}
+	}
+
+	hasPolish := false
+	for _, stage := range stages {
+		if stage.Preset == "polish" {
+			hasPolish = true
+		}
+	}
+	addPolish := c.AI.Polish.Enabled && !hasPolish
+
+	resolved := make([]StageConfig, 0, len(stages)+1)
+	for _, stage := range stages {
+		resolved = append(resolved, c.resolveStage(stage))
+		if addPolish && stage.Preset == "discovery" {
+			resolved = append(resolved, c.PolishStage())
+			addPolish = false
+		}
+	}
+	if addPolish {
+		resolved = append(resolved, c.PolishStage())
+	}
+	return resolved
+}
+
+// PolishStage returns the polish preset stage configured by ai.polish
+func (c *Config) PolishStage() StageConfig {
+	temperature := c.AI.Polish.PolishTemperature
+	return c.resolveStage(StageConfig{
+		Name:           "polish",
+		Preset:         "polish",
+		Provider:       c.GetPolishProvider(),
+		Model:          c.GetPolishModel(),
+		APIKeyEnv:      c.AI.Polish.PolishAPIKeyEnv,
+		PromptTemplate: c.AI.Polish.PromptTemplate,
+		Temperature:    &temperature,
+		MaxTokens:      c.AI.Polish.PolishMaxTokens,
+	})
+}
+
+// resolveStage fills in a stage's defaults from the ai section
+func (c *Config) resolveStage(stage StageConfig) StageConfig {
+	if stage.Name == "" {
+		stage.Name = stage.Preset
+	}
+	if stage.Provider == "" {
+		stage.Provider = c.AI.Provider
+	}
+	if stage.Model == "" {
+		if stage.Provider == c.AI.Provider {
+			stage.Model = c.AI.Model
+		} else {
+			stage.Model = getDefaultModel(stage.Provider)
+		}
+	}
+	if stage.APIKeyEnv == "" {
+		if stage.Provider == c.AI.Provider {
+			stage.APIKeyEnv = c.AI.APIKeyEnv
+		} else {
+			stage.APIKeyEnv = GetDefaultAPIKeyEnv(stage.Provider)
+		}
+	}
+	if stage.Temperature == nil {
+		temperature := c.AI.Temperature
+		if stage.Preset == "polish" {
+			temperature = c.AI.Polish.PolishTemperature
+		}
+		stage.Temperature = &temperature
+	}
+	if stage.MaxTokens == 0 {
+		stage.MaxTokens = c.AI.MaxTokens
+		if stage.Preset == "polish" && c.AI.Polish.PolishMaxTokens > 0 {
+			stage.MaxTokens = c.AI.Polish.PolishMaxTokens
+		}
+	}
+	return stage
+}
+
+// validatePipeline checks the ai.pipeline stages
+func (c *Config) validatePipeline(validProviders map[string]bool) error {
+	names := make(map[string]bool)
+	discovery := 0
+	for i, stage := range c.AI.Pipeline {
+		switch stage.Preset {
+		case "discovery":
+			discovery++
+		case "polish":
+		case "":
+			if stage.PromptTemplate == "" {
+				return fmt.Errorf("pipeline stage %d: custom stages need a prompt_template", i+1)
+			}
+			if stage.Name == "" {
+				return fmt.Errorf("pipeline stage %d: custom stages need a name", i+1)
+			}
+		default:
+			return fmt.Errorf("pipeline stage %d: invalid preset: %s (supported: discovery, polish)", i+1, stage.Preset)
+		}
+
+		stage = c.resolveStage(stage)
+		if names[stage.Name] {
+			return fmt.Errorf("pipeline stage %d: duplicate stage name: %s", i+1, stage.Name)
+		}
+		names[stage.Name] = true
+
+		if !validProviders[stage.Provider] {
+			return fmt.Errorf("pipeline stage %s: invalid provider: %s (supported: anthropic, openai, cerebras, groq, openrouter, gemini, azure-openai, ollama, exec, replay)", stage.Name, stage.Provider)
+		}
+		if *stage.Temperature < 0 || *stage.Temperature > 1 {
+			return fmt.Errorf("pipeline stage %s: temperature must be between 0 and 1, got: %.2f", stage.Name, *stage.Temperature)
+		}
+		if stage.MaxTokens < 0 {
+			return fmt.Errorf("pipeline stage %s: max_tokens must be positive, got: %d", stage.Name, stage.MaxTokens)
+		}
+	}
+
+	if discovery > 1 {
+		return fmt.Errorf("pipeline can have only one discovery stage, got: %d", discovery)
+	}
+	return nil
+}
+
+// validateEnsemble checks the ensemble mode, candidates and judge
+func (c *Config) validateEnsemble(validProviders map[string]bool) error {
+	validModes := map[string]bool{
+		"off":   true,
+		"merge": true,
+		"judge": true,
+	}
+
+	if !validModes[c.AI.Ensemble.Mode] {
+		return fmt.Errorf("invalid ensemble mode: %s (supported: off, merge, judge)", c.AI.Ensemble.Mode)
+	}
+	if c.AI.Ensemble.Mode == "off" {
+		return nil
+	}
+
+	if len(c.AI.Ensemble.Candidates) < 2 {
+		return fmt.Errorf("ensemble needs at least 2 candidates, got: %d", len(c.AI.Ensemble.Candidates))
+	}
+	for i, candidate := range c.EnsembleCandidates() {
+		if !validProviders[candidate.Provider] {
+			return fmt.Errorf("ensemble candidate %d: invalid provider: %s (supported: anthropic, openai, cerebras, groq, openrouter, gemini, azure-openai, ollama, exec, replay)", i+1, candidate.Provider)
+		}
+	}
+	if c.AI.Ensemble.Mode == "judge" && !validProviders[c.EnsembleJudge().Provider] {
+		return fmt.Errorf("invalid ensemble judge provider: %s (supported: anthropic, openai, cerebras, groq, openrouter, gemini, azure-openai, ollama, exec, replay)", c.EnsembleJudge().Provider)
+	}
+	return nil
+}
@@ -29,6 +29,10 @@ func TestDefault(t *testing.T) {
 	if config.Output.Format != "keepachangelog" {
 		t.Errorf("Expected keepachangelog format, got %s", config.Output.Format)
 	}
+
+	if config.AI.ContextTokens != 8000 || config.AI.OverviewTokens != 4000 {
+		t.Errorf("Expected 8000/4000 context budgets, got %d/%d", config.AI.ContextTokens, config.AI.OverviewTokens)
+	}
 }
 
 func TestLoad(t *testing.T) {
@@ -114,6 +118,42 @@ filters:
 	}
 }
 
+func TestLoadKeepsExplicitZero(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.yml")
+	configContent := `ai:
+  provider: openai
+  max_continuations: 0
+  grounding:
+    min_score: 0
+  cache:
+    ttl: 0s
+`
+	if err := os.WriteFile(configPath, []byte(configContent), 0644); err != nil {
+		t.Fatalf("Failed to create test config file: %v", err)
+	}
+
+	config, err := Load(configPath)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	if got := config.GetMaxContinuations(); got != 0 {
+		t.Errorf("Expected max_continuations: 0 to turn continuations off, got %d", got)
+	}
+
+	if got := config.GetGroundingMinScore(); got != 0 {
+		t.Errorf("Expected grounding min_score: 0 to be kept, got %.2f", got)
+	}
+
+	if got := config.GetCacheTTL(); got != 0 {
+		t.Errorf("Expected cache ttl: 0 (never expire) to be kept, got %s", got)
+	}
+
+	if got := Default().GetMaxContinuations(); got != 2 {
+		t.Errorf("Expected 2 continuations by default, got %d", got)
+	}
+}
+
 func TestLoadNonExistent(t *testing.T) {
 	_, err := Load("/nonexistent/config.yml")
 	if err == nil {
@@ -180,6 +220,7 @@ func TestGetDefaultModel(t *testing.T) {
 		{"openai", "gpt-4o-mini"},
 		{"cerebras", "zai-glm-4.6"},
 		{"groq", "llama-3.3-70b-versatile"},
+		{"gemini", "gemini-2.5-flash"},
 		{"ollama", "llama3.2"},
 		{"unknown", ""},
 	}
@@ -201,6 +242,8 @@ func TestGetDefaultAPIKeyEnv(t *testing.T) {
 		{"openai", "OPENAI_API_KEY"},
 		{"cerebras", "CEREBRAS_API_KEY"},
 		{"groq", "GROQ_API_KEY"},
+		{"gemini", "GEMINI_API_KEY"},
+		{"azure-openai", "AZURE_OPENAI_API_KEY"},
 		{"ollama", ""},
 		{"unknown", ""},
 	}
@@ -453,6 +496,228 @@ func TestValidate(t *testing.T) {
 			},
 			expectErr: true,
 		},
+		{
+			name: "Invalid truncation mode",
+			config: &Config{
+				AI: AIConfig{
+					Provider:     "anthropic",
+					MaxTokens:    8000,
+					Temperature:  0.3,
+					OnTruncation: "ignore",
+					Retry: RetryConfig{
+						Backoff: "exponential",
+					},
+				},
+			},
+			expectErr: true,
+		},
+		{
+			name: "Invalid context overflow mode",
+			config: &Config{
+				AI: AIConfig{
+					Provider:          "anthropic",
+					MaxTokens:         8000,
+					Temperature:       0.3,
+					OnTruncation:      "continue",
+					OnContextOverflow: "truncate",
+					Retry: RetryConfig{
+						Backoff: "exponential",
+					},
+				},
+			},
+			expectErr: true,
+		},
+		{
+			name: "Invalid redaction mode",
+			config: func() *Config {
+				c := Default()
+				c.AI.Redaction.Mode = "warn"
+				return c
+			}(),
+			expectErr: true,
+		},
+		{
+			name: "Invalid redaction pattern",
+			config: func() *Config {
+				c := Default()
+				c.AI.Redaction.Patterns = []string{"internal-[a-z+"}
+				return c
+			}(),
+			expectErr: true,
+		},
+		{
+			name: "Polish prompt and template both set",
+			config: func() *Config {
+				c := Default()
+				c.AI.Polish.Enabled = true
+				c.AI.Polish.PolishPrompt = "Polish: %s"
+				c.AI.Polish.PromptTemplate = "polish.tmpl"
+				return c
+			}(),
+			expectErr: true,
+		},
+		{
+			name: "Invalid output format",
+			config: func() *Config {
+				c := Default()
+				c.AI.OutputFormat = "yaml"
+				return c
+			}(),
+			expectErr: true,
+		},
+		{
+			name: "Valid pipeline",
+			config: func() *Config {
+				c := Default()
+				c.AI.Pipeline = []StageConfig{
+					{Preset: "discovery"},
+					{Name: "critique", PromptTemplate: "critique.tmpl", Provider: "openai"},
+					{Preset: "polish"},
+				}
+				return c
+			}(),
+			expectErr: false,
+		},
+		{
+			name: "Custom stage without template",
+			config: func() *Config {
+				c := Default()
+				c.AI.Pipeline = []StageConfig{{Name: "critique"}}
+				return c
+			}(),
+			expectErr: true,
+		},
+		{
+			name: "Two discovery stages",
+			config: func() *Config {
+				c := Default()
+				c.AI.Pipeline = []StageConfig{{Preset: "discovery"}, {Name: "again", Preset: "discovery"}}
+				return c
+			}(),
+			expectErr: true,
+		},
+		{
+			name: "Duplicate stage names",
+			config: func() *Config {
+				c := Default()
+				c.AI.Pipeline = []StageConfig{{Preset: "polish"}, {Name: "polish", PromptTemplate: "x.tmpl"}}
+				return c
+			}(),
+			expectErr: true,
+		},
+		{
+			name: "Invalid stage preset",
+			config: func() *Config {
+				c := Default()
+				c.AI.Pipeline = []StageConfig{{Preset: "translate"}}
+				return c
+			}(),
+			expectErr: true,
+		},
+		{
+			name: "Invalid ensemble mode",
+			config: func() *Config {
+				c := Default()
+				c.AI.Ensemble.Mode = "vote"
+				return c
+			}(),
+			expectErr: true,
+		},
+		{
+			name: "Ensemble with one candidate",
+			config: func() *Config {
+				c := Default()
+				c.AI.Ensemble.Mode = "merge"
+				c.AI.Ensemble.Candidates = []CandidateConfig{{Provider: "groq"}}
+				return c
+			}(),
+			expectErr: true,
+		},
+		{
+			name: "Valid ensemble",
+			config: func() *Config {
+				c := Default()
+				c.AI.Ensemble.Mode = "judge"
+				c.AI.Ensemble.Candidates = []CandidateConfig{{Provider: "groq"}, {Provider: "cerebras", Model: "llama-3.3-70b"}}
+				return c
+			}(),
+			expectErr: false,
+		},
+		{
+			name: "Exec provider without command",
+			config: func() *Config {
+				c := Default()
+				c.AI.Provider = "exec"
+				return c
+			}(),
+			expectErr: true,
+		},
+		{
+			name: "Replay provider without recorded provider",
+			config: func() *Config {
+				c := Default()
+				c.AI.Provider = "replay"
+				return c
+			}(),
+			expectErr: true,
+		},
+		{
+			name: "Replay provider",
+			config: func() *Config {
+				c := Default()
+				c.AI.Provider = "replay"
+				c.AI.Replay.Provider = "openai"
+				return c
+			}(),
+			expectErr: false,
+		},
+		{
+			name: "Invalid cache mode",
+			config: func() *Config {
+				c := Default()
+				c.AI.Cache.Mode = "always"
+				return c
+			}(),
+			expectErr: true,
+		},
+		{
+			name: "Negative cache ttl",
+			config: func() *Config {
+				c := Default()
+				ttl := -time.Hour
+				c.AI.Cache.TTL = &ttl
+				return c
+			}(),
+			expectErr: true,
+		},
+		{
+			name: "Invalid grounding mode",
+			config: func() *Config {
+				c := Default()
+				c.AI.Grounding.Mode = "strict"
+				return c
+			}(),
+			expectErr: true,
+		},
+		{
+			name: "Grounding min_score above 1",
+			config: func() *Config {
+				c := Default()
+				minScore := 1.5
+				c.AI.Grounding.MinScore = &minScore
+				return c
+			}(),
+			expectErr: true,
+		},
+		{
+			name: "Invalid output check mode",
+			config: func() *Config {
+				c := Default()
+				c.AI.OutputCheck = "strict"
+				return c
+			}(),
+			expectErr: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -467,3 +732,91 @@ func TestValidate(t *testing.T) {
 		})
 	}
 }
+
+func TestStages(t *testing.T) {
+	cfg := Default()
+	cfg.AI.APIKeyEnv = "MY_CEREBRAS_KEY"
+
+	stages := cfg.Stages()
+	if len(stages) != 1 || stages[0].Name != "discovery" || stages[0].Model != cfg.AI.Model {
+		t.Fatalf("Expected only discovery by default, got %+v", stages)
+	}
+
+	cfg.AI.Polish.Enabled = true
+	cfg.AI.Polish.PolishModel = "llama-3.3-70b"
+	cfg.AI.Polish.PolishTemperature = 0
+	stages = cfg.Stages()
+	if len(stages) != 2 {
+		t.Fatalf("Expected discovery and polish, got %+v", stages)
+	}
+	polish := stages[1]
+	if polish.Preset != "polish" || polish.Model != "llama-3.3-70b" || *polish.Temperature != 0 ||
+		polish.MaxTokens != cfg.AI.Polish.PolishMaxTokens || polish.APIKeyEnv != "MY_CEREBRAS_KEY" {
+		t.Errorf("Unexpected polish preset: %+v", polish)
+	}
+
+	cfg.AI.Pipeline = []StageConfig{
+		{Preset: "discovery"},
+		{Name: "translate", Provider: "openai", PromptTemplate: "translate.tmpl"},
+	}
+	stages = cfg.Stages()
+	if len(stages) != 3 || stages[1].Preset != "polish" || stages[2].Name != "translate" {
+		t.Fatalf("Expected polish inserted after discovery, got %+v", stages)
+	}
+	translate := stages[2]
+	if translate.Model != "gpt-4o-mini" || translate.APIKeyEnv != "OPENAI_API_KEY" ||
+		*translate.Temperature != cfg.AI.Temperature || translate.MaxTokens != cfg.AI.MaxTokens {
+		t.Errorf("Expected the other provider's defaults for translate, got %+v", translate)
+	}
+
+	cfg.AI.Pipeline = []StageConfig{{Name: "critique", PromptTemplate: "critique.tmpl"}}
+	stages = cfg.Stages()
+	if len(stages) != 2 || stages[1].Preset != "polish" {
+		t.Errorf("Expected polish last in a pipeline without discovery, got %+v", stages)
+	}
+}
+
+func TestEnsembleCandidates(t *testing.T) {
+	cfg := Default()
+	cfg.AI.APIKeyEnv = "MY_CEREBRAS_KEY"
+	cfg.AI.Ensemble.Candidates = []CandidateConfig{
+		{Provider: "cerebras"},
+		{Provider: "groq"},
+		{Provider: "openai", Model: "gpt-4o", APIKeyEnv: "TEAM_OPENAI_KEY"},
+	}
+
+	candidates := cfg.EnsembleCandidates()
+	want := []CandidateConfig{
+		{Provider: "cerebras", Model: cfg.AI.Model, APIKeyEnv: "MY_CEREBRAS_KEY"},
+		{Provider: "groq", Model: "llama-3.3-70b-versatile", APIKeyEnv: "GROQ_API_KEY"},
+		{Provider: "openai", Model: "gpt-4o", APIKeyEnv: "TEAM_OPENAI_KEY"},
+	}
+	for i := range want {
+		if candidates[i] != want[i] {
+			t.Errorf("Candidate %d = %+v, want %+v", i+1, candidates[i], want[i])
+		}
+	}
+
+	if judge := cfg.EnsembleJudge(); judge.Provider != "cerebras" || judge.Model != cfg.AI.Model {
+		t.Errorf("Expected the judge to default to the main provider and model, got %+v", judge)
+	}
+}
+
+func TestCustomFor(t *testing.T) {
+	cfg := Default()
+	cfg.AI.Provider = "azure-openai"
+	cfg.AI.Model = "gpt-4o"
+	cfg.AI.Custom = map[string]string{"azure_resource": "contoso", "azure_deployment": "notes-prod"}
+
+	if got := cfg.CustomFor("gpt-4o"); got["azure_deployment"] != "notes-prod" {
+		t.Errorf("Expected the main model to keep azure_deployment, got %v", got)
+	}
+
+	got := cfg.CustomFor("gpt-4o-mini")
+	if _, ok := got["azure_deployment"]; ok || got["azure_resource"] != "contoso" {
+		t.Errorf("Expected only azure_deployment left out for another model, got %v", got)
+	}
+	if cfg.AI.Custom["azure_deployment"] != "notes-prod" {
+		t.Error("Expected ai.custom to be unchanged")
+	}
+}
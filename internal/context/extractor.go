@@ -1,75 +1,352 @@
 package context
 
 import (
+	"bufio"
+	"errors"
+	"go/parser"
+	"go/token"
+	"os"
 	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
 
+	"github.com/1broseidon/promptext-notes/internal/ai"
+	"github.com/1broseidon/promptext-notes/internal/diff"
+	"github.com/1broseidon/promptext-notes/internal/git"
 	"github.com/1broseidon/promptext/pkg/promptext"
 	"github.com/bmatcuk/doublestar/v4"
 )
 
-// ExtractCodeContext extracts code context from changed files using promptext.
-// It focuses on relevant file types (.go, .md, .yml, .yaml) and applies a token budget.
-// The excludePatterns parameter allows filtering out specific files using glob patterns.
-func ExtractCodeContext(changedFiles []string, excludePatterns []string) (*promptext.Result, error) {
-	// Focus on code and documentation files
-	relevantExts := []string{".go", ".md", ".yml", ".yaml"}
+// Reasons a file was included in the code context
+const (
+	ReasonChanged      = "changed"
+	ReasonChangedDecls = "changed declarations"
+	ReasonSamePackage  = "same package"
+	ReasonImported     = "imported"
+	ReasonOverview     = "project overview"
+)
+
+// ContextFile records a file included in the code context and why
+type ContextFile struct {
+	Path   string
+	Reason string
+	Via    string // The changed file that led to a related file, empty otherwise
+	Tokens int
+}
+
+// Default token budgets for code context extraction
+const (
+	DefaultTokenBudget    = 8000
+	DefaultOverviewBudget = 4000
+)
+
+// DefaultInclude lists the files considered when no include patterns are given
+var DefaultInclude = []string{"*.go", "*.md", "*.yml", "*.yaml"}
+
+// ExtractOptions configures code context extraction
+type ExtractOptions struct {
+	Include        []string // Glob patterns of files to consider (default: DefaultInclude)
+	Exclude        []string // Glob patterns of files to leave out (default: CHANGELOG.md, README.md)
+	TokenBudget    int      // Budget for changed and related files (default: DefaultTokenBudget)
+	OverviewBudget int      // Budget for the project overview (default: DefaultOverviewBudget)
+
+	// Diff, when given, narrows changed Go files to the declarations their
+	// hunks touch. SinceRef is the ref the diff starts at, used to show the
+	// previous version of changed exported APIs.
+	Diff     []diff.File
+	SinceRef string
+}
+
+// ExtractCodeContext extracts code context for the changed files.
+// It considers files matching the include patterns and applies a token budget:
+// changed files come first, then Go files in the same package as a changed Go file,
+// then files of the module's packages they import, until the budget is spent.
+// Changed Go files with a diff are reduced to the declarations that changed.
+// Files matching the exclude patterns are left out.
+// If no changed file is relevant, a project overview is extracted instead.
+func ExtractCodeContext(changedFiles []string, opts ExtractOptions) (*promptext.Result, []ContextFile, error) {
+	include := opts.Include
+	if len(include) == 0 {
+		include = DefaultInclude
+	}
 
 	// Default exclusions if none provided
+	excludePatterns := opts.Exclude
 	if len(excludePatterns) == 0 {
 		excludePatterns = []string{"CHANGELOG.md", "README.md"}
 	}
 
-	// Filter changed files by extension and exclude patterns
+	relevant := func(file string) bool {
+		return Selected(file, include, excludePatterns)
+	}
+
+	// Filter changed files by include and exclude patterns
 	var relevantFiles []string
 	for _, file := range changedFiles {
-		// Check if file matches any exclude pattern (supports globs)
-		excluded := false
-		for _, pattern := range excludePatterns {
-			// Try glob match first
-			matched, err := doublestar.Match(pattern, file)
-			if err == nil && matched {
-				excluded = true
-				break
-			}
-			// Fallback to basename exact match for backwards compatibility
-			if filepath.Base(file) == pattern {
-				excluded = true
-				break
-			}
+		if relevant(file) {
+			relevantFiles = append(relevantFiles, file)
 		}
-		if excluded {
-			continue
+	}
+
+	if len(relevantFiles) == 0 {
+		overviewBudget := opts.OverviewBudget
+		if overviewBudget <= 0 {
+			overviewBudget = DefaultOverviewBudget
+		}
+		return extractOverview(include, excludePatterns, overviewBudget)
+	}
+
+	tokenBudget := opts.TokenBudget
+	if tokenBudget <= 0 {
+		tokenBudget = DefaultTokenBudget
+	}
+	candidates := relatedFiles(relevantFiles, relevant)
+
+	diffs := make(map[string]*diff.File)
+	for i := range opts.Diff {
+		diffs[opts.Diff[i].Path] = &opts.Diff[i]
+	}
+
+	infos := []promptext.FileInfo{}
+	var included []ContextFile
+	used := 0
+	for _, candidate := range candidates {
+		data, err := os.ReadFile(candidate.Path)
+		if err != nil {
+			continue // Deleted in this range or unreadable
 		}
+		content := string(data)
 
-		ext := filepath.Ext(file)
-		for _, relevantExt := range relevantExts {
-			if ext == relevantExt {
-				relevantFiles = append(relevantFiles, file)
-				break
+		if f := diffs[candidate.Path]; f != nil && candidate.Reason == ReasonChanged {
+			if decls, ok := changedDeclarations(f, content, opts.SinceRef); ok {
+				content = decls
+				candidate.Reason = ReasonChangedDecls
 			}
 		}
+
+		tokens := ai.CountTokens(content)
+		if used+tokens > tokenBudget {
+			continue // A smaller file further down may still fit
+		}
+		used += tokens
+
+		candidate.Tokens = tokens
+		included = append(included, candidate)
+		infos = append(infos, promptext.FileInfo{
+			Path:    candidate.Path,
+			Content: content,
+			Tokens:  tokens,
+		})
 	}
 
-	// Determine token budget based on number of relevant files
-	tokenBudget := 8000
-	if len(relevantFiles) == 0 {
-		// If no relevant files, get project summary with smaller budget
-		tokenBudget = 4000
+	result := &promptext.Result{
+		ProjectOutput: &promptext.ProjectOutput{
+			Files: infos,
+			Budget: &promptext.BudgetInfo{
+				MaxTokens:       tokenBudget,
+				EstimatedTokens: used,
+			},
+		},
+		TokenCount:  used,
+		TotalTokens: used,
 	}
+	formatted, err := result.As(promptext.FormatPTX)
+	if err != nil {
+		return nil, nil, err
+	}
+	result.FormattedOutput = formatted
+
+	return result, included, nil
+}
+
+// changedDeclarations narrows a changed Go file to the declarations its diff
+// touches. Other languages, files without hunks and files that don't parse are
+// left whole.
+func changedDeclarations(f *diff.File, content, sinceRef string) (string, bool) {
+	if filepath.Ext(f.Path) != ".go" || len(f.Hunks) == 0 {
+		return "", false
+	}
+
+	oldContent := ""
+	if f.Status != "added" && sinceRef != "" {
+		// Without the old version only the new side is shown
+		oldContent, _ = git.ShowFile(sinceRef, f.OldPath)
+	}
+
+	return extractGoDeclarations(f, content, oldContent)
+}
 
-	// Extract context with token budget and exclusions
-	// NOTE: We extract from the full repo (not just changed files) because:
-	// 1. AI needs context from unchanged files (imports, interfaces, related code)
-	// 2. Promptext doesn't support extracting from specific file list
-	// The relevantFiles list is used to determine token budget and can be logged
-	result, err := promptext.Extract(".",
-		promptext.WithExtensions(relevantExts...),
+// extractOverview extracts a project summary from the whole repository with a
+// smaller budget, used when no changed file is relevant
+func extractOverview(include, excludePatterns []string, tokenBudget int) (*promptext.Result, []ContextFile, error) {
+	options := []promptext.Option{
 		promptext.WithTokenBudget(tokenBudget),
 		promptext.WithExcludes(excludePatterns...), // Apply exclude patterns to promptext
-	)
+	}
+	// promptext filters by extension, so only "*.ext" style patterns carry over
+	if exts := includeExtensions(include); len(exts) > 0 {
+		options = append(options, promptext.WithExtensions(exts...))
+	}
+
+	result, err := promptext.Extract(".", options...)
+	if errors.Is(err, promptext.ErrNoFilesMatched) {
+		// Nothing matches the include patterns, continue without code context
+		return &promptext.Result{ProjectOutput: &promptext.ProjectOutput{Files: []promptext.FileInfo{}}}, nil, nil
+	}
 	if err != nil {
-		return nil, err
+		return nil, nil, err
+	}
+
+	var included []ContextFile
+	for _, file := range result.ProjectOutput.Files {
+		included = append(included, ContextFile{Path: file.Path, Reason: ReasonOverview, Tokens: file.Tokens})
 	}
 
-	return result, nil
+	return result, included, nil
+}
+
+// relatedFiles lists candidate files in priority order: the changed files,
+// then same-package Go files, then Go files of imported packages in the module.
+// Each file appears once, with the first reason found.
+func relatedFiles(changed []string, relevant func(string) bool) []ContextFile {
+	seen := make(map[string]bool)
+	var candidates []ContextFile
+	add := func(path, reason, via string) {
+		path = filepath.ToSlash(filepath.Clean(path))
+		if seen[path] || !relevant(path) {
+			return
+		}
+		seen[path] = true
+		candidates = append(candidates, ContextFile{Path: path, Reason: reason, Via: via})
+	}
+
+	for _, file := range changed {
+		add(file, ReasonChanged, "")
+	}
+
+	for _, file := range changed {
+		if filepath.Ext(file) != ".go" {
+			continue
+		}
+		for _, sibling := range goFiles(filepath.Dir(file)) {
+			add(sibling, ReasonSamePackage, file)
+		}
+	}
+
+	modulePath := readModulePath("go.mod")
+	if modulePath == "" {
+		return candidates
+	}
+
+	for _, file := range changed {
+		if filepath.Ext(file) != ".go" {
+			continue
+		}
+		for _, dir := range localImports(file, modulePath) {
+			for _, imported := range goFiles(dir) {
+				add(imported, ReasonImported, file)
+			}
+		}
+	}
+
+	return candidates
+}
+
+// goFiles returns the non-test Go files of a directory, sorted by name
+func goFiles(dir string) []string {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+
+	var files []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || filepath.Ext(name) != ".go" || strings.HasSuffix(name, "_test.go") {
+			continue
+		}
+		files = append(files, filepath.Join(dir, name))
+	}
+	sort.Strings(files)
+	return files
+}
+
+// localImports returns the directories of packages imported by a Go file that
+// belong to the module
+func localImports(file, modulePath string) []string {
+	parsed, err := parser.ParseFile(token.NewFileSet(), file, nil, parser.ImportsOnly)
+	if err != nil {
+		return nil
+	}
+
+	var dirs []string
+	for _, imp := range parsed.Imports {
+		importPath, err := strconv.Unquote(imp.Path.Value)
+		if err != nil {
+			continue
+		}
+		if importPath == modulePath {
+			dirs = append(dirs, ".")
+		} else if rest, ok := strings.CutPrefix(importPath, modulePath+"/"); ok {
+			dirs = append(dirs, filepath.FromSlash(rest))
+		}
+	}
+	return dirs
+}
+
+// readModulePath returns the module path declared in a go.mod file
+func readModulePath(goMod string) string {
+	f, err := os.Open(goMod)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if rest, ok := strings.CutPrefix(line, "module "); ok {
+			return strings.Trim(strings.TrimSpace(rest), `"`)
+		}
+	}
+	return ""
+}
+
+// Selected reports whether a file matches one of the include patterns (all
+// files when there are none) and none of the exclude patterns
+func Selected(file string, include, exclude []string) bool {
+	if len(include) > 0 && !matchesAny(file, include) {
+		return false
+	}
+	return !matchesAny(file, exclude)
+}
+
+// matchesAny checks if a file matches any glob pattern. Patterns without a
+// slash also match the file's base name, so "*.ts" matches "web/src/app.ts".
+func matchesAny(file string, patterns []string) bool {
+	base := filepath.Base(file)
+	for _, pattern := range patterns {
+		if matched, err := doublestar.Match(pattern, file); err == nil && matched {
+			return true
+		}
+		if !strings.Contains(pattern, "/") {
+			if matched, err := doublestar.Match(pattern, base); err == nil && matched {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// includeExtensions returns the extensions of "*.ext" and "**/*.ext" patterns
+func includeExtensions(include []string) []string {
+	var exts []string
+	for _, pattern := range include {
+		pattern = strings.TrimPrefix(pattern, "**/")
+		if ext, ok := strings.CutPrefix(pattern, "*."); ok && !strings.ContainsAny(ext, "*?[{/") {
+			exts = append(exts, "."+ext)
+		}
+	}
+	return exts
 }
@@ -3,6 +3,7 @@ package context
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -49,7 +50,7 @@ func TestExtractCodeContext(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result, err := ExtractCodeContext(tt.changedFiles, nil) // nil uses default exclusions
+			result, _, err := ExtractCodeContext(tt.changedFiles, ExtractOptions{}) // Empty options use the defaults
 			if (err != nil) != tt.wantErr {
 				t.Errorf("ExtractCodeContext() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -87,7 +88,7 @@ func TestExtractCodeContextFileFiltering(t *testing.T) {
 		"package-lock.json", // Should be filtered out
 	}
 
-	result, err := ExtractCodeContext(changedFiles, nil) // nil uses default exclusions
+	result, _, err := ExtractCodeContext(changedFiles, ExtractOptions{}) // Empty options use the defaults
 	if err != nil {
 		t.Fatalf("ExtractCodeContext() unexpected error: %v", err)
 	}
@@ -134,7 +135,7 @@ func TestExtractCodeContextInTestDirectory(t *testing.T) {
 
 	// Test with files that exist
 	changedFiles := []string{"test.go", "README.md", "config.yml"}
-	result, err := ExtractCodeContext(changedFiles, nil) // nil uses default exclusions
+	result, _, err := ExtractCodeContext(changedFiles, ExtractOptions{}) // Empty options use the defaults
 	if err != nil {
 		t.Fatalf("ExtractCodeContext() unexpected error: %v", err)
 	}
@@ -168,7 +169,7 @@ func TestExtractCodeContextTokenBudget(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result, err := ExtractCodeContext(tt.changedFiles, nil) // nil uses default exclusions
+			result, _, err := ExtractCodeContext(tt.changedFiles, ExtractOptions{}) // Empty options use the defaults
 			if err != nil {
 				t.Fatalf("ExtractCodeContext() unexpected error: %v", err)
 			}
@@ -182,3 +183,168 @@ func TestExtractCodeContextTokenBudget(t *testing.T) {
 		})
 	}
 }
+
+func TestExtractCodeContextRelatedFiles(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	testFiles := map[string]string{
+		"go.mod":                 "module example.com/app\n\ngo 1.24\n",
+		"cmd/app/main.go":        "package main\n\nimport (\n\t\"fmt\"\n\n\t\"example.com/app/internal/store\"\n)\n\nfunc main() { fmt.Println(store.Get()) }\n",
+		"cmd/app/flags.go":       "package main\n\nvar verbose bool\n",
+		"cmd/app/main_test.go":   "package main\n",
+		"internal/store/get.go":  "package store\n\nfunc Get() string { return \"\" }\n",
+		"internal/store/put.go":  "package store\n\nfunc Put(string) {}\n",
+		"internal/other/skip.go": "package other\n",
+	}
+	for name, content := range testFiles {
+		path := filepath.Join(tmpDir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to create test file: %v", err)
+		}
+	}
+
+	origDir, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get working directory: %v", err)
+	}
+	defer os.Chdir(origDir)
+
+	if err := os.Chdir(tmpDir); err != nil {
+		t.Fatalf("Failed to change directory: %v", err)
+	}
+
+	result, files, err := ExtractCodeContext([]string{"cmd/app/main.go", "docs/removed.md"}, ExtractOptions{})
+	if err != nil {
+		t.Fatalf("ExtractCodeContext() unexpected error: %v", err)
+	}
+
+	want := []ContextFile{
+		{Path: "cmd/app/main.go", Reason: ReasonChanged},
+		{Path: "cmd/app/flags.go", Reason: ReasonSamePackage, Via: "cmd/app/main.go"},
+		{Path: "internal/store/get.go", Reason: ReasonImported, Via: "cmd/app/main.go"},
+		{Path: "internal/store/put.go", Reason: ReasonImported, Via: "cmd/app/main.go"},
+	}
+	if len(files) != len(want) {
+		t.Fatalf("Expected %d files, got %+v", len(want), files)
+	}
+	for i, file := range files {
+		if file.Path != want[i].Path || file.Reason != want[i].Reason || file.Via != want[i].Via {
+			t.Errorf("File %d = %+v, want %+v", i, file, want[i])
+		}
+		if file.Tokens <= 0 {
+			t.Errorf("Expected a token count for %s", file.Path)
+		}
+	}
+
+	if len(result.ProjectOutput.Files) != len(want) {
+		t.Errorf("Expected %d files in the result, got %d", len(want), len(result.ProjectOutput.Files))
+	}
+	if !strings.Contains(result.FormattedOutput, "func Put(string) {}") {
+		t.Error("Expected imported package files in the formatted output")
+	}
+	if strings.Contains(result.FormattedOutput, "package other") {
+		t.Error("Unrelated files must not be extracted")
+	}
+}
+
+func TestSelected(t *testing.T) {
+	include := []string{"*.go", "*.ts", "services/**/*.py"}
+	exclude := []string{"*_test.go", "vendor/**", "CHANGELOG.md"}
+
+	tests := []struct {
+		file string
+		want bool
+	}{
+		{"main.go", true},
+		{"internal/ai/cost.go", true},
+		{"internal/ai/cost_test.go", false},
+		{"web/src/app.ts", true},
+		{"services/api/handlers.py", true},
+		{"scripts/release.py", false},
+		{"vendor/github.com/pkg/errors/errors.go", false},
+		{"CHANGELOG.md", false},
+		{"README.md", false},
+	}
+
+	for _, tt := range tests {
+		if got := Selected(tt.file, include, exclude); got != tt.want {
+			t.Errorf("Selected(%q) = %v, want %v", tt.file, got, tt.want)
+		}
+	}
+
+	if !Selected("anything.bin", nil, nil) {
+		t.Error("Without include patterns every file should be selected")
+	}
+}
+
+func TestIncludeExtensions(t *testing.T) {
+	got := includeExtensions([]string{"*.go", "**/*.ts", "docs/*.md", "Makefile", "*.{js,jsx}"})
+	if strings.Join(got, ",") != ".go,.ts" {
+		t.Errorf("includeExtensions() = %v", got)
+	}
+}
+
+func TestExtractCodeContextIncludePatterns(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	testFiles := map[string]string{
+		"web/src/app.ts":        "export const app = () => 'hello';\n",
+		"services/api/main.py":  "def main():\n    return 'hello'\n",
+		"services/api/large.py": strings.Repeat("value = compute('a fairly long line of python')\n", 400),
+	}
+	for name, content := range testFiles {
+		path := filepath.Join(tmpDir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to create test file: %v", err)
+		}
+	}
+
+	origDir, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get working directory: %v", err)
+	}
+	defer os.Chdir(origDir)
+
+	if err := os.Chdir(tmpDir); err != nil {
+		t.Fatalf("Failed to change directory: %v", err)
+	}
+
+	changed := []string{"services/api/large.py", "web/src/app.ts", "services/api/main.py"}
+
+	// The default include patterns have no TypeScript or Python
+	_, files, err := ExtractCodeContext(changed, ExtractOptions{})
+	if err != nil {
+		t.Fatalf("ExtractCodeContext() unexpected error: %v", err)
+	}
+	for _, file := range files {
+		if file.Reason == ReasonChanged {
+			t.Errorf("Unexpected changed file with default includes: %s", file.Path)
+		}
+	}
+
+	// With include patterns and a small budget, files that fit are kept
+	result, files, err := ExtractCodeContext(changed, ExtractOptions{
+		Include:     []string{"*.ts", "*.py"},
+		TokenBudget: 500,
+	})
+	if err != nil {
+		t.Fatalf("ExtractCodeContext() unexpected error: %v", err)
+	}
+
+	var paths []string
+	for _, file := range files {
+		paths = append(paths, file.Path)
+	}
+	if strings.Join(paths, ",") != "web/src/app.ts,services/api/main.py" {
+		t.Errorf("Expected the files within budget, got %v", paths)
+	}
+	if result.TokenCount > 500 {
+		t.Errorf("TokenCount %d exceeds the budget", result.TokenCount)
+	}
+}
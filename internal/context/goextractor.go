@@ -0,0 +1,228 @@
+package context
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"sort"
+	"strings"
+
+	"github.com/1broseidon/promptext-notes/internal/diff"
+)
+
+// goDecl is a top-level Go declaration (or one spec of a grouped declaration)
+type goDecl struct {
+	key       string // e.g. "func Parse", "method (*File).Text", "type File"
+	exported  bool
+	text      string // Source including the doc comment
+	startLine int    // First line, including the doc comment
+	endLine   int
+}
+
+// extractGoDeclarations renders the declarations touched by a file's diff
+// hunks: the new version of each changed declaration, the old version as well
+// for changed exported APIs, and removed declarations. oldSource is optional;
+// without it only the new side is shown. The second return value is false when
+// the file can't be parsed, so the caller can fall back to the whole file.
+func extractGoDeclarations(f *diff.File, newSource, oldSource string) (string, bool) {
+	newDecls, ok := parseGoDecls(newSource)
+	if !ok {
+		return "", false
+	}
+
+	var oldDecls []goDecl
+	if oldSource != "" {
+		oldDecls, _ = parseGoDecls(oldSource)
+	}
+
+	oldLines, newLines := f.ChangedLines()
+	changed := make(map[string]bool)
+	for _, decl := range touchedDecls(newDecls, newLines) {
+		changed[decl.key] = true
+	}
+	for _, decl := range touchedDecls(oldDecls, oldLines) {
+		changed[decl.key] = true
+	}
+
+	oldByKey := make(map[string]goDecl)
+	for _, decl := range oldDecls {
+		oldByKey[decl.key] = decl
+	}
+	newKeys := make(map[string]bool)
+	for _, decl := range newDecls {
+		newKeys[decl.key] = true
+	}
+
+	var out strings.Builder
+	for _, decl := range newDecls {
+		if !changed[decl.key] {
+			continue
+		}
+
+		old, existed := oldByKey[decl.key]
+		switch {
+		case !existed && len(oldDecls) > 0:
+			fmt.Fprintf(&out, "// Added: %s\n%s\n\n", decl.key, decl.text)
+		case existed && decl.exported && old.text != decl.text:
+			fmt.Fprintf(&out, "// Changed exported API: %s\n// Before:\n%s\n// After:\n%s\n\n", decl.key, old.text, decl.text)
+		default:
+			fmt.Fprintf(&out, "// Changed: %s\n%s\n\n", decl.key, decl.text)
+		}
+	}
+
+	for _, decl := range oldDecls {
+		if !changed[decl.key] || newKeys[decl.key] {
+			continue
+		}
+		if decl.exported {
+			fmt.Fprintf(&out, "// Removed exported API: %s\n%s\n\n", decl.key, decl.text)
+		} else {
+			fmt.Fprintf(&out, "// Removed: %s\n\n", decl.key)
+		}
+	}
+
+	if out.Len() == 0 {
+		// Only imports or the package clause changed
+		return "// No declarations changed (imports or package clause only)\n", true
+	}
+
+	return strings.TrimRight(out.String(), "\n") + "\n", true
+}
+
+// touchedDecls returns the declarations containing any of the given lines
+func touchedDecls(decls []goDecl, lines []int) []goDecl {
+	var touched []goDecl
+	for _, decl := range decls {
+		i := sort.SearchInts(lines, decl.startLine)
+		if i < len(lines) && lines[i] <= decl.endLine {
+			touched = append(touched, decl)
+		}
+	}
+	return touched
+}
+
+// parseGoDecls parses Go source into its top-level declarations. Specs of
+// grouped declarations (const/var/type blocks) are returned individually.
+func parseGoDecls(source string) ([]goDecl, bool) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "", source, parser.ParseComments)
+	if err != nil {
+		return nil, false
+	}
+
+	lineOf := func(pos token.Pos) int { return fset.Position(pos).Line }
+	textOf := func(from, to token.Pos) string {
+		return source[fset.Position(from).Offset:fset.Position(to).Offset]
+	}
+
+	var decls []goDecl
+	for _, d := range file.Decls {
+		switch d := d.(type) {
+		case *ast.FuncDecl:
+			start := d.Pos()
+			if d.Doc != nil {
+				start = d.Doc.Pos()
+			}
+			decls = append(decls, goDecl{
+				key:       funcKey(d),
+				exported:  funcExported(d),
+				text:      textOf(start, d.End()),
+				startLine: lineOf(start),
+				endLine:   lineOf(d.End()),
+			})
+
+		case *ast.GenDecl:
+			if d.Tok == token.IMPORT {
+				continue
+			}
+			for _, spec := range d.Specs {
+				// Specs of a group are shown on their own with the keyword prepended
+				start, end, doc := spec.Pos(), spec.End(), specDoc(spec)
+				text := d.Tok.String() + " " + textOf(start, end)
+				if !d.Lparen.IsValid() {
+					start, end, doc = d.Pos(), d.End(), d.Doc
+					text = textOf(start, end)
+				}
+				if doc != nil {
+					text = textOf(doc.Pos(), doc.End()) + "\n" + text
+					start = doc.Pos()
+				}
+
+				name, exported := specName(spec)
+				decls = append(decls, goDecl{
+					key:       d.Tok.String() + " " + name,
+					exported:  exported,
+					text:      text,
+					startLine: lineOf(start),
+					endLine:   lineOf(end),
+				})
+			}
+		}
+	}
+
+	return decls, true
+}
+
+// funcKey names a function or method declaration
+func funcKey(d *ast.FuncDecl) string {
+	if d.Recv == nil || len(d.Recv.List) == 0 {
+		return "func " + d.Name.Name
+	}
+	return fmt.Sprintf("method (%s).%s", recvType(d.Recv.List[0].Type), d.Name.Name)
+}
+
+// funcExported reports whether a function, or a method on an exported type, is exported
+func funcExported(d *ast.FuncDecl) bool {
+	if !d.Name.IsExported() {
+		return false
+	}
+	if d.Recv == nil || len(d.Recv.List) == 0 {
+		return true
+	}
+	return ast.IsExported(strings.TrimLeft(recvType(d.Recv.List[0].Type), "*"))
+}
+
+// recvType renders a receiver type expression such as *File or List[T]
+func recvType(expr ast.Expr) string {
+	switch t := expr.(type) {
+	case *ast.StarExpr:
+		return "*" + recvType(t.X)
+	case *ast.Ident:
+		return t.Name
+	case *ast.IndexExpr:
+		return recvType(t.X)
+	case *ast.IndexListExpr:
+		return recvType(t.X)
+	default:
+		return "?"
+	}
+}
+
+// specDoc returns the doc comment of a spec inside a grouped declaration
+func specDoc(spec ast.Spec) *ast.CommentGroup {
+	switch s := spec.(type) {
+	case *ast.TypeSpec:
+		return s.Doc
+	case *ast.ValueSpec:
+		return s.Doc
+	}
+	return nil
+}
+
+// specName returns the names declared by a spec and whether any is exported
+func specName(spec ast.Spec) (string, bool) {
+	switch s := spec.(type) {
+	case *ast.TypeSpec:
+		return s.Name.Name, s.Name.IsExported()
+	case *ast.ValueSpec:
+		var names []string
+		exported := false
+		for _, name := range s.Names {
+			names = append(names, name.Name)
+			exported = exported || name.IsExported()
+		}
+		return strings.Join(names, ", "), exported
+	}
+	return "", false
+}
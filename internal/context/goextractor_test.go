@@ -0,0 +1,173 @@
+package context
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/1broseidon/promptext-notes/internal/diff"
+)
+
+const oldGoSource = `package store
+
+import "errors"
+
+// ErrNotFound is returned for missing keys
+var ErrNotFound = errors.New("not found")
+
+// Get returns the value for a key
+func Get(key string) (string, error) {
+	return "", ErrNotFound
+}
+
+func helper() int {
+	return 1
+}
+
+// Legacy is going away
+func Legacy() {}
+`
+
+const newGoSource = `package store
+
+import "errors"
+
+// ErrNotFound is returned for missing keys
+var ErrNotFound = errors.New("not found")
+
+// Get returns the value for a key, or def when it is missing
+func Get(key, def string) (string, error) {
+	return def, nil
+}
+
+func helper() int {
+	return 2
+}
+
+// Put stores a value
+func Put(key, value string) {}
+`
+
+const goSourceDiff = `diff --git a/store/store.go b/store/store.go
+index 1111111..2222222 100644
+--- a/store/store.go
++++ b/store/store.go
+@@ -7,5 +7,5 @@ var ErrNotFound = errors.New("not found")
+
+-// Get returns the value for a key
+-func Get(key string) (string, error) {
+-	return "", ErrNotFound
++// Get returns the value for a key, or def when it is missing
++func Get(key, def string) (string, error) {
++	return def, nil
+ }
+@@ -13,7 +13,7 @@ func Get(key string) (string, error) {
+ func helper() int {
+-	return 1
++	return 2
+ }
+
+-// Legacy is going away
+-func Legacy() {}
++// Put stores a value
++func Put(key, value string) {}
+`
+
+func TestExtractGoDeclarations(t *testing.T) {
+	files := diff.Parse(goSourceDiff)
+	if len(files) != 1 {
+		t.Fatalf("Expected 1 file, got %d", len(files))
+	}
+
+	got, ok := extractGoDeclarations(&files[0], newGoSource, oldGoSource)
+	if !ok {
+		t.Fatal("Expected the source to parse")
+	}
+
+	wants := []string{
+		"// Changed exported API: func Get\n// Before:\n// Get returns the value for a key\nfunc Get(key string)",
+		"// After:\n// Get returns the value for a key, or def when it is missing\nfunc Get(key, def string)",
+		"// Changed: func helper\nfunc helper() int {\n\treturn 2\n}",
+		"// Added: func Put\n// Put stores a value\nfunc Put(key, value string) {}",
+		"// Removed exported API: func Legacy\n// Legacy is going away\nfunc Legacy() {}",
+	}
+	for _, want := range wants {
+		if !strings.Contains(got, want) {
+			t.Errorf("Expected output to contain %q, got:\n%s", want, got)
+		}
+	}
+
+	if strings.Contains(got, "ErrNotFound = errors.New") {
+		t.Errorf("Unchanged declarations should be left out, got:\n%s", got)
+	}
+}
+
+func TestExtractGoDeclarationsWithoutOldSource(t *testing.T) {
+	files := diff.Parse(goSourceDiff)
+
+	got, ok := extractGoDeclarations(&files[0], newGoSource, "")
+	if !ok {
+		t.Fatal("Expected the source to parse")
+	}
+	if strings.Contains(got, "Before:") || strings.Contains(got, "Legacy") {
+		t.Errorf("Only the new side should be shown without the old source, got:\n%s", got)
+	}
+	if !strings.Contains(got, "// Changed: func Get") || !strings.Contains(got, "// Changed: func Put") {
+		t.Errorf("Expected the changed declarations of the new side, got:\n%s", got)
+	}
+}
+
+func TestExtractGoDeclarationsParseError(t *testing.T) {
+	files := diff.Parse(goSourceDiff)
+
+	if _, ok := extractGoDeclarations(&files[0], "package store\nfunc {", ""); ok {
+		t.Error("Expected unparseable source to fall back to the whole file")
+	}
+}
+
+func TestParseGoDecls(t *testing.T) {
+	source := `package p
+
+const (
+	// A is exported
+	A = 1
+	b = 2
+)
+
+type T struct{}
+
+// M is a method
+func (t *T) M() {}
+
+func (t t2) m() {}
+`
+	decls, ok := parseGoDecls(source)
+	if !ok {
+		t.Fatal("Expected the source to parse")
+	}
+
+	want := []struct {
+		key      string
+		exported bool
+		start    int
+		end      int
+	}{
+		{"const A", true, 4, 5},
+		{"const b", false, 6, 6},
+		{"type T", true, 9, 9},
+		{"method (*T).M", true, 11, 12},
+		{"method (t2).m", false, 14, 14},
+	}
+	if len(decls) != len(want) {
+		t.Fatalf("Expected %d declarations, got %d: %+v", len(want), len(decls), decls)
+	}
+	for i, w := range want {
+		d := decls[i]
+		if d.key != w.key || d.exported != w.exported || d.startLine != w.start || d.endLine != w.end {
+			t.Errorf("Declaration %d = %s (exported %v, lines %d-%d), want %s (exported %v, lines %d-%d)",
+				i, d.key, d.exported, d.startLine, d.endLine, w.key, w.exported, w.start, w.end)
+		}
+	}
+	if decls[0].text != "// A is exported\nconst A = 1" {
+		t.Errorf("Grouped specs should get their keyword and doc comment, got %q", decls[0].text)
+	}
+}
@@ -0,0 +1,211 @@
+package deps
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/1broseidon/promptext-notes/internal/git"
+)
+
+// Kinds of dependency change
+const (
+	KindAdded      = "added"
+	KindRemoved    = "removed"
+	KindUpgraded   = "upgraded"
+	KindDowngraded = "downgraded"
+	KindChanged    = "changed" // Versions that can't be ordered, e.g. ranges or git refs
+)
+
+// Change is a dependency added, removed or moved to another version in a manifest
+type Change struct {
+	Manifest string // Path of the manifest, e.g. "go.mod" or "web/package.json"
+	Name     string // Dependency name; "go" and "toolchain" for go.mod directives
+	Kind     string
+	From     string // Empty when added
+	To       string // Empty when removed
+}
+
+// String describes the change as a release notes item
+func (c Change) String() string {
+	name := "`" + c.Name + "`"
+	switch c.Name {
+	case goDirective:
+		name = "Go version"
+	case toolchainDirective:
+		name = "Go toolchain"
+	}
+
+	var change string
+	switch c.Kind {
+	case KindAdded:
+		change = fmt.Sprintf("%s %s added", name, c.To)
+	case KindRemoved:
+		change = fmt.Sprintf("%s %s removed", name, c.From)
+	default:
+		change = fmt.Sprintf("%s %s %s → %s", name, c.Kind, c.From, c.To)
+	}
+	return fmt.Sprintf("%s (%s)", change, c.Manifest)
+}
+
+// manifests maps supported manifest file names to their parsers, in report order
+var manifests = []struct {
+	name  string
+	parse func(content string) map[string]string
+}{
+	{"go.mod", parseGoMod},
+	{"package.json", parsePackageJSON},
+	{"requirements.txt", parseRequirements},
+	{"Cargo.toml", parseCargoToml},
+}
+
+// Detect compares the dependency manifests among the changed files between the
+// since ref and HEAD. Changes are grouped by manifest type (go.mod first, then
+// package.json, requirements.txt and Cargo.toml) and sorted by name.
+func Detect(since string, changedFiles []string) ([]Change, error) {
+	var changes []Change
+	for _, manifest := range manifests {
+		var files []string
+		for _, file := range changedFiles {
+			if path.Base(file) == manifest.name {
+				files = append(files, file)
+			}
+		}
+		sort.Strings(files)
+
+		for _, file := range files {
+			// A manifest missing on one side was added or deleted in this range
+			before, _ := git.ShowFile(since, file)
+			after, err := git.ShowFile("HEAD", file)
+			if err != nil && before == "" {
+				return nil, err
+			}
+			changes = append(changes, Compare(file, manifest.parse(before), manifest.parse(after))...)
+		}
+	}
+	return changes, nil
+}
+
+// Compare lists the differences between two dependency → version maps
+func Compare(manifest string, before, after map[string]string) []Change {
+	names := make(map[string]bool)
+	for name := range before {
+		names[name] = true
+	}
+	for name := range after {
+		names[name] = true
+	}
+	sorted := make([]string, 0, len(names))
+	for name := range names {
+		sorted = append(sorted, name)
+	}
+	// go.mod directives come before the dependencies
+	sort.Slice(sorted, func(i, j int) bool {
+		if directiveOrder(sorted[i]) != directiveOrder(sorted[j]) {
+			return directiveOrder(sorted[i]) < directiveOrder(sorted[j])
+		}
+		return sorted[i] < sorted[j]
+	})
+
+	var changes []Change
+	for _, name := range sorted {
+		from, had := before[name]
+		to, has := after[name]
+		change := Change{Manifest: manifest, Name: name, From: from, To: to}
+		switch {
+		case !had:
+			change.Kind = KindAdded
+		case !has:
+			change.Kind = KindRemoved
+		case from == to:
+			continue
+		default:
+			change.Kind = versionChange(from, to)
+		}
+		changes = append(changes, change)
+	}
+	return changes
+}
+
+// directiveOrder sorts the go and toolchain directives first
+func directiveOrder(name string) int {
+	switch name {
+	case goDirective:
+		return 0
+	case toolchainDirective:
+		return 1
+	default:
+		return 2
+	}
+}
+
+// versionChange classifies a version change as an upgrade or downgrade when
+// both versions can be ordered
+func versionChange(from, to string) string {
+	a, okA := parseVersion(from)
+	b, okB := parseVersion(to)
+	if !okA || !okB {
+		return KindChanged
+	}
+
+	for i := 0; i < len(a) || i < len(b); i++ {
+		var x, y int
+		if i < len(a) {
+			x = a[i]
+		}
+		if i < len(b) {
+			y = b[i]
+		}
+		if x != y {
+			if y > x {
+				return KindUpgraded
+			}
+			return KindDowngraded
+		}
+	}
+
+	// Same release, e.g. v1.2.0-rc.1 → v1.2.0, or a new pseudo-version
+	if preA, preB := prerelease(from), prerelease(to); preA != preB {
+		if preA == "" {
+			return KindDowngraded
+		}
+		if preB == "" || preB > preA {
+			return KindUpgraded
+		}
+		return KindDowngraded
+	}
+	return KindChanged
+}
+
+// parseVersion extracts the numeric release parts of a version such as
+// "v1.2.3", "^1.2.0", "==2.31.0" or "go1.24.1"
+func parseVersion(version string) ([]int, bool) {
+	version = strings.TrimLeft(strings.TrimSpace(version), "^~=v ")
+	version = strings.TrimPrefix(version, "go")
+	if i := strings.IndexAny(version, "-+"); i >= 0 {
+		version = version[:i]
+	}
+	if version == "" {
+		return nil, false
+	}
+
+	var parts []int
+	for _, part := range strings.Split(version, ".") {
+		n, err := strconv.Atoi(part)
+		if err != nil {
+			return nil, false
+		}
+		parts = append(parts, n)
+	}
+	return parts, true
+}
+
+// prerelease returns the pre-release or build suffix of a version
+func prerelease(version string) string {
+	if i := strings.IndexAny(version, "-+"); i >= 0 {
+		return version[i+1:]
+	}
+	return ""
+}
@@ -0,0 +1,141 @@
+package deps
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCompareGoMod(t *testing.T) {
+	before := parseGoMod(`module example.com/app
+
+go 1.22
+
+require (
+	github.com/spf13/cobra v1.8.0
+	gopkg.in/yaml.v3 v3.0.1
+	github.com/old/lib v0.3.0
+	golang.org/x/sys v0.10.0 // indirect
+)
+`)
+	after := parseGoMod(`module example.com/app
+
+go 1.24
+
+toolchain go1.24.2
+
+require (
+	github.com/spf13/cobra v1.9.1
+	gopkg.in/yaml.v3 v3.0.0
+	github.com/new/lib v1.0.0
+	golang.org/x/sys v0.20.0 // indirect
+)
+
+require github.com/single/dep v0.1.0
+`)
+
+	var got []string
+	for _, change := range Compare("go.mod", before, after) {
+		got = append(got, change.String())
+	}
+
+	want := []string{
+		"Go version upgraded 1.22 → 1.24 (go.mod)",
+		"Go toolchain go1.24.2 added (go.mod)",
+		"`github.com/new/lib` v1.0.0 added (go.mod)",
+		"`github.com/old/lib` v0.3.0 removed (go.mod)",
+		"`github.com/single/dep` v0.1.0 added (go.mod)",
+		"`github.com/spf13/cobra` upgraded v1.8.0 → v1.9.1 (go.mod)",
+		"`gopkg.in/yaml.v3` downgraded v3.0.1 → v3.0.0 (go.mod)",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Compare() =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestParsePackageJSON(t *testing.T) {
+	got := parsePackageJSON(`{
+  "name": "web",
+  "dependencies": {"react": "^18.2.0"},
+  "devDependencies": {"vite": "~5.0.0"}
+}`)
+	want := map[string]string{"react": "^18.2.0", "vite (dev)": "~5.0.0"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parsePackageJSON() = %v, want %v", got, want)
+	}
+
+	if got := parsePackageJSON("not json"); len(got) != 0 {
+		t.Errorf("Invalid JSON should have no dependencies, got %v", got)
+	}
+}
+
+func TestParseRequirements(t *testing.T) {
+	got := parseRequirements(`# Runtime
+Django==4.2.1
+requests[socks]>=2.31 ; python_version > "3.8"
+numpy
+-r dev.txt
+--index-url https://example.com/simple
+`)
+	want := map[string]string{"django": "==4.2.1", "requests": ">=2.31", "numpy": ""}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseRequirements() = %v, want %v", got, want)
+	}
+}
+
+func TestParseCargoToml(t *testing.T) {
+	got := parseCargoToml(`[package]
+name = "app"
+version = "0.1.0"
+
+[dependencies]
+serde = { version = "1.0", features = ["derive"] }
+anyhow = "1"
+local = { path = "../local" }
+shared.workspace = true
+
+[dependencies.tokio]
+version = "1.35"
+features = ["full"]
+
+[dev-dependencies]
+criterion = "0.5"
+
+[target.'cfg(unix)'.build-dependencies]
+cc = "1.0"
+`)
+	want := map[string]string{
+		"serde":           "1.0",
+		"anyhow":          "1",
+		"local":           "../local",
+		"shared":          "workspace",
+		"tokio":           "1.35",
+		"criterion (dev)": "0.5",
+		"cc (build)":      "1.0",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseCargoToml() = %v, want %v", got, want)
+	}
+}
+
+func TestVersionChange(t *testing.T) {
+	tests := []struct {
+		from, to string
+		want     string
+	}{
+		{"v1.2.3", "v1.10.0", KindUpgraded},
+		{"^18.2.0", "^17.0.2", KindDowngraded},
+		{"==4.2.1", "==4.2.10", KindUpgraded},
+		{"v1.2.0-rc.1", "v1.2.0", KindUpgraded},
+		{"v1.2.0", "v1.2.0-rc.1", KindDowngraded},
+		{"v0.0.0-20240101000000-abcdef", "v0.0.0-20240301000000-123456", KindUpgraded},
+		{">=2.0,<3", ">=2.1", KindChanged},
+		{"1.0", "workspace", KindChanged},
+	}
+
+	for _, tt := range tests {
+		if got := versionChange(tt.from, tt.to); got != tt.want {
+			t.Errorf("versionChange(%q, %q) = %s, want %s", tt.from, tt.to, got, tt.want)
+		}
+	}
+}
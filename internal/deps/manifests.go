@@ -0,0 +1,194 @@
+package deps
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// go.mod directives reported alongside dependencies
+const (
+	goDirective        = "go"
+	toolchainDirective = "toolchain"
+)
+
+// parseGoMod returns the direct requirements of a go.mod file with the go and
+// toolchain directives. Indirect requirements follow the direct ones and are
+// left out.
+func parseGoMod(content string) map[string]string {
+	deps := make(map[string]string)
+	inRequire := false
+	for _, line := range strings.Split(content, "\n") {
+		line, comment, _ := strings.Cut(line, "//")
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		if inRequire {
+			if fields[0] == ")" {
+				inRequire = false
+				continue
+			}
+			addRequirement(deps, fields, comment)
+			continue
+		}
+
+		switch fields[0] {
+		case goDirective, toolchainDirective:
+			if len(fields) == 2 {
+				deps[fields[0]] = fields[1]
+			}
+		case "require":
+			if len(fields) == 2 && fields[1] == "(" {
+				inRequire = true
+			} else {
+				addRequirement(deps, fields[1:], comment)
+			}
+		}
+	}
+	return deps
+}
+
+// addRequirement adds a "module version" requirement unless it is indirect
+func addRequirement(deps map[string]string, fields []string, comment string) {
+	if len(fields) != 2 || strings.TrimSpace(comment) == "indirect" {
+		return
+	}
+	deps[strings.Trim(fields[0], `"`)] = fields[1]
+}
+
+// parsePackageJSON returns the dependencies and devDependencies of a
+// package.json file; dev dependencies are suffixed with " (dev)"
+func parsePackageJSON(content string) map[string]string {
+	var manifest struct {
+		Dependencies    map[string]string `json:"dependencies"`
+		DevDependencies map[string]string `json:"devDependencies"`
+	}
+	deps := make(map[string]string)
+	if err := json.Unmarshal([]byte(content), &manifest); err != nil {
+		return deps
+	}
+
+	for name, version := range manifest.Dependencies {
+		deps[name] = version
+	}
+	for name, version := range manifest.DevDependencies {
+		deps[name+" (dev)"] = version
+	}
+	return deps
+}
+
+// parseRequirements returns the packages of a pip requirements file with their
+// version specifiers ("" when unpinned). Options and includes are skipped.
+func parseRequirements(content string) map[string]string {
+	deps := make(map[string]string)
+	for _, line := range strings.Split(content, "\n") {
+		line, _, _ = strings.Cut(line, "#")
+		line, _, _ = strings.Cut(line, ";") // Environment markers
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "-") {
+			continue
+		}
+
+		i := strings.IndexAny(line, "=<>!~ [")
+		if i < 0 {
+			deps[strings.ToLower(line)] = ""
+			continue
+		}
+		name := strings.ToLower(line[:i])
+		rest := line[i:]
+		if strings.HasPrefix(rest, "[") {
+			// Extras, e.g. requests[socks]>=2.0
+			if end := strings.Index(rest, "]"); end >= 0 {
+				rest = rest[end+1:]
+			}
+		}
+		deps[name] = strings.TrimSpace(rest)
+	}
+	return deps
+}
+
+// parseCargoToml returns the crates of a Cargo.toml file's dependency tables.
+// Dev and build dependencies are suffixed with " (dev)" and " (build)".
+func parseCargoToml(content string) map[string]string {
+	deps := make(map[string]string)
+	suffix, inTable := "", false
+	crate := "" // Set inside a [dependencies.<crate>] table
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		if strings.HasPrefix(line, "[") {
+			table := strings.Trim(line, "[] ")
+			// Drops the prefix of target-specific tables, e.g. [target.'cfg(unix)'.dependencies]
+			for _, kind := range []string{"dev-dependencies", "build-dependencies", "workspace.dependencies", "dependencies"} {
+				if i := strings.Index(table, kind); i >= 0 {
+					table = table[i:]
+					break
+				}
+			}
+
+			table, crate, _ = strings.Cut(table, ".")
+			if table == "workspace" {
+				table, crate, _ = strings.Cut(crate, ".")
+			}
+			inTable = true
+			switch table {
+			case "dependencies":
+				suffix = ""
+			case "dev-dependencies":
+				suffix = " (dev)"
+			case "build-dependencies":
+				suffix = " (build)"
+			default:
+				inTable = false
+			}
+			if inTable && crate != "" {
+				deps[crate+suffix] = ""
+			}
+			continue
+		}
+		if !inTable {
+			continue
+		}
+
+		name, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		name, value = strings.Trim(strings.TrimSpace(name), `"`), strings.TrimSpace(value)
+		if crate != "" {
+			if name == "version" || (name == "git" || name == "path") && deps[crate+suffix] == "" {
+				deps[crate+suffix] = strings.Trim(value, `"`)
+			}
+			continue
+		}
+		if base, ok := strings.CutSuffix(name, ".workspace"); ok {
+			deps[base+suffix] = "workspace"
+			continue
+		}
+		deps[name+suffix] = cargoVersion(value)
+	}
+	return deps
+}
+
+// cargoVersion extracts the version from `"1.0"` or `{ version = "1.0", ... }`;
+// git and path dependencies without a version give their source instead
+func cargoVersion(value string) string {
+	if strings.HasPrefix(value, `"`) {
+		return strings.Trim(value, `"`)
+	}
+
+	inline := strings.Trim(value, "{} ")
+	for _, key := range []string{"version", "git", "path"} {
+		for _, pair := range strings.Split(inline, ",") {
+			k, v, ok := strings.Cut(pair, "=")
+			if ok && strings.TrimSpace(k) == key {
+				return strings.Trim(strings.TrimSpace(v), `"`)
+			}
+		}
+	}
+	return value
+}
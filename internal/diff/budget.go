@@ -0,0 +1,309 @@
+package diff
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/1broseidon/promptext-notes/internal/ai"
+)
+
+// minPartialTokens is the smallest remaining budget worth filling with part of a file
+const minPartialTokens = 200
+
+// Selection is the part of a diff that fits a token budget
+type Selection struct {
+	Text     string // Included diffs in original order, then stats for the rest
+	Included int    // Files included in full
+	Partial  int    // Files included with some hunks omitted
+	Omitted  int    // Files reduced to a stat line because of the budget
+	Dropped  int    // Filtered, lockfile, vendored and binary files (always a stat line)
+	Tokens   int
+}
+
+// Rank orders files by relevance for release notes: by kind (source first,
+// generated last), then by the size of the semantic change. Dropped files are
+// left out.
+func Rank(files []File) []*File {
+	var ranked []*File
+	for i := range files {
+		if !files[i].Dropped() {
+			ranked = append(ranked, &files[i])
+		}
+	}
+
+	sort.SliceStable(ranked, func(i, j int) bool {
+		if ranked[i].Kind != ranked[j].Kind {
+			return ranked[i].Kind < ranked[j].Kind
+		}
+		return ranked[i].SemanticLines > ranked[j].SemanticLines
+	})
+
+	return ranked
+}
+
+// Budget fills maxTokens with the most relevant file diffs. Files that don't
+// fit are included hunk by hunk where possible, otherwise replaced by a
+// one-line stat so the model still knows they changed.
+func Budget(files []File, maxTokens int) Selection {
+	var sel Selection
+	if maxTokens < 0 {
+		maxTokens = 0
+	}
+
+	// Stat lines for files that may be left out come off the budget first
+	reserve := lineTokens(statsHeader(len(files)))
+	for i := range files {
+		reserve += ai.CountTokens(files[i].Stat()) + 1
+	}
+	if reserve > maxTokens/4 {
+		reserve = maxTokens / 4
+	}
+	remaining := maxTokens - reserve
+
+	chosen := make(map[*File]string)
+	for _, f := range Rank(files) {
+		text := f.Text()
+		tokens := ai.CountTokens(text)
+		if tokens <= remaining {
+			chosen[f] = text
+			remaining -= tokens
+			sel.Included++
+			continue
+		}
+
+		if remaining < minPartialTokens {
+			continue
+		}
+
+		if partial, used, ok := partialFile(f, remaining); ok {
+			chosen[f] = partial
+			remaining -= used
+			sel.Partial++
+		}
+	}
+
+	var text strings.Builder
+	var stats []*File
+	for i := range files {
+		f := &files[i]
+		if diffText, ok := chosen[f]; ok {
+			text.WriteString(diffText)
+			continue
+		}
+
+		if f.Dropped() {
+			sel.Dropped++
+		} else {
+			sel.Omitted++
+		}
+		stats = append(stats, f)
+	}
+
+	// The stat lines get what the diffs left: at least the reserve
+	writeStats(&text, stats, reserve+remaining)
+
+	sel.Text = strings.TrimRight(text.String(), "\n")
+	sel.Tokens = ai.CountTokens(sel.Text)
+	return sel
+}
+
+// writeStats lists the files left out of the diff as stat lines within
+// maxTokens. When they don't all fit, the most relevant are listed and the
+// rest are collapsed into per-directory totals.
+func writeStats(text *strings.Builder, files []*File, maxTokens int) {
+	if len(files) == 0 {
+		return
+	}
+
+	header := statsHeader(len(files))
+	budget := maxTokens - lineTokens(header)
+	if budget < 0 {
+		return
+	}
+
+	lines := make([]string, len(files))
+	used := 0
+	for i, f := range files {
+		lines[i] = "#   " + f.Stat()
+		used += lineTokens(lines[i])
+	}
+	text.WriteString(header)
+	if used <= budget {
+		for _, line := range lines {
+			text.WriteString(line + "\n")
+		}
+		return
+	}
+
+	// Files over the budget before filtered, lockfile, vendored and binary
+	// ones, each by relevance
+	order := make([]int, len(files))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		a, b := files[order[i]], files[order[j]]
+		if a.Dropped() != b.Dropped() {
+			return !a.Dropped()
+		}
+		if a.Kind != b.Kind {
+			return a.Kind < b.Kind
+		}
+		return a.SemanticLines > b.SemanticLines
+	})
+
+	// Half of the budget lists single files, the rest sums up directories
+	used = 0
+	listed := 0
+	for _, i := range order {
+		tokens := lineTokens(lines[i])
+		if used+tokens > budget/2 {
+			break
+		}
+		text.WriteString(lines[i] + "\n")
+		used += tokens
+		listed++
+	}
+
+	rest := make([]*File, 0, len(files)-listed)
+	for _, i := range order[listed:] {
+		rest = append(rest, files[i])
+	}
+	writeDirectoryTotals(text, rest, budget-used)
+}
+
+// statsHeader introduces the stat lines of n files
+func statsHeader(n int) string {
+	return fmt.Sprintf("\n# %d more changed file(s) not shown (filtered, lockfiles, vendored, binary or over the diff budget):\n", n)
+}
+
+// dirTotal sums up the changes of the files in one directory
+type dirTotal struct {
+	dir     string
+	files   int
+	added   int
+	deleted int
+}
+
+// writeDirectoryTotals writes one line per directory, two levels deep when
+// those fit and top-level otherwise; directories that still don't fit are
+// counted in a last line
+func writeDirectoryTotals(text *strings.Builder, files []*File, maxTokens int) {
+	var totals []dirTotal
+	var lines []string
+	used := 0
+	for _, depth := range []int{2, 1} {
+		totals = groupByDirectory(files, depth)
+		lines = make([]string, len(totals))
+		used = 0
+		for i, total := range totals {
+			lines[i] = fmt.Sprintf("#   %s: %d file(s), +%s -%s", total.dir, total.files, formatCount(total.added), formatCount(total.deleted))
+			used += lineTokens(lines[i])
+		}
+		if used <= maxTokens {
+			break
+		}
+	}
+
+	if used <= maxTokens {
+		for _, line := range lines {
+			text.WriteString(line + "\n")
+		}
+		return
+	}
+
+	// Largest directories first, then a count of the rest
+	remaining := maxTokens - lineTokens(fmt.Sprintf("#   ... %d more file(s) in %d other directories", len(files), len(totals)))
+	skippedFiles := len(files)
+	written := 0
+	for i, line := range lines {
+		tokens := lineTokens(line)
+		if tokens > remaining {
+			break
+		}
+		text.WriteString(line + "\n")
+		remaining -= tokens
+		skippedFiles -= totals[i].files
+		written++
+	}
+	if remaining >= 0 {
+		text.WriteString(fmt.Sprintf("#   ... %d more file(s) in %d other directories\n", skippedFiles, len(totals)-written))
+	}
+}
+
+// groupByDirectory sums up files by their directory cut to depth levels,
+// largest directories first
+func groupByDirectory(files []*File, depth int) []dirTotal {
+	index := make(map[string]int)
+	var totals []dirTotal
+	for _, f := range files {
+		dir := "./"
+		if parts := strings.Split(f.Path, "/"); len(parts) > 1 {
+			dir = strings.Join(parts[:min(depth, len(parts)-1)], "/") + "/"
+		}
+
+		i, ok := index[dir]
+		if !ok {
+			i = len(totals)
+			index[dir] = i
+			totals = append(totals, dirTotal{dir: dir})
+		}
+		totals[i].files++
+		totals[i].added += f.Added
+		totals[i].deleted += f.Deleted
+	}
+
+	sort.SliceStable(totals, func(i, j int) bool {
+		if totals[i].files != totals[j].files {
+			return totals[i].files > totals[j].files
+		}
+		return totals[i].dir < totals[j].dir
+	})
+	return totals
+}
+
+// formatCount shortens line counts: 950, 1.2k, 12k
+func formatCount(n int) string {
+	switch {
+	case n >= 10_000:
+		return fmt.Sprintf("%dk", (n+500)/1000)
+	case n >= 1000:
+		return strings.TrimSuffix(fmt.Sprintf("%.1f", float64(n)/1000), ".0") + "k"
+	default:
+		return fmt.Sprintf("%d", n)
+	}
+}
+
+// lineTokens counts a line's tokens plus one for its newline
+func lineTokens(line string) int {
+	return ai.CountTokens(line) + 1
+}
+
+// partialFile includes the file header and hunks, in order, while they fit
+func partialFile(f *File, maxTokens int) (string, int, bool) {
+	var text strings.Builder
+	text.WriteString(f.Header)
+	used := ai.CountTokens(f.Header)
+
+	included := 0
+	for _, hunk := range f.Hunks {
+		tokens := ai.CountTokens(hunk.Text)
+		if used+tokens > maxTokens-20 {
+			break
+		}
+		text.WriteString(hunk.Text)
+		used += tokens
+		included++
+	}
+	if included == 0 {
+		return "", 0, false
+	}
+
+	note := fmt.Sprintf("# ... %d more hunk(s) of %s omitted (+%d -%d in total)\n",
+		len(f.Hunks)-included, f.Path, f.Added, f.Deleted)
+	text.WriteString(note)
+	used += ai.CountTokens(note)
+
+	return text.String(), used, true
+}
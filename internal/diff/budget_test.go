@@ -0,0 +1,141 @@
+package diff
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// fileDiff builds a single-file diff with one hunk per entry in hunkLines
+func fileDiff(name string, hunkLines ...int) string {
+	var diff strings.Builder
+	diff.WriteString(fmt.Sprintf("diff --git a/%s b/%s\n--- a/%s\n+++ b/%s\n", name, name, name, name))
+	for i, lines := range hunkLines {
+		diff.WriteString(fmt.Sprintf("@@ -%d,0 +%d,%d @@\n", i*100, i*100, lines))
+		for j := 0; j < lines; j++ {
+			diff.WriteString(fmt.Sprintf("+\tvalue%d := compute(%d, \"changed line\")\n", j, j))
+		}
+	}
+	return diff.String()
+}
+
+func TestRank(t *testing.T) {
+	files := Parse(fileDiff("docs/guide.md", 50) + fileDiff("internal/small.go", 2) +
+		fileDiff("go.sum", 100) + fileDiff("internal/big.go", 40) + fileDiff("internal/big_test.go", 80))
+
+	ranked := Rank(files)
+	var order []string
+	for _, f := range ranked {
+		order = append(order, f.Path)
+	}
+
+	want := "internal/big.go internal/small.go docs/guide.md internal/big_test.go"
+	if got := strings.Join(order, " "); got != want {
+		t.Errorf("Rank() = %s, want %s", got, want)
+	}
+}
+
+func TestBudgetWithinLimit(t *testing.T) {
+	unified := fileDiff("a.go", 5) + fileDiff("b.go", 5)
+	sel := Budget(Parse(unified), 100_000)
+
+	if sel.Included != 2 || sel.Omitted != 0 || sel.Partial != 0 {
+		t.Errorf("Expected all files included, got %+v", sel)
+	}
+	if sel.Text != strings.TrimRight(unified, "\n") {
+		t.Error("A diff within budget should be unchanged")
+	}
+}
+
+func TestBudgetOmitsAndSummarizes(t *testing.T) {
+	files := Parse(fileDiff("internal/core.go", 30) + fileDiff("go.sum", 200) +
+		fileDiff("internal/extra.go", 400) + fileDiff("README.md", 400))
+
+	sel := Budget(files, 1500)
+
+	if !strings.Contains(sel.Text, "diff --git a/internal/core.go") {
+		t.Error("Expected the most relevant file to be included")
+	}
+	if strings.Contains(sel.Text, "+example") || strings.Contains(sel.Text, "diff --git a/go.sum") {
+		t.Error("Lockfiles must never be included")
+	}
+	if sel.Dropped != 1 {
+		t.Errorf("Expected 1 dropped file, got %d", sel.Dropped)
+	}
+	for _, stat := range []string{"go.sum | +200 -0 (modified, lockfile)", "README.md | +400 -0"} {
+		if !strings.Contains(sel.Text, stat) {
+			t.Errorf("Expected stat line %q in:\n%s", stat, sel.Text)
+		}
+	}
+	if sel.Tokens > 1500 {
+		t.Errorf("Selection of %d tokens exceeds the budget", sel.Tokens)
+	}
+}
+
+func TestBudgetPartialFile(t *testing.T) {
+	files := Parse(fileDiff("internal/api.go", 20, 20, 20, 400))
+
+	sel := Budget(files, 1500)
+
+	if sel.Partial != 1 {
+		t.Fatalf("Expected a partially included file, got %+v", sel)
+	}
+	if !strings.Contains(sel.Text, "more hunk(s) of internal/api.go omitted (+460 -0 in total)") {
+		t.Errorf("Expected a hunk omission note, got:\n%s", sel.Text)
+	}
+}
+
+func TestBudgetFilteredFiles(t *testing.T) {
+	files := Parse(fileDiff("internal/core.go", 5) + fileDiff("web/app.ts", 5))
+	Filter(files, func(path string) bool { return strings.HasSuffix(path, ".go") })
+
+	sel := Budget(files, 100_000)
+
+	if strings.Contains(sel.Text, "diff --git a/web/app.ts") {
+		t.Error("Filtered files must not be included")
+	}
+	if !strings.Contains(sel.Text, "web/app.ts | +5 -0 (modified, source, filtered)") {
+		t.Errorf("Expected a stat line for the filtered file, got:\n%s", sel.Text)
+	}
+	if sel.Included != 1 || sel.Dropped != 1 {
+		t.Errorf("Expected 1 included and 1 dropped file, got %+v", sel)
+	}
+}
+
+func TestBudgetThousandsOfFiles(t *testing.T) {
+	var unified strings.Builder
+	unified.WriteString(fileDiff("internal/core.go", 30))
+	for i := 0; i < 2000; i++ {
+		unified.WriteString(fileDiff(fmt.Sprintf("web/components/widget%d.ts", i), 3))
+	}
+	for i := 0; i < 500; i++ {
+		unified.WriteString(fileDiff(fmt.Sprintf("vendor/lib/file%d.go", i), 2))
+	}
+	files := Parse(unified.String())
+
+	for _, maxTokens := range []int{24000, 1000} {
+		sel := Budget(files, maxTokens)
+		if sel.Tokens > maxTokens {
+			t.Errorf("Budget(%d): selection of %d tokens exceeds the budget", maxTokens, sel.Tokens)
+		}
+		if sel.Included+sel.Partial+sel.Omitted+sel.Dropped != len(files) {
+			t.Errorf("Budget(%d): expected every file counted, got %+v", maxTokens, sel)
+		}
+	}
+
+	sel := Budget(files, 24000)
+	if !strings.Contains(sel.Text, "diff --git a/internal/core.go") {
+		t.Error("Expected the most relevant file to be included")
+	}
+	if !strings.Contains(sel.Text, "#   vendor/lib/: 500 file(s), +1k -0") {
+		t.Errorf("Expected the vendored files collapsed into a directory total, got:\n%s", sel.Text)
+	}
+}
+
+func TestFormatCount(t *testing.T) {
+	for n, want := range map[int]string{0: "0", 950: "950", 1000: "1k", 1234: "1.2k", 12345: "12k"} {
+		if got := formatCount(n); got != want {
+			t.Errorf("formatCount(%d) = %s, want %s", n, got, want)
+		}
+	}
+}
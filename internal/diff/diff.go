@@ -0,0 +1,341 @@
+package diff
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// Kind classifies a changed file by how useful its diff is for release notes.
+// Lower kinds are more relevant.
+type Kind int
+
+const (
+	KindSource Kind = iota
+	KindConfig
+	KindDocs
+	KindTest
+	KindGenerated
+	KindLockfile // Dropped from the diff
+	KindVendored // Dropped from the diff
+	KindBinary   // No textual diff
+)
+
+// String returns the kind's name as used in diff summaries
+func (k Kind) String() string {
+	switch k {
+	case KindSource:
+		return "source"
+	case KindConfig:
+		return "config"
+	case KindDocs:
+		return "docs"
+	case KindTest:
+		return "test"
+	case KindGenerated:
+		return "generated"
+	case KindLockfile:
+		return "lockfile"
+	case KindVendored:
+		return "vendored"
+	case KindBinary:
+		return "binary"
+	default:
+		return "unknown"
+	}
+}
+
+// Hunk is one "@@" section of a file diff
+type Hunk struct {
+	Header string // The "@@ -a,b +c,d @@" line
+	Text   string // Header and body lines, newline terminated
+}
+
+// File is the diff of a single file
+type File struct {
+	Path    string // New path (old path for deleted files)
+	OldPath string
+	Status  string // added, deleted, renamed or modified
+	Kind    Kind
+	Header  string // Everything before the first hunk, newline terminated
+	Hunks   []Hunk
+	Added   int
+	Deleted int
+
+	// SemanticLines counts changed lines that are not blank, the measure of
+	// change size used for ranking
+	SemanticLines int
+
+	// Filtered marks files left out by the file include/exclude filters
+	Filtered bool
+}
+
+// Text returns the file's full diff
+func (f *File) Text() string {
+	var text strings.Builder
+	text.WriteString(f.Header)
+	for _, hunk := range f.Hunks {
+		text.WriteString(hunk.Text)
+	}
+	return text.String()
+}
+
+// Stat returns a one-line summary of the file's change
+func (f *File) Stat() string {
+	kind := f.Kind.String()
+	if f.Filtered {
+		kind += ", filtered"
+	}
+	if f.Kind == KindBinary {
+		return fmt.Sprintf("%s | binary (%s, %s)", f.Path, f.Status, kind)
+	}
+	return fmt.Sprintf("%s | +%d -%d (%s, %s)", f.Path, f.Added, f.Deleted, f.Status, kind)
+}
+
+// Dropped reports whether the file is never included in the diff
+func (f *File) Dropped() bool {
+	return f.Filtered || f.Kind == KindLockfile || f.Kind == KindVendored || f.Kind == KindBinary
+}
+
+// ChangedLines returns the line numbers deleted from the old file and added
+// to the new file, as given by the hunk headers
+func (f *File) ChangedLines() (oldLines, newLines []int) {
+	for _, hunk := range f.Hunks {
+		var oldStart, newStart int
+		if _, err := fmt.Sscanf(hunkRange(hunk.Header, '-'), "%d", &oldStart); err != nil {
+			continue
+		}
+		if _, err := fmt.Sscanf(hunkRange(hunk.Header, '+'), "%d", &newStart); err != nil {
+			continue
+		}
+
+		oldLine, newLine := oldStart, newStart
+		lines := strings.Split(strings.TrimSuffix(hunk.Text, "\n"), "\n")
+		for _, line := range lines[1:] {
+			switch {
+			case strings.HasPrefix(line, "-"):
+				oldLines = append(oldLines, oldLine)
+				oldLine++
+			case strings.HasPrefix(line, "+"):
+				newLines = append(newLines, newLine)
+				newLine++
+			case strings.HasPrefix(line, "\\"):
+				// "\ No newline at end of file"
+			default:
+				oldLine++
+				newLine++
+			}
+		}
+	}
+	return oldLines, newLines
+}
+
+// hunkRange returns the start of the old ('-') or new ('+') range in a hunk header
+func hunkRange(header string, side byte) string {
+	for _, field := range strings.Fields(header) {
+		if len(field) > 1 && field[0] == side {
+			start, _, _ := strings.Cut(field[1:], ",")
+			return start
+		}
+	}
+	return ""
+}
+
+// Filter marks the files for which keep returns false as filtered
+func Filter(files []File, keep func(path string) bool) {
+	for i := range files {
+		if !keep(files[i].Path) {
+			files[i].Filtered = true
+		}
+	}
+}
+
+// Parse splits a unified diff (git diff output) into per-file diffs with hunks
+func Parse(unified string) []File {
+	var files []File
+	var current *File
+	var header strings.Builder
+	var hunk *strings.Builder
+	var hunkHeader string
+
+	flushHunk := func() {
+		if current != nil && hunk != nil {
+			current.Hunks = append(current.Hunks, Hunk{Header: hunkHeader, Text: hunk.String()})
+		}
+		hunk = nil
+	}
+	flushFile := func() {
+		flushHunk()
+		if current != nil {
+			if current.Header == "" {
+				current.Header = header.String()
+			}
+			current.Kind = classify(current)
+			files = append(files, *current)
+		}
+		current = nil
+	}
+
+	for _, line := range strings.SplitAfter(unified, "\n") {
+		if line == "" {
+			continue
+		}
+		if !strings.HasSuffix(line, "\n") {
+			line += "\n"
+		}
+		content := strings.TrimSuffix(line, "\n")
+
+		if strings.HasPrefix(content, "diff --git ") {
+			flushFile()
+			header.Reset()
+			header.WriteString(line)
+			oldPath, newPath := parseGitHeader(content)
+			current = &File{Path: newPath, OldPath: oldPath, Status: "modified"}
+			continue
+		}
+		if current == nil {
+			continue
+		}
+
+		if strings.HasPrefix(content, "@@") {
+			if hunk == nil {
+				current.Header = header.String()
+			}
+			flushHunk()
+			hunk = &strings.Builder{}
+			hunkHeader = content
+			hunk.WriteString(line)
+			continue
+		}
+
+		if hunk == nil {
+			header.WriteString(line)
+			switch {
+			case strings.HasPrefix(content, "new file mode"):
+				current.Status = "added"
+			case strings.HasPrefix(content, "deleted file mode"):
+				current.Status = "deleted"
+				current.Path = current.OldPath
+			case strings.HasPrefix(content, "rename from "):
+				current.Status = "renamed"
+				current.OldPath = strings.TrimPrefix(content, "rename from ")
+			case strings.HasPrefix(content, "rename to "):
+				current.Path = strings.TrimPrefix(content, "rename to ")
+			case strings.HasPrefix(content, "Binary files ") || content == "GIT binary patch":
+				current.Kind = KindBinary
+			}
+			continue
+		}
+
+		hunk.WriteString(line)
+		switch {
+		case strings.HasPrefix(content, "+"):
+			current.Added++
+			if strings.TrimSpace(content[1:]) != "" {
+				current.SemanticLines++
+			}
+		case strings.HasPrefix(content, "-"):
+			current.Deleted++
+			if strings.TrimSpace(content[1:]) != "" {
+				current.SemanticLines++
+			}
+		}
+	}
+	flushFile()
+
+	return files
+}
+
+// parseGitHeader extracts the old and new paths from "diff --git a/X b/Y"
+func parseGitHeader(line string) (string, string) {
+	paths := strings.TrimPrefix(line, "diff --git ")
+	if i := strings.LastIndex(paths, " b/"); i >= 0 {
+		return strings.TrimPrefix(paths[:i], "a/"), paths[i+3:]
+	}
+	return paths, paths
+}
+
+// lockfiles are dependency lock files whose diffs carry no release information
+var lockfiles = map[string]bool{
+	"go.sum":            true,
+	"package-lock.json": true,
+	"yarn.lock":         true,
+	"pnpm-lock.yaml":    true,
+	"bun.lockb":         true,
+	"Cargo.lock":        true,
+	"poetry.lock":       true,
+	"Pipfile.lock":      true,
+	"uv.lock":           true,
+	"Gemfile.lock":      true,
+	"composer.lock":     true,
+}
+
+// classify determines a file's kind from its path and content
+func classify(f *File) Kind {
+	if f.Kind == KindBinary {
+		return KindBinary
+	}
+
+	p := f.Path
+	base := path.Base(p)
+	ext := path.Ext(p)
+
+	if lockfiles[base] {
+		return KindLockfile
+	}
+
+	for _, dir := range []string{"vendor/", "node_modules/", "third_party/"} {
+		if strings.HasPrefix(p, dir) || strings.Contains(p, "/"+dir) {
+			return KindVendored
+		}
+	}
+
+	if isGenerated(f, base) {
+		return KindGenerated
+	}
+
+	switch {
+	case strings.HasSuffix(base, "_test.go"),
+		strings.Contains(base, ".test."), strings.Contains(base, ".spec."),
+		strings.HasPrefix(base, "test_") && ext == ".py",
+		strings.HasPrefix(p, "test/"), strings.HasPrefix(p, "tests/"),
+		strings.Contains(p, "/test/"), strings.Contains(p, "/tests/"),
+		strings.Contains(p, "/testdata/"), strings.HasPrefix(p, "testdata/"):
+		return KindTest
+	}
+
+	switch ext {
+	case ".md", ".rst", ".txt", ".adoc":
+		return KindDocs
+	case ".yml", ".yaml", ".json", ".toml", ".ini", ".cfg", ".conf", ".env":
+		return KindConfig
+	}
+	if strings.HasPrefix(p, "docs/") {
+		return KindDocs
+	}
+	if base == "Dockerfile" || base == "Makefile" || base == "go.mod" {
+		return KindConfig
+	}
+
+	return KindSource
+}
+
+// isGenerated detects generated files by name or by Go's "Code generated ... DO NOT EDIT." marker
+func isGenerated(f *File, base string) bool {
+	for _, suffix := range []string{".pb.go", "_generated.go", ".gen.go", ".min.js", ".min.css", ".js.map", ".css.map"} {
+		if strings.HasSuffix(base, suffix) {
+			return true
+		}
+	}
+	if strings.HasPrefix(base, "zz_generated") {
+		return true
+	}
+
+	if len(f.Hunks) > 0 {
+		first := f.Hunks[0].Text
+		if strings.Contains(first, "Code generated") && strings.Contains(first, "DO NOT EDIT") {
+			return true
+		}
+	}
+	return false
+}
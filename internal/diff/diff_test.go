@@ -0,0 +1,132 @@
+package diff
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+const sampleDiff = `diff --git a/internal/ai/cost.go b/internal/ai/cost.go
+index 1111111..2222222 100644
+--- a/internal/ai/cost.go
++++ b/internal/ai/cost.go
+@@ -1,3 +1,4 @@
+ package ai
++
++// Budget caps spending
+ func a() {}
+@@ -10,2 +11,2 @@ func b() {
+-	return 1
++	return 2
+diff --git a/go.sum b/go.sum
+index 3333333..4444444 100644
+--- a/go.sum
++++ b/go.sum
+@@ -1 +1,2 @@
++example.com/mod v1.0.0 h1:abc=
+diff --git a/docs/old.md b/docs/new.md
+similarity index 90%
+rename from docs/old.md
+rename to docs/new.md
+diff --git a/assets/logo.png b/assets/logo.png
+new file mode 100644
+Binary files /dev/null and b/assets/logo.png differ
+diff --git a/api/v1/service.pb.go b/api/v1/service.pb.go
+deleted file mode 100644
+--- a/api/v1/service.pb.go
++++ /dev/null
+@@ -1,2 +0,0 @@
+-// Code generated by protoc-gen-go. DO NOT EDIT.
+-package v1`
+
+func TestParse(t *testing.T) {
+	files := Parse(sampleDiff)
+	if len(files) != 5 {
+		t.Fatalf("Expected 5 files, got %d", len(files))
+	}
+
+	cost := files[0]
+	if cost.Path != "internal/ai/cost.go" || cost.Status != "modified" || cost.Kind != KindSource {
+		t.Errorf("Unexpected first file: %+v", cost)
+	}
+	if len(cost.Hunks) != 2 {
+		t.Fatalf("Expected 2 hunks, got %d", len(cost.Hunks))
+	}
+	if cost.Added != 3 || cost.Deleted != 1 || cost.SemanticLines != 3 {
+		t.Errorf("Expected +3 -1 with 3 semantic lines, got +%d -%d with %d", cost.Added, cost.Deleted, cost.SemanticLines)
+	}
+	if !strings.HasPrefix(cost.Hunks[1].Header, "@@ -10,2 +11,2 @@") {
+		t.Errorf("Unexpected hunk header: %q", cost.Hunks[1].Header)
+	}
+	if !strings.HasSuffix(cost.Header, "+++ b/internal/ai/cost.go\n") {
+		t.Errorf("Header should end before the first hunk, got %q", cost.Header)
+	}
+
+	tests := []struct {
+		index  int
+		path   string
+		status string
+		kind   Kind
+	}{
+		{1, "go.sum", "modified", KindLockfile},
+		{2, "docs/new.md", "renamed", KindDocs},
+		{3, "assets/logo.png", "added", KindBinary},
+		{4, "api/v1/service.pb.go", "deleted", KindGenerated},
+	}
+	for _, tt := range tests {
+		f := files[tt.index]
+		if f.Path != tt.path || f.Status != tt.status || f.Kind != tt.kind {
+			t.Errorf("File %d = %s (%s, %s), want %s (%s, %s)",
+				tt.index, f.Path, f.Status, f.Kind, tt.path, tt.status, tt.kind)
+		}
+	}
+
+	if got := strings.Join([]string{files[0].Text(), files[1].Text()}, ""); !strings.HasPrefix(sampleDiff, strings.TrimSuffix(got, "\n")) {
+		t.Error("Text() should reproduce the original diff")
+	}
+}
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		path string
+		want Kind
+	}{
+		{"cmd/app/main.go", KindSource},
+		{"web/src/App.tsx", KindSource},
+		{"internal/ai/cost_test.go", KindTest},
+		{"web/src/App.test.tsx", KindTest},
+		{"tests/test_api.py", KindTest},
+		{"README.md", KindDocs},
+		{".github/workflows/ci.yml", KindConfig},
+		{"go.mod", KindConfig},
+		{"package-lock.json", KindLockfile},
+		{"web/yarn.lock", KindLockfile},
+		{"vendor/github.com/pkg/errors/errors.go", KindVendored},
+		{"web/node_modules/react/index.js", KindVendored},
+		{"internal/api/zz_generated.deepcopy.go", KindGenerated},
+		{"static/app.min.js", KindGenerated},
+	}
+
+	for _, tt := range tests {
+		if got := classify(&File{Path: tt.path}); got != tt.want {
+			t.Errorf("classify(%q) = %s, want %s", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestChangedLines(t *testing.T) {
+	files := Parse(sampleDiff)
+
+	oldLines, newLines := files[0].ChangedLines()
+	if fmt.Sprint(oldLines) != "[10]" {
+		t.Errorf("Expected old line 10 deleted, got %v", oldLines)
+	}
+	if fmt.Sprint(newLines) != "[2 3 11]" {
+		t.Errorf("Expected new lines 2, 3 and 11 added, got %v", newLines)
+	}
+
+	oldLines, newLines = files[4].ChangedLines()
+	if fmt.Sprint(oldLines) != "[1 2]" || len(newLines) != 0 {
+		t.Errorf("Expected only old lines 1 and 2 for a deleted file, got %v and %v", oldLines, newLines)
+	}
+}
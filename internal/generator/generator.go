@@ -3,10 +3,11 @@ package generator
 import (
 	"fmt"
 	"strings"
-	"time"
 
 	"github.com/1broseidon/promptext-notes/internal/analyzer"
 	"github.com/1broseidon/promptext-notes/internal/config"
+	"github.com/1broseidon/promptext-notes/internal/notes"
+	"github.com/1broseidon/promptext-notes/internal/prompt"
 	"github.com/1broseidon/promptext/pkg/promptext"
 )
 
@@ -27,10 +28,10 @@ func GenerateReleaseNotes(version string, categories analyzer.CommitCategories,
 
 	// Header
 	notes.WriteString(fmt.Sprintf("## [%s] - %s\n\n",
-		version, time.Now().Format("2006-01-02")))
+		version, prompt.Today()))
 
 	// Determine which sections to include
-	sections := []string{"breaking", "added", "fixed", "changed", "docs"}
+	sections := []string{"breaking", "added", "fixed", "changed", "dependencies", "docs"}
 	if cfg != nil && len(cfg.Output.Sections) > 0 {
 		sections = cfg.Output.Sections
 	}
@@ -39,11 +40,14 @@ func GenerateReleaseNotes(version string, categories analyzer.CommitCategories,
 	for _, section := range sections {
 		switch strings.ToLower(section) {
 		case "breaking":
-			if len(categories.Breaking) > 0 {
+			if len(categories.Breaking) > 0 || len(categories.APIChanges) > 0 {
 				notes.WriteString("### ⚠️ Breaking Changes\n")
 				for _, item := range categories.Breaking {
 					notes.WriteString(fmt.Sprintf("- %s\n", strings.TrimSpace(item)))
 				}
+				for _, item := range categories.APIChanges {
+					notes.WriteString(fmt.Sprintf("- %s\n", strings.TrimSpace(item)))
+				}
 				notes.WriteString("\n")
 			}
 
@@ -74,6 +78,15 @@ func GenerateReleaseNotes(version string, categories analyzer.CommitCategories,
 				notes.WriteString("\n")
 			}
 
+		case "dependencies", "deps":
+			if len(categories.Dependencies) > 0 {
+				notes.WriteString("### Dependencies\n")
+				for _, item := range categories.Dependencies {
+					notes.WriteString(fmt.Sprintf("- %s\n", strings.TrimSpace(item)))
+				}
+				notes.WriteString("\n")
+			}
+
 		case "docs", "documentation":
 			if len(categories.Docs) > 0 {
 				notes.WriteString("### Documentation\n")
@@ -101,3 +114,65 @@ func GenerateReleaseNotes(version string, categories analyzer.CommitCategories,
 
 	return notes.String()
 }
+
+// sectionHeadings are the headings of the sections structured notes can have
+var sectionHeadings = map[string]string{
+	"breaking":     "### ⚠️ Breaking Changes",
+	"added":        "### Added",
+	"changed":      "### Changed",
+	"fixed":        "### Fixed",
+	"dependencies": "### Dependencies",
+	"deprecated":   "### Deprecated",
+	"security":     "### Security",
+}
+
+// RenderNotes renders structured release notes from the model in the order of
+// the configured sections; sections the config doesn't list follow in the
+// order of notes.Sections, so no item is lost.
+func RenderNotes(version string, releaseNotes *notes.Notes, cfg *config.Config) string {
+	var out strings.Builder
+
+	if version == "" {
+		version = "Unreleased"
+	}
+	out.WriteString(fmt.Sprintf("## [%s] - %s\n\n",
+		version, prompt.Today()))
+
+	var order []string
+	if cfg != nil {
+		for _, section := range cfg.Output.Sections {
+			section = strings.ToLower(section)
+			if section == "deps" {
+				section = "dependencies"
+			}
+			order = append(order, section)
+		}
+	}
+	order = append(order, notes.Sections...)
+
+	written := make(map[string]bool)
+	for _, section := range order {
+		heading, ok := sectionHeadings[section]
+		if !ok || written[section] {
+			continue
+		}
+		written[section] = true
+
+		items := releaseNotes.Section(section)
+		if len(items) == 0 {
+			continue
+		}
+
+		out.WriteString(heading + "\n")
+		for _, item := range items {
+			line := "- **" + strings.TrimSpace(item.Title) + "**"
+			if description := strings.TrimSpace(item.Description); description != "" {
+				line += " - " + description
+			}
+			out.WriteString(line + "\n")
+		}
+		out.WriteString("\n")
+	}
+
+	return strings.TrimRight(out.String(), "\n") + "\n"
+}
@@ -6,6 +6,8 @@ import (
 	"time"
 
 	"github.com/1broseidon/promptext-notes/internal/analyzer"
+	"github.com/1broseidon/promptext-notes/internal/config"
+	"github.com/1broseidon/promptext-notes/internal/notes"
 	"github.com/1broseidon/promptext/pkg/promptext"
 )
 
@@ -179,3 +181,64 @@ func TestGenerateReleaseNotesOnlyBreaking(t *testing.T) {
 		}
 	}
 }
+
+func TestGenerateReleaseNotesAPIChanges(t *testing.T) {
+	categories := analyzer.CommitCategories{
+		Features:   []string{"add export"},
+		APIChanges: []string{"`store`: func Legacy removed (was `func()`)"},
+	}
+	result := &promptext.Result{
+		ProjectOutput: &promptext.ProjectOutput{Files: []promptext.FileInfo{}},
+	}
+
+	notes := GenerateReleaseNotes("v2.0.0", categories, result, nil)
+
+	if !strings.Contains(notes, "### ⚠️ Breaking Changes\n- `store`: func Legacy removed (was `func()`)\n") {
+		t.Errorf("Detected API changes should be listed as breaking, got:\n%s", notes)
+	}
+	if !strings.Contains(notes, "**Commits**: 1") {
+		t.Error("Detected API changes should not be counted as commits")
+	}
+}
+
+func TestGenerateReleaseNotesDependencies(t *testing.T) {
+	categories := analyzer.CommitCategories{
+		Dependencies: []string{"`github.com/spf13/cobra` upgraded v1.8.0 → v1.9.1 (go.mod)"},
+	}
+	result := &promptext.Result{
+		ProjectOutput: &promptext.ProjectOutput{Files: []promptext.FileInfo{}},
+	}
+
+	notes := GenerateReleaseNotes("v1.1.0", categories, result, nil)
+	if !strings.Contains(notes, "### Dependencies\n- `github.com/spf13/cobra` upgraded v1.8.0 → v1.9.1 (go.mod)\n") {
+		t.Errorf("Expected a Dependencies section, got:\n%s", notes)
+	}
+
+	cfg := &config.Config{Output: config.OutputConfig{Sections: []string{"added"}}}
+	if notes := GenerateReleaseNotes("v1.1.0", categories, result, cfg); strings.Contains(notes, "### Dependencies") {
+		t.Error("Dependencies section should follow output.sections")
+	}
+}
+
+func TestRenderNotes(t *testing.T) {
+	releaseNotes := &notes.Notes{Sections: []notes.Section{
+		{Name: "fixed", Items: []notes.Item{{Title: "Startup crash", Description: "No longer crashes without a config.", Commits: []string{"a1b2c3d"}}}},
+		{Name: "security", Items: []notes.Item{{Title: "Token handling", Description: "Tokens are no longer logged."}}},
+		{Name: "added", Items: []notes.Item{{Title: "PDF export", Description: "Export as PDF."}}},
+		{Name: "added", Items: []notes.Item{{Title: "Dark mode"}}},
+		{Name: "changed"},
+	}}
+	cfg := &config.Config{Output: config.OutputConfig{Sections: []string{"added", "fixed"}}}
+
+	rendered := RenderNotes("v1.2.0", releaseNotes, cfg)
+
+	want := "### Added\n- **PDF export** - Export as PDF.\n- **Dark mode**\n\n" +
+		"### Fixed\n- **Startup crash** - No longer crashes without a config.\n\n" +
+		"### Security\n- **Token handling** - Tokens are no longer logged.\n"
+	if !strings.HasPrefix(rendered, "## [v1.2.0] - ") || !strings.HasSuffix(rendered, want) {
+		t.Errorf("Unexpected rendering:\n%s", rendered)
+	}
+	if strings.Contains(rendered, "### Changed") {
+		t.Error("Empty sections should be omitted")
+	}
+}
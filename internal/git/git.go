@@ -56,6 +56,37 @@ func GetCommits(since string) ([]string, error) {
 	return commits, nil
 }
 
+// Commit is a commit's abbreviated hash and subject.
+type Commit struct {
+	Hash    string
+	Subject string
+}
+
+// GetCommitHashes returns the commits between the given tag/commit and HEAD with
+// their abbreviated hashes, newest first, matching GetCommits.
+func GetCommitHashes(since string) ([]Commit, error) {
+	cmd := exec.Command("git", "log", since+"..HEAD", "--pretty=format:%h %s")
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get commit hashes: %w", err)
+	}
+
+	return parseCommitHashes(string(output)), nil
+}
+
+// parseCommitHashes parses git log output with one "<hash> <subject>" per line.
+func parseCommitHashes(output string) []Commit {
+	var commits []Commit
+	for _, line := range strings.Split(output, "\n") {
+		hash, subject, _ := strings.Cut(strings.TrimSpace(line), " ")
+		if hash == "" {
+			continue
+		}
+		commits = append(commits, Commit{Hash: hash, Subject: strings.TrimSpace(subject)})
+	}
+	return commits
+}
+
 // IsGitRepository checks if the current directory is a git repository.
 func IsGitRepository() bool {
 	cmd := exec.Command("git", "rev-parse", "--git-dir")
@@ -83,3 +114,71 @@ func GetDiff(since string) (string, error) {
 	}
 	return strings.TrimSpace(string(output)), nil
 }
+
+// CommitFiles is a commit subject with the files it touched.
+type CommitFiles struct {
+	Subject string
+	Files   []string
+}
+
+// GetCommitFiles returns each commit between the given tag/commit and HEAD with the files it changed.
+// Commits are listed newest first, matching GetCommits.
+func GetCommitFiles(since string) ([]CommitFiles, error) {
+	cmd := exec.Command("git", "log", since+"..HEAD", "--pretty=format:%x00%s", "--name-only")
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get commit files: %w", err)
+	}
+
+	return parseCommitFiles(string(output)), nil
+}
+
+// parseCommitFiles parses git log output where each commit starts with a NUL
+// byte followed by its subject, then the changed file names one per line.
+func parseCommitFiles(output string) []CommitFiles {
+	var commits []CommitFiles
+	for _, entry := range strings.Split(output, "\x00") {
+		lines := strings.Split(strings.TrimSpace(entry), "\n")
+		subject := strings.TrimSpace(lines[0])
+		if subject == "" {
+			continue
+		}
+
+		commit := CommitFiles{Subject: subject}
+		for _, line := range lines[1:] {
+			if file := strings.TrimSpace(line); file != "" {
+				commit.Files = append(commit.Files, file)
+			}
+		}
+		commits = append(commits, commit)
+	}
+	return commits
+}
+
+// ShowFile returns the contents of a file at the given tag/commit.
+func ShowFile(ref, path string) (string, error) {
+	cmd := exec.Command("git", "show", ref+":"+path)
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to show %s at %s: %w", path, ref, err)
+	}
+	return string(output), nil
+}
+
+// ListFiles returns the files directly inside a directory at the given tag/commit.
+// A directory that doesn't exist at that ref has no files.
+func ListFiles(ref, dir string) ([]string, error) {
+	cmd := exec.Command("git", "ls-tree", "--name-only", ref, dir+"/")
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list files in %s at %s: %w", dir, ref, err)
+	}
+
+	var files []string
+	for _, line := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+		if trimmed := strings.TrimSpace(line); trimmed != "" {
+			files = append(files, trimmed)
+		}
+	}
+	return files, nil
+}
@@ -103,3 +103,43 @@ func TestGetCommitsWithInvalidRef(t *testing.T) {
 		t.Error("GetCommits() with invalid ref should return error")
 	}
 }
+
+func TestParseCommitFiles(t *testing.T) {
+	output := "\x00feat: add export\ninternal/export/pdf.go\ninternal/export/pdf_test.go\n\n" +
+		"\x00Merge branch 'main'\n\n" +
+		"\x00fix: crash on start\ncmd/app/main.go"
+
+	commits := parseCommitFiles(output)
+	if len(commits) != 3 {
+		t.Fatalf("Expected 3 commits, got %d", len(commits))
+	}
+
+	if commits[0].Subject != "feat: add export" || len(commits[0].Files) != 2 {
+		t.Errorf("Unexpected first commit: %+v", commits[0])
+	}
+	if len(commits[1].Files) != 0 {
+		t.Errorf("Merge commit should have no files, got %v", commits[1].Files)
+	}
+	if commits[2].Files[0] != "cmd/app/main.go" {
+		t.Errorf("Unexpected files for last commit: %v", commits[2].Files)
+	}
+}
+
+func TestParseCommitHashes(t *testing.T) {
+	output := "a1b2c3d feat: add export\n\ne4f5a6b fix: crash on start\n0c0ffee"
+
+	commits := parseCommitHashes(output)
+	want := []Commit{
+		{Hash: "a1b2c3d", Subject: "feat: add export"},
+		{Hash: "e4f5a6b", Subject: "fix: crash on start"},
+		{Hash: "0c0ffee", Subject: ""},
+	}
+	if len(commits) != len(want) {
+		t.Fatalf("Expected %d commits, got %+v", len(want), commits)
+	}
+	for i := range want {
+		if commits[i] != want[i] {
+			t.Errorf("Commit %d = %+v, want %+v", i, commits[i], want[i])
+		}
+	}
+}
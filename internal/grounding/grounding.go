@@ -0,0 +1,285 @@
+package grounding
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// UnverifiedMarker is appended to items without support in mark mode
+const UnverifiedMarker = " _(unverified)_"
+
+// Kinds of source an item can be matched to
+const (
+	SourceCommit    = "commit"
+	SourceFile      = "file"
+	SourceHunk      = "hunk"
+	SourceDetected  = "detected"  // Breaking API and dependency changes found in the code
+	SourceCandidate = "candidate" // An item of another ensemble candidate
+)
+
+// Source is one piece of input evidence; its name is matched with its text,
+// so an item naming a file matches the file's hunks
+type Source struct {
+	Kind string
+	Name string // Commit subject, file path, or "path @@ header" for hunks
+	Text string
+
+	keywords map[string]bool
+}
+
+// Item is a bullet of the release notes
+type Item struct {
+	Line    int    // Index of the item's line in the release notes
+	Section string // Heading of the section the item is in, without "### "
+	Text    string // The item without its bullet
+}
+
+// Result is the verdict for one item
+type Result struct {
+	Item     Item
+	Score    float64 // Share of the item's keywords found in the best source
+	Evidence *Source // Best matching source, nil when nothing matched
+	Grounded bool
+	Judged   bool // Grounded by the judge model rather than lexically
+}
+
+// ParseItems returns the bullets of the release notes. Continuation lines
+// belong to the bullet above them.
+func ParseItems(content string) []Item {
+	var items []Item
+	section := ""
+	for i, line := range strings.Split(content, "\n") {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(trimmed, "#"):
+			section = strings.TrimSpace(strings.TrimLeft(trimmed, "#"))
+		case strings.HasPrefix(line, "- ") || strings.HasPrefix(line, "* "):
+			items = append(items, Item{Line: i, Section: section, Text: strings.TrimSpace(line[2:])})
+		case trimmed != "" && len(items) > 0 && items[len(items)-1].Line < i && line != trimmed:
+			// Indented continuation of the previous item
+			items[len(items)-1].Text += " " + trimmed
+		}
+	}
+	return items
+}
+
+// Check matches each item of the release notes against the sources and
+// reports whether one source contains at least minScore of its keywords.
+// Items without keywords, such as "- None", are grounded.
+func Check(content string, sources []Source, minScore float64) []Result {
+	for i := range sources {
+		sources[i].keywords = keywordSet(sources[i].Name + " " + sources[i].Text)
+	}
+
+	var results []Result
+	for _, item := range ParseItems(content) {
+		result := Result{Item: item}
+		words := keywords(item.Text)
+		if len(words) == 0 {
+			result.Score = 1
+			result.Grounded = true
+			results = append(results, result)
+			continue
+		}
+
+		for i := range sources {
+			found := 0
+			for _, word := range words {
+				if sources[i].keywords[word] {
+					found++
+				}
+			}
+			score := float64(found) / float64(len(words))
+			if score > result.Score {
+				result.Score = score
+				result.Evidence = &sources[i]
+			}
+		}
+		result.Grounded = result.Score >= minScore
+		results = append(results, result)
+	}
+	return results
+}
+
+// Ungrounded returns the results of items without support
+func Ungrounded(results []Result) []Result {
+	var ungrounded []Result
+	for _, result := range results {
+		if !result.Grounded {
+			ungrounded = append(ungrounded, result)
+		}
+	}
+	return ungrounded
+}
+
+// Apply marks (mode "mark") or removes (mode "remove") the ungrounded items.
+// Removing every item of a section removes its heading too.
+func Apply(content string, results []Result, mode string) string {
+	if mode != "mark" && mode != "remove" {
+		return content
+	}
+
+	ungrounded := make(map[int]bool)
+	for _, result := range Ungrounded(results) {
+		ungrounded[result.Item.Line] = true
+	}
+	if len(ungrounded) == 0 {
+		return content
+	}
+
+	lines := strings.Split(content, "\n")
+	var out []string
+	removing := false
+	for i, line := range lines {
+		isItem := strings.HasPrefix(line, "- ") || strings.HasPrefix(line, "* ")
+		if isItem {
+			removing = false
+		}
+		if ungrounded[i] {
+			if mode == "mark" {
+				out = append(out, line+UnverifiedMarker)
+				continue
+			}
+			removing = true
+			continue
+		}
+		// Continuation lines of a removed item go with it
+		if removing && !isItem && line != strings.TrimSpace(line) && strings.TrimSpace(line) != "" {
+			continue
+		}
+		removing = false
+		out = append(out, line)
+	}
+
+	if mode == "remove" {
+		out = dropEmptySections(out)
+	}
+	return strings.Join(out, "\n")
+}
+
+// dropEmptySections removes "###" headings left without items
+func dropEmptySections(lines []string) []string {
+	var out []string
+	for i := 0; i < len(lines); i++ {
+		if !strings.HasPrefix(strings.TrimSpace(lines[i]), "### ") {
+			out = append(out, lines[i])
+			continue
+		}
+
+		end := i + 1
+		for end < len(lines) && !strings.HasPrefix(strings.TrimSpace(lines[end]), "#") {
+			end++
+		}
+		empty := true
+		for _, line := range lines[i+1 : end] {
+			if strings.TrimSpace(line) != "" {
+				empty = false
+				break
+			}
+		}
+		if !empty {
+			out = append(out, lines[i])
+			continue
+		}
+		// Skip the heading and the blank lines after it
+		i = end - 1
+	}
+	return out
+}
+
+// Report renders the ungrounded items for verbose output
+func Report(results []Result) string {
+	var report strings.Builder
+	for _, result := range Ungrounded(results) {
+		report.WriteString(fmt.Sprintf("   [%s] %s (best match %.0f%%", result.Item.Section, result.Item.Text, result.Score*100))
+		if result.Evidence != nil {
+			report.WriteString(fmt.Sprintf(", %s %s", result.Evidence.Kind, result.Evidence.Name))
+		}
+		report.WriteString(")\n")
+	}
+	return report.String()
+}
+
+// stopwords are words release notes use regardless of the change
+var stopwords = map[string]bool{
+	"the": true, "and": true, "for": true, "with": true, "from": true, "into": true,
+	"that": true, "this": true, "when": true, "now": true, "are": true, "was": true,
+	"can": true, "will": true, "its": true, "their": true, "than": true, "more": true,
+	"add": true, "added": true, "adds": true, "new": true, "support": true, "supports": true,
+	"fix": true, "fixed": true, "fixes": true, "change": true, "changed": true, "changes": true,
+	"update": true, "updated": true, "updates": true, "improve": true, "improved": true,
+	"improves": true, "remove": true, "removed": true, "removes": true, "allow": true,
+	"allows": true, "use": true, "uses": true, "using": true, "feature": true, "feat": true,
+	"chore": true, "refactor": true, "docs": true, "better": true, "option": true,
+	"instead": true, "users": true, "user": true, "you": true, "your": true,
+}
+
+// keywords returns the distinct significant words of text, stemmed
+func keywords(text string) []string {
+	seen := make(map[string]bool)
+	var words []string
+	for _, word := range splitWords(text) {
+		if len(word) < 3 || stopwords[word] {
+			continue
+		}
+		word = stem(word)
+		if !seen[word] {
+			seen[word] = true
+			words = append(words, word)
+		}
+	}
+	return words
+}
+
+// keywordSet is keywords as a set
+func keywordSet(text string) map[string]bool {
+	set := make(map[string]bool)
+	for _, word := range keywords(text) {
+		set[word] = true
+	}
+	return set
+}
+
+// splitWords lowercases text and splits it into words, also splitting
+// identifiers such as darkMode and dark_mode
+func splitWords(text string) []string {
+	var words []string
+	var word []rune
+	flush := func() {
+		if len(word) > 0 {
+			words = append(words, strings.ToLower(string(word)))
+			word = word[:0]
+		}
+	}
+
+	runes := []rune(text)
+	for i, r := range runes {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			flush()
+			continue
+		}
+		// Split camelCase, keeping acronyms such as "JSONSchema" as "JSON", "Schema"
+		if unicode.IsUpper(r) && len(word) > 0 {
+			prev := runes[i-1]
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if unicode.IsLower(prev) || (unicode.IsUpper(prev) && nextLower) {
+				flush()
+			}
+		}
+		word = append(word, r)
+	}
+	flush()
+	return words
+}
+
+// stem reduces inflected forms to a common prefix, so "validation" matches
+// "validated" and "exports" matches "export"
+func stem(word string) string {
+	const length = 6
+	word = strings.TrimSuffix(word, "s")
+	if len(word) > length {
+		return word[:length]
+	}
+	return word
+}
@@ -0,0 +1,92 @@
+package grounding
+
+import (
+	"strings"
+	"testing"
+)
+
+const notes = `### Added
+- **Structured Output** - Ask the model for JSON validated against a schema
+- **Dark mode** - New dark theme for the UI
+
+### Fixed
+- **Export crash** - Fixed a crash in exportReleaseNotes
+  when the output directory is missing
+`
+
+func testSources() []Source {
+	return []Source{
+		{Kind: SourceCommit, Name: "feat: add structured JSON output with schema validation"},
+		{Kind: SourceFile, Name: "internal/export/export.go"},
+		{Kind: SourceHunk, Name: "internal/export/export.go @@ -10,3 +10,6 @@",
+			Text: "+func exportReleaseNotes(dir string) error {\n+\tif _, err := os.Stat(dir); err != nil { // missing output directory\n"},
+	}
+}
+
+func TestParseItems(t *testing.T) {
+	items := ParseItems(notes)
+	if len(items) != 3 {
+		t.Fatalf("Expected 3 items, got %d", len(items))
+	}
+	if items[1].Section != "Added" || items[2].Section != "Fixed" {
+		t.Errorf("Unexpected sections: %q, %q", items[1].Section, items[2].Section)
+	}
+	if !strings.HasSuffix(items[2].Text, "when the output directory is missing") {
+		t.Errorf("Expected the continuation line in the item, got %q", items[2].Text)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	results := Check(notes, testSources(), 0.5)
+
+	ungrounded := Ungrounded(results)
+	if len(ungrounded) != 1 || !strings.Contains(ungrounded[0].Item.Text, "Dark mode") {
+		t.Fatalf("Expected only the dark mode item ungrounded, got %+v", ungrounded)
+	}
+	if results[0].Evidence == nil || results[0].Evidence.Kind != SourceCommit {
+		t.Errorf("Expected the commit as evidence for structured output, got %+v", results[0].Evidence)
+	}
+	if results[2].Evidence == nil || results[2].Evidence.Kind != SourceHunk {
+		t.Errorf("Expected the hunk as evidence for the crash fix, got %+v", results[2].Evidence)
+	}
+	if !strings.Contains(Report(results), "[Added] **Dark mode**") {
+		t.Errorf("Report should list the ungrounded item:\n%s", Report(results))
+	}
+}
+
+func TestApply(t *testing.T) {
+	results := Check(notes, testSources(), 0.5)
+
+	marked := Apply(notes, results, "mark")
+	if !strings.Contains(marked, "New dark theme for the UI"+UnverifiedMarker) {
+		t.Errorf("Expected the item marked:\n%s", marked)
+	}
+
+	removed := Apply(notes, results, "remove")
+	if strings.Contains(removed, "Dark mode") || !strings.Contains(removed, "Structured Output") {
+		t.Errorf("Expected only the dark mode item removed:\n%s", removed)
+	}
+
+	if Apply(notes, results, "warn") != notes {
+		t.Error("warn should leave the release notes unchanged")
+	}
+}
+
+func TestApplyRemovesEmptySections(t *testing.T) {
+	content := "### Added\n- **Dark mode** - New dark theme\n\n### Fixed\n- **Export crash** - exportReleaseNotes no longer crashes\n"
+	removed := Apply(content, Check(content, testSources(), 0.5), "remove")
+
+	if strings.Contains(removed, "### Added") {
+		t.Errorf("Expected the empty section removed:\n%s", removed)
+	}
+	if !strings.Contains(removed, "### Fixed\n- **Export crash**") {
+		t.Errorf("Expected the grounded section kept:\n%s", removed)
+	}
+}
+
+func TestSplitWords(t *testing.T) {
+	got := strings.Join(splitWords("parseJSONSchema dark_mode HTTPServer"), " ")
+	if got != "parse json schema dark mode http server" {
+		t.Errorf("splitWords() = %q", got)
+	}
+}
@@ -0,0 +1,156 @@
+package notes
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Sections are the section names the model may use, in the order they are
+// rendered when the output config doesn't list them
+var Sections = []string{"breaking", "added", "changed", "fixed", "dependencies", "deprecated", "security"}
+
+// Notes are release notes as structured output from the model
+type Notes struct {
+	Sections []Section `json:"sections"`
+}
+
+// Section is a changelog section such as "added" with its items
+type Section struct {
+	Name  string `json:"name"`
+	Items []Item `json:"items"`
+}
+
+// Item is one change in a section
+type Item struct {
+	Title       string   `json:"title"`
+	Description string   `json:"description"`
+	Commits     []string `json:"commits"` // Abbreviated hashes of the commits the item comes from
+}
+
+// Schema returns the JSON Schema of Notes, in the strict form structured
+// output modes require: every property required, no additional properties
+func Schema() map[string]interface{} {
+	item := map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"title":       map[string]interface{}{"type": "string"},
+			"description": map[string]interface{}{"type": "string"},
+			"commits": map[string]interface{}{
+				"type":  "array",
+				"items": map[string]interface{}{"type": "string"},
+			},
+		},
+		"required":             []string{"title", "description", "commits"},
+		"additionalProperties": false,
+	}
+
+	section := map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"name":  map[string]interface{}{"type": "string", "enum": Sections},
+			"items": map[string]interface{}{"type": "array", "items": item},
+		},
+		"required":             []string{"name", "items"},
+		"additionalProperties": false,
+	}
+
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"sections": map[string]interface{}{"type": "array", "items": section},
+		},
+		"required":             []string{"sections"},
+		"additionalProperties": false,
+	}
+}
+
+// Parse decodes model output into Notes. Text around the JSON object, such as
+// a ```json fence, is ignored; unknown fields are an error.
+func Parse(content string) (*Notes, error) {
+	start := strings.Index(content, "{")
+	end := strings.LastIndex(content, "}")
+	if start < 0 || end < start {
+		return nil, errors.New("no JSON object in the response")
+	}
+
+	decoder := json.NewDecoder(strings.NewReader(content[start : end+1]))
+	decoder.DisallowUnknownFields()
+
+	var notes Notes
+	if err := decoder.Decode(&notes); err != nil {
+		return nil, fmt.Errorf("invalid JSON: %w", err)
+	}
+	return &notes, nil
+}
+
+// Validate checks the notes against the schema rules that decoding doesn't
+// enforce, and that every cited commit is one of knownCommits (abbreviated
+// hashes; a citation may be longer or shorter than the known hash). A nil
+// knownCommits skips the commit check, for when the hashes couldn't be read.
+// All problems are reported together so one repair request can fix them.
+func (n *Notes) Validate(knownCommits []string) error {
+	var problems []string
+	for i := range n.Sections {
+		section := &n.Sections[i]
+		section.Name = strings.ToLower(strings.TrimSpace(section.Name))
+		if !isSection(section.Name) {
+			problems = append(problems, fmt.Sprintf("section %d: unknown name %q (use one of: %s)",
+				i+1, section.Name, strings.Join(Sections, ", ")))
+		}
+
+		for j, item := range section.Items {
+			where := fmt.Sprintf("section %q item %d", section.Name, j+1)
+			if strings.TrimSpace(item.Title) == "" {
+				problems = append(problems, where+": title is empty")
+			}
+			for _, commit := range item.Commits {
+				if knownCommits != nil && !isKnownCommit(commit, knownCommits) {
+					problems = append(problems, fmt.Sprintf("%s: commit %q is not in the commit history", where, commit))
+				}
+			}
+		}
+	}
+
+	if len(problems) > 0 {
+		return errors.New(strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// isSection reports whether name is one of Sections
+func isSection(name string) bool {
+	for _, section := range Sections {
+		if name == section {
+			return true
+		}
+	}
+	return false
+}
+
+// isKnownCommit matches a cited hash against the known abbreviated hashes
+func isKnownCommit(commit string, known []string) bool {
+	commit = strings.ToLower(strings.TrimSpace(commit))
+	if len(commit) < 4 {
+		return false
+	}
+	for _, hash := range known {
+		if strings.HasPrefix(commit, hash) || strings.HasPrefix(hash, commit) {
+			return true
+		}
+	}
+	return false
+}
+
+// Section returns the items of the named section, merging sections the
+// model listed more than once
+func (n *Notes) Section(name string) []Item {
+	var items []Item
+	for _, section := range n.Sections {
+		if section.Name == name {
+			items = append(items, section.Items...)
+		}
+	}
+	return items
+}
@@ -0,0 +1,81 @@
+package notes
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	content := "Here are the notes:\n```json\n" +
+		`{"sections": [{"name": "added", "items": [{"title": "PDF export", "description": "Export as PDF.", "commits": ["a1b2c3d"]}]}]}` +
+		"\n```"
+
+	notes, err := Parse(content)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	items := notes.Section("added")
+	if len(items) != 1 || items[0].Title != "PDF export" || items[0].Commits[0] != "a1b2c3d" {
+		t.Errorf("Unexpected notes: %+v", notes)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, content := range []string{
+		"### Added\n- PDF export",
+		`{"sections": [{"name": "added", "items": [], "summary": "x"}]}`,
+		`{"sections": "added"}`,
+	} {
+		if _, err := Parse(content); err == nil {
+			t.Errorf("Parse(%q) should fail", content)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	notes := &Notes{Sections: []Section{
+		{Name: " Added ", Items: []Item{{Title: "Export", Commits: []string{"a1b2c3d4e5"}}}},
+		{Name: "misc", Items: []Item{{Title: "", Commits: []string{"deadbee"}}}},
+	}}
+
+	err := notes.Validate([]string{"a1b2c3d", "e4f5a6b"})
+	if err == nil {
+		t.Fatal("Expected validation errors")
+	}
+	for _, want := range []string{
+		`section 2: unknown name "misc"`,
+		`section "misc" item 1: title is empty`,
+		`commit "deadbee" is not in the commit history`,
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Error should contain %q, got: %v", want, err)
+		}
+	}
+	if notes.Sections[0].Name != "added" {
+		t.Errorf("Section names should be normalized, got %q", notes.Sections[0].Name)
+	}
+
+	valid := &Notes{Sections: []Section{{Name: "fixed", Items: []Item{{Title: "Crash", Commits: []string{"E4F5A6B"}}}}}}
+	if err := valid.Validate([]string{"e4f5a6b"}); err != nil {
+		t.Errorf("Validate() error = %v", err)
+	}
+	if err := valid.Validate(nil); err != nil {
+		t.Errorf("Expected no commit check without known commits, got %v", err)
+	}
+	if err := valid.Validate([]string{}); err == nil {
+		t.Error("Expected an empty commit history to reject cited commits")
+	}
+}
+
+func TestSchema(t *testing.T) {
+	data, err := json.Marshal(Schema())
+	if err != nil {
+		t.Fatalf("Schema should marshal: %v", err)
+	}
+	for _, want := range []string{`"additionalProperties":false`, `"enum":["breaking","added"`, `"required":["title","description","commits"]`} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("Schema should contain %s", want)
+		}
+	}
+}
@@ -0,0 +1,37 @@
+package prompt
+
+import (
+	"fmt"
+	"strings"
+)
+
+// EnsembleJudgeSystemPrompt returns the instructions for the ensemble judge,
+// which picks the best of several candidate changelogs
+func EnsembleJudgeSystemPrompt(fence Fence) string {
+	var prompt strings.Builder
+
+	prompt.WriteString("You compare candidate release notes written for the same release.\n\n")
+	fence.writeSecurityRules(&prompt)
+
+	prompt.WriteString("## Task\n\n")
+	prompt.WriteString("The candidates are numbered. Pick the one that covers the user-facing changes in the commits most completely and accurately, ")
+	prompt.WriteString("without inventing changes, with clear wording and correctly categorized sections.\n\n")
+	prompt.WriteString("Respond with only a JSON object with the number of the best candidate, for example: {\"best\": 2}\n")
+
+	return prompt.String()
+}
+
+// EnsembleJudgePrompt lists the candidates with the commits as evidence
+func EnsembleJudgePrompt(candidates, commits []string, fence Fence) string {
+	var prompt strings.Builder
+
+	for i, candidate := range candidates {
+		prompt.WriteString(fmt.Sprintf("## Candidate %d\n\n", i+1))
+		prompt.WriteString(fence.Wrap(fmt.Sprintf("candidate-%d", i+1), candidate))
+		prompt.WriteString("\n")
+	}
+	prompt.WriteString("## Commits\n\n")
+	prompt.WriteString(fence.Wrap("commits", strings.Join(commits, "\n")))
+
+	return prompt.String()
+}
@@ -0,0 +1,41 @@
+package prompt
+
+import (
+	"fmt"
+	"strings"
+)
+
+// JudgeSystemPrompt returns the instructions for the grounding judge, which
+// decides which release notes items the commits and diff support
+func JudgeSystemPrompt(fence Fence) string {
+	var prompt strings.Builder
+
+	prompt.WriteString("You verify release notes against the changes they describe.\n\n")
+	fence.writeSecurityRules(&prompt)
+
+	prompt.WriteString("## Task\n\n")
+	prompt.WriteString("The release notes items are numbered. An item is supported when the commits or the diff show the change it describes; ")
+	prompt.WriteString("wording may differ, but a feature, fix or behavior that appears nowhere in the changes is not supported.\n\n")
+	prompt.WriteString("Respond with only a JSON object listing the numbers of the supported items, for example: {\"supported\": [1, 3]}\n")
+
+	return prompt.String()
+}
+
+// JudgePrompt lists the items to verify with the commits and diff as evidence
+func JudgePrompt(items, commits []string, diff string, fence Fence) string {
+	var prompt strings.Builder
+
+	var numbered strings.Builder
+	for i, item := range items {
+		numbered.WriteString(fmt.Sprintf("%d. %s\n", i+1, item))
+	}
+
+	prompt.WriteString("## Release Notes Items\n\n")
+	prompt.WriteString(fence.Wrap("items", numbered.String()))
+	prompt.WriteString("\n## Commits\n\n")
+	prompt.WriteString(fence.Wrap("commits", strings.Join(commits, "\n")))
+	prompt.WriteString("\n## Diff\n\n")
+	prompt.WriteString(fence.Wrap("diff", diff))
+
+	return prompt.String()
+}
@@ -0,0 +1,77 @@
+package prompt
+
+import (
+	"fmt"
+	"strings"
+)
+
+// NoChangesMarker is the answer a chunk summary gives when the chunk has no user-facing changes.
+const NoChangesMarker = "NO USER-FACING CHANGES"
+
+// ChunkSummary is the intermediate summary of one chunk of a map-reduce run.
+type ChunkSummary struct {
+	Component string // Directories covered by the chunk
+	Summary   string
+}
+
+// ChunkSystemPrompt returns the instructions for summarizing one chunk: how to
+// treat the fenced untrusted input, the output format and rules. It is sent as
+// the system prompt with GenerateChunkPrompt.
+func ChunkSystemPrompt(fence Fence) string {
+	var prompt strings.Builder
+
+	prompt.WriteString("You summarize one part of a large software release so it can be merged with the other parts into release notes.\n\n")
+	fence.writeSecurityRules(&prompt)
+
+	prompt.WriteString("## Output Format\n\n")
+	prompt.WriteString("Use ONLY these headings, omitting any without items:\n\n")
+	prompt.WriteString("```markdown\n")
+	prompt.WriteString("### Breaking\n- **Short title** - What changed and what users must do\n")
+	prompt.WriteString("### Added\n- **Short title** - New capability\n")
+	prompt.WriteString("### Changed\n- **Short title** - Improvement to existing behavior\n")
+	prompt.WriteString("### Fixed\n- **Short title** - Problem users no longer hit\n")
+	prompt.WriteString("### Deprecated\n- **Short title** - What is deprecated\n")
+	prompt.WriteString("### Security\n- **Short title** - Security fix or hardening\n")
+	prompt.WriteString("```\n\n")
+
+	prompt.WriteString("## Rules\n\n")
+	prompt.WriteString("- Base every item on the diff; commit messages may be incomplete or misleading\n")
+	prompt.WriteString("- One line per item, 1-2 sentences maximum\n")
+	prompt.WriteString("- Omit internal changes (refactoring, tests, CI/CD, docs)\n")
+	prompt.WriteString(fmt.Sprintf("- If nothing in this part affects users, output exactly: %s\n", NoChangesMarker))
+	prompt.WriteString("- Output only the summary, no preamble\n")
+
+	return prompt.String()
+}
+
+// GenerateChunkPrompt generates the map prompt asking for a structured summary
+// of one chunk (a component's commits and diff) of a large release. The
+// instructions are in ChunkSystemPrompt.
+func GenerateChunkPrompt(version, component string, part, parts int, commits []string, diff string, fence Fence) string {
+	var prompt strings.Builder
+
+	if version == "" {
+		version = "Unreleased"
+	}
+
+	prompt.WriteString("# Release Notes Chunk Summary\n\n")
+	prompt.WriteString(fmt.Sprintf("Release %s is too large to review at once, so it was split by component. "+
+		"This is part %d of %d and covers: `%s`.\n\n", version, part, parts, component))
+	prompt.WriteString("Summarize the user-facing changes in this part. Your summary will be merged with the other parts into the final release notes.\n\n")
+
+	prompt.WriteString("## Diff (PRIMARY SOURCE)\n\n")
+	if diff != "" {
+		prompt.WriteString(fence.Wrap("diff", diff))
+		prompt.WriteString("\n")
+	} else {
+		prompt.WriteString("(no diff for this part)\n\n")
+	}
+
+	prompt.WriteString("## Commits (Reference Only)\n\n")
+	prompt.WriteString(fence.Wrap("commits", strings.Join(commits, "\n")))
+	prompt.WriteString("\n")
+
+	prompt.WriteString("Summarize this part as described in your instructions.\n")
+
+	return prompt.String()
+}
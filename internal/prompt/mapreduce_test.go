@@ -0,0 +1,65 @@
+package prompt
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/1broseidon/promptext-notes/internal/analyzer"
+)
+
+func TestGenerateChunkPrompt(t *testing.T) {
+	prompt := GenerateChunkPrompt("v2.0.0", "internal/export", 2, 5,
+		[]string{"feat: add PDF export"}, "diff --git a/internal/export/pdf.go b/internal/export/pdf.go\n+func Export() {}", NewFence())
+
+	expected := []string{
+		"part 2 of 5",
+		"`internal/export`",
+		"feat: add PDF export",
+		"+func Export() {}",
+	}
+	for _, want := range expected {
+		if !strings.Contains(prompt, want) {
+			t.Errorf("Expected chunk prompt to contain %q", want)
+		}
+	}
+}
+
+func TestChunkSystemPrompt(t *testing.T) {
+	got := ChunkSystemPrompt(Fence{id: "f00d"})
+
+	for _, want := range []string{"<<<UNTRUSTED <source> f00d>>>", "## Output Format", "### Breaking", NoChangesMarker} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Expected chunk system prompt to contain %q", want)
+		}
+	}
+}
+
+func TestDefaultReducePrompt(t *testing.T) {
+	categories := analyzer.CommitCategories{Features: []string{"add export"}}
+	summaries := []ChunkSummary{
+		{Component: "cmd/app", Summary: "### Fixed\n- **Startup** - No longer crashes"},
+		{Component: "internal/export", Summary: "### Added\n- **PDF export** - Export as PDF"},
+	}
+
+	commits := make([]string, 1200)
+	data := NewTemplateData("v2.0.0", "v1.0.0", commits, categories, nil, "2 files changed", "", NewFence())
+	data.Summaries = summaries
+	prompt, err := Default().Reduce(data)
+	if err != nil {
+		t.Fatalf("Reduce() error = %v", err)
+	}
+
+	expected := []string{
+		"**Commits analyzed**: 1200",
+		"**Commit types**: 1 features, 0 fixes, 0 breaking, 0 other changes",
+		"**Parts summarized**: 2",
+		"### Part 1: `cmd/app`",
+		"### Part 2: `internal/export`",
+		"**PDF export** - Export as PDF",
+	}
+	for _, want := range expected {
+		if !strings.Contains(prompt, want) {
+			t.Errorf("Expected reduce prompt to contain %q", want)
+		}
+	}
+}
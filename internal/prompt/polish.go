@@ -0,0 +1,19 @@
+package prompt
+
+import "strings"
+
+// PolishRetryPrompt repeats the polish prompt with what the previous attempt
+// changed, asking to keep the draft's structure this time
+func PolishRetryPrompt(polishPrompt string, problems []string) string {
+	var prompt strings.Builder
+
+	prompt.WriteString(polishPrompt)
+	prompt.WriteString("\n\nYour previous output changed the structure of the changelog:\n")
+	for _, problem := range problems {
+		prompt.WriteString("- " + problem + "\n")
+	}
+	prompt.WriteString("\nPolish the changelog again. Keep the version header line unchanged, every section heading, ")
+	prompt.WriteString("and exactly the same items in each section; only improve the wording of each item.\n")
+
+	return prompt.String()
+}
@@ -0,0 +1,40 @@
+package prompt
+
+import (
+	"fmt"
+	"strings"
+)
+
+// JSONInstructions asks for the release notes as JSON matching schema instead
+// of markdown; it is appended to the system prompt when ai.output_format is json
+func JSONInstructions(schema string, sections []string) string {
+	var prompt strings.Builder
+
+	prompt.WriteString("\n## Output Format: JSON\n\n")
+	prompt.WriteString("Respond with a single JSON object and nothing else (no markdown, no code fence). ")
+	prompt.WriteString("These rules replace the markdown example format above; the section and item rules still apply.\n\n")
+	prompt.WriteString("```json\n")
+	prompt.WriteString(schema)
+	prompt.WriteString("\n```\n\n")
+	prompt.WriteString(fmt.Sprintf("- `name` is the section: %s\n", strings.Join(sections, ", ")))
+	prompt.WriteString("- `title` is the short item title and `description` its 1-2 sentences, both without markdown bullets or bold\n")
+	prompt.WriteString("- `commits` lists the hashes shown before each commit in the commit history that the item comes from, or [] if none\n")
+	prompt.WriteString("- Leave out sections without items\n")
+
+	return prompt.String()
+}
+
+// JSONRepairPrompt asks the model to fix a response that failed validation
+func JSONRepairPrompt(promptText, response string, problem error) string {
+	var prompt strings.Builder
+
+	prompt.WriteString(promptText)
+	prompt.WriteString("\n## Invalid Previous Response\n\n")
+	prompt.WriteString("Your previous response did not match the required JSON format:\n\n")
+	prompt.WriteString(problem.Error())
+	prompt.WriteString("\n\nPrevious response:\n\n")
+	prompt.WriteString(response)
+	prompt.WriteString("\n\nRespond again with the corrected JSON object only.\n")
+
+	return prompt.String()
+}
@@ -0,0 +1,30 @@
+package prompt
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestJSONInstructions(t *testing.T) {
+	instructions := JSONInstructions(`{"type": "object"}`, []string{"added", "fixed"})
+
+	for _, want := range []string{"## Output Format: JSON", "```json\n{\"type\": \"object\"}\n```", "added, fixed", "`commits`"} {
+		if !strings.Contains(instructions, want) {
+			t.Errorf("Instructions should contain %q", want)
+		}
+	}
+}
+
+func TestJSONRepairPrompt(t *testing.T) {
+	repair := JSONRepairPrompt("original prompt", `{"sections": 1}`, errors.New("bad sections"))
+
+	if !strings.HasPrefix(repair, "original prompt") {
+		t.Error("Repair prompt should start with the original prompt")
+	}
+	for _, want := range []string{"bad sections", `{"sections": 1}`, "corrected JSON"} {
+		if !strings.Contains(repair, want) {
+			t.Errorf("Repair prompt should contain %q", want)
+		}
+	}
+}
@@ -0,0 +1,296 @@
+package prompt
+
+import (
+	_ "embed"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/1broseidon/promptext-notes/internal/analyzer"
+	"github.com/1broseidon/promptext/pkg/promptext"
+)
+
+// DefaultTemplate is the built-in discovery prompt template
+//
+//go:embed templates/discovery.tmpl
+var DefaultTemplate string
+
+// DefaultPolishTemplate is the built-in polish prompt template
+//
+//go:embed templates/polish.tmpl
+var DefaultPolishTemplate string
+
+// TemplateData is what prompt templates are rendered with
+type TemplateData struct {
+	Version       string                    // Version being released, "Unreleased" if not set
+	Date          string                    // Today's date, YYYY-MM-DD (pinned by SOURCE_DATE_EPOCH)
+	Range         Range                     // Refs the release covers
+	Commits       []string                  // Commit subjects, after filtering
+	Categories    analyzer.CommitCategories // Commits by type, plus detected API and dependency changes
+	ChangeType    string                    // Kinds of change, e.g. "new features, bug fixes"
+	DiffStats     string                    // git diff --stat output
+	Diff          string                    // Diff fitted to the diff budget; files left out are stat lines
+	CodeContext   string                    // Formatted contents of the context files
+	ContextTokens int                       // Tokens in the code context
+	ContextFiles  []ContextFile             // Files in the code context
+	PreviousNotes string                    // Release notes of the previous version from CHANGELOG.md, if any
+	Draft         string                    // Output of the previous pipeline stage (stages after discovery)
+	Outputs       map[string]string         // Outputs of the earlier pipeline stages by stage name
+	Summaries     []ChunkSummary            // Part summaries of a map-reduce run ("reduce" block only)
+	Fence         Fence                     // Encloses untrusted input: {{.Fence.Wrap "commits" (join .Commits "\n")}}
+}
+
+// Range is the span of history a release covers
+type Range struct {
+	From string // Tag the release starts after
+	To   string // Ref the release ends at
+}
+
+// ContextFile is a file in the code context
+type ContextFile struct {
+	Path   string
+	Tokens int
+}
+
+// Today returns the release date, YYYY-MM-DD. SOURCE_DATE_EPOCH (seconds
+// since 1970) pins it, so prompts are reproducible across days.
+func Today() string {
+	now := time.Now()
+	if epoch, err := strconv.ParseInt(os.Getenv("SOURCE_DATE_EPOCH"), 10, 64); err == nil {
+		now = time.Unix(epoch, 0).UTC()
+	}
+	return now.Format("2006-01-02")
+}
+
+// NewTemplateData collects the release inputs for rendering a template
+func NewTemplateData(version, fromTag string, commits []string, categories analyzer.CommitCategories, result *promptext.Result, diffStats, diff string, fence Fence) *TemplateData {
+	if version == "" {
+		version = "Unreleased"
+	}
+
+	data := &TemplateData{
+		Version:    version,
+		Date:       Today(),
+		Range:      Range{From: fromTag, To: "HEAD"},
+		Commits:    commits,
+		Categories: categories,
+		ChangeType: changeType(categories),
+		DiffStats:  diffStats,
+		Diff:       diff,
+		Fence:      fence,
+	}
+	if result != nil {
+		data.CodeContext = result.FormattedOutput
+		data.ContextTokens = result.TokenCount
+		if result.ProjectOutput != nil {
+			for _, file := range result.ProjectOutput.Files {
+				data.ContextFiles = append(data.ContextFiles, ContextFile{Path: file.Path, Tokens: file.Tokens})
+			}
+		}
+	}
+	return data
+}
+
+// changeType summarizes the kinds of change in a release
+func changeType(categories analyzer.CommitCategories) string {
+	var changeTypes []string
+	if len(categories.Breaking) > 0 || len(categories.APIChanges) > 0 {
+		changeTypes = append(changeTypes, "breaking changes")
+	}
+	if len(categories.Features) > 0 {
+		changeTypes = append(changeTypes, "new features")
+	}
+	if len(categories.Fixes) > 0 {
+		changeTypes = append(changeTypes, "bug fixes")
+	}
+	if len(categories.Changes) > 0 {
+		changeTypes = append(changeTypes, "improvements")
+	}
+
+	if len(changeTypes) == 0 {
+		return "miscellaneous updates"
+	}
+	return strings.Join(changeTypes, ", ")
+}
+
+// templateFuncs are available in prompt templates in addition to the
+// text/template builtins
+var templateFuncs = template.FuncMap{
+	"join":     func(items []string, sep string) string { return strings.Join(items, sep) },
+	"bullets":  func(items []string) string { return "- " + strings.Join(items, "\n- ") },
+	"contains": strings.Contains,
+	"lower":    strings.ToLower,
+	"upper":    strings.ToUpper,
+	"trim":     strings.TrimSpace,
+	"add":      func(a, b int) int { return a + b },
+}
+
+// Template is a parsed prompt template. Its main body renders the prompt; an
+// optional "system" block renders the instructions sent as the system prompt,
+// and a "reduce" block the prompt merging map-reduce part summaries.
+type Template struct {
+	tmpl *template.Template
+}
+
+var defaultTemplate = template.Must(template.New("discovery").Funcs(templateFuncs).Parse(DefaultTemplate))
+
+// Default returns the parsed DefaultTemplate
+func Default() *Template {
+	return &Template{tmpl: defaultTemplate}
+}
+
+// ParseTemplate parses a discovery prompt template. Blocks it doesn't define,
+// such as "system", are taken from DefaultTemplate; empty text is the default.
+func ParseTemplate(text string) (*Template, error) {
+	tmpl := template.Must(defaultTemplate.Clone())
+	if strings.TrimSpace(text) == "" {
+		return &Template{tmpl: tmpl}, nil
+	}
+
+	if _, err := tmpl.Parse(text); err != nil {
+		return nil, err
+	}
+	return &Template{tmpl: tmpl}, nil
+}
+
+// LoadTemplate reads and parses a discovery prompt template file; an empty
+// path returns the default template
+func LoadTemplate(path string) (*Template, error) {
+	if path == "" {
+		return ParseTemplate("")
+	}
+
+	text, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read prompt template: %w", err)
+	}
+	tmpl, err := ParseTemplate(string(text))
+	if err != nil {
+		return nil, fmt.Errorf("invalid prompt template %s: %w", path, err)
+	}
+	return tmpl, nil
+}
+
+// Prompt renders the prompt
+func (t *Template) Prompt(data *TemplateData) (string, error) {
+	return t.execute("", data)
+}
+
+// Reduce renders the "reduce" block: the prompt that merges data.Summaries
+// into the release notes
+func (t *Template) Reduce(data *TemplateData) (string, error) {
+	return t.execute("reduce", data)
+}
+
+// System renders the system prompt: the rules for the fenced untrusted input,
+// which templates can't change, followed by the template's "system" block
+func (t *Template) System(data *TemplateData) (string, error) {
+	instructions, err := t.execute("system", data)
+	if err != nil {
+		return "", err
+	}
+
+	var prompt strings.Builder
+	prompt.WriteString("You write release notes for a software project from its git history and code changes.\n\n")
+	data.Fence.writeSecurityRules(&prompt)
+	prompt.WriteString(instructions)
+	return prompt.String(), nil
+}
+
+// execute renders the named block, or the main body when name is empty
+func (t *Template) execute(name string, data *TemplateData) (string, error) {
+	var out strings.Builder
+	var err error
+	if name == "" {
+		err = t.tmpl.Execute(&out, data)
+	} else {
+		err = t.tmpl.ExecuteTemplate(&out, name, data)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to render prompt template: %w", err)
+	}
+	return out.String(), nil
+}
+
+// StageTemplate is a parsed prompt template for a pipeline stage after
+// discovery, such as polish
+type StageTemplate struct {
+	name string
+	tmpl *template.Template
+}
+
+// PolishTemplate is the template of the polish stage
+type PolishTemplate = StageTemplate
+
+// LoadPolishTemplate reads and parses a polish prompt template file; an empty
+// path returns DefaultPolishTemplate
+func LoadPolishTemplate(path string) (*PolishTemplate, error) {
+	if path == "" {
+		return parseStageTemplate("polish", "", DefaultPolishTemplate)
+	}
+	return LoadStageTemplate("polish", path)
+}
+
+// LoadStageTemplate reads and parses the prompt template file of the named
+// pipeline stage
+func LoadStageTemplate(name, path string) (*StageTemplate, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s prompt template: %w", name, err)
+	}
+	return parseStageTemplate(name, path, string(content))
+}
+
+// parseStageTemplate parses a stage template's text
+func parseStageTemplate(name, path, text string) (*StageTemplate, error) {
+	tmpl, err := template.New(name).Funcs(templateFuncs).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s prompt template %s: %w", name, path, err)
+	}
+	return &StageTemplate{name: name, tmpl: tmpl}, nil
+}
+
+// Prompt renders the stage prompt; data.Draft holds the previous stage's output
+func (t *StageTemplate) Prompt(data *TemplateData) (string, error) {
+	var out strings.Builder
+	if err := t.tmpl.Execute(&out, data); err != nil {
+		return "", fmt.Errorf("failed to render %s prompt template: %w", t.name, err)
+	}
+	return out.String(), nil
+}
+
+// StageSystemPrompt returns the system prompt of a pipeline stage after
+// discovery: the rules for the fenced untrusted input, which a stage template
+// may include with .Diff, .Commits or .CodeContext
+func StageSystemPrompt(fence Fence) string {
+	var prompt strings.Builder
+	prompt.WriteString("You revise release notes for a software project as one stage of a pipeline.\n\n")
+	fence.writeSecurityRules(&prompt)
+	return prompt.String()
+}
+
+// PreviousNotes returns the first release section ("## [...]") of a
+// Keep a Changelog file, or "" if there is none
+func PreviousNotes(changelog string) string {
+	lines := strings.Split(changelog, "\n")
+	start := -1
+	for i, line := range lines {
+		if !strings.HasPrefix(line, "## ") {
+			continue
+		}
+		if start >= 0 {
+			return strings.TrimSpace(strings.Join(lines[start:i], "\n"))
+		}
+		// An [Unreleased] section holds no released notes
+		if strings.HasPrefix(line, "## [") && !strings.HasPrefix(strings.ToLower(line), "## [unreleased]") {
+			start = i
+		}
+	}
+	if start < 0 {
+		return ""
+	}
+	return strings.TrimSpace(strings.Join(lines[start:], "\n"))
+}
@@ -0,0 +1,208 @@
+package prompt
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/1broseidon/promptext-notes/internal/analyzer"
+	"github.com/1broseidon/promptext/pkg/promptext"
+)
+
+func newTemplateData() *TemplateData {
+	categories := analyzer.CommitCategories{Features: []string{"add export"}, Fixes: []string{"fix crash"}}
+	result := &promptext.Result{
+		TokenCount:      10,
+		FormattedOutput: "package export",
+		ProjectOutput: &promptext.ProjectOutput{
+			Files: []promptext.FileInfo{{Path: "export.go", Tokens: 10}},
+		},
+	}
+	data := NewTemplateData("v1.2.0", "v1.1.0", []string{"feat: add export", "fix: crash"},
+		categories, result, "1 file changed", "+func Export() {}", Fence{id: "f00d"})
+	data.PreviousNotes = "## [v1.1.0] - 2025-01-01\n\n### Added\n- Import"
+	return data
+}
+
+func TestNewTemplateData(t *testing.T) {
+	data := newTemplateData()
+
+	if data.Range != (Range{From: "v1.1.0", To: "HEAD"}) {
+		t.Errorf("Range = %+v", data.Range)
+	}
+	if data.ChangeType != "new features, bug fixes" {
+		t.Errorf("ChangeType = %q", data.ChangeType)
+	}
+	if len(data.ContextFiles) != 1 || data.ContextFiles[0] != (ContextFile{Path: "export.go", Tokens: 10}) {
+		t.Errorf("ContextFiles = %+v", data.ContextFiles)
+	}
+	if got := NewTemplateData("", "", nil, analyzer.CommitCategories{}, nil, "", "", Fence{}); got.Version != "Unreleased" {
+		t.Errorf("Version = %q, want Unreleased", got.Version)
+	}
+}
+
+func TestToday(t *testing.T) {
+	t.Setenv("SOURCE_DATE_EPOCH", "1735689600")
+	if got := Today(); got != "2025-01-01" {
+		t.Errorf("Today() = %q with SOURCE_DATE_EPOCH set, want 2025-01-01", got)
+	}
+
+	t.Setenv("SOURCE_DATE_EPOCH", "")
+	if got := Today(); got != time.Now().Format("2006-01-02") {
+		t.Errorf("Today() = %q, want today's date", got)
+	}
+}
+
+func TestParseTemplate(t *testing.T) {
+	tmpl, err := ParseTemplate(`Notes for {{.Version}} ({{.Range.From}}..{{.Range.To}}), {{len .Commits}} commits.
+{{.Fence.Wrap "commits" (join .Commits "\n")}}Last time:
+{{.PreviousNotes}}`)
+	if err != nil {
+		t.Fatalf("ParseTemplate() error = %v", err)
+	}
+
+	data := newTemplateData()
+	got, err := tmpl.Prompt(data)
+	if err != nil {
+		t.Fatalf("Prompt() error = %v", err)
+	}
+	want := "Notes for v1.2.0 (v1.1.0..HEAD), 2 commits.\n" +
+		"<<<UNTRUSTED commits f00d>>>\nfeat: add export\nfix: crash\n<<<END commits f00d>>>\n" +
+		"Last time:\n## [v1.1.0] - 2025-01-01\n\n### Added\n- Import"
+	if got != want {
+		t.Errorf("Prompt() = %q, want %q", got, want)
+	}
+
+	// Without a "system" block the default instructions are used
+	system, err := tmpl.System(data)
+	if err != nil {
+		t.Fatalf("System() error = %v", err)
+	}
+	if system != renderSystem(t, data) {
+		t.Error("Expected the default system prompt")
+	}
+}
+
+func TestParseTemplateSystemBlock(t *testing.T) {
+	tmpl, err := ParseTemplate(`{{define "system"}}Write playful notes for {{.Version}}.{{end}}{{.Diff}}`)
+	if err != nil {
+		t.Fatalf("ParseTemplate() error = %v", err)
+	}
+
+	system, err := tmpl.System(newTemplateData())
+	if err != nil {
+		t.Fatalf("System() error = %v", err)
+	}
+	// The rules for untrusted input are kept ahead of the template's instructions
+	if !strings.Contains(system, "## Untrusted Input") || !strings.HasSuffix(system, "Write playful notes for v1.2.0.") {
+		t.Errorf("Unexpected system prompt:\n%s", system)
+	}
+	if strings.Contains(system, "## Critical Rules") {
+		t.Error("The template's system block should replace the default one")
+	}
+}
+
+func TestParseTemplateErrors(t *testing.T) {
+	if _, err := ParseTemplate("{{.Version"); err == nil {
+		t.Error("Expected a parse error")
+	}
+
+	tmpl, err := ParseTemplate("{{.Author}}")
+	if err != nil {
+		t.Fatalf("ParseTemplate() error = %v", err)
+	}
+	if _, err := tmpl.Prompt(newTemplateData()); err == nil || !strings.Contains(err.Error(), "Author") {
+		t.Errorf("Expected an error naming the unknown field, got %v", err)
+	}
+}
+
+func TestLoadTemplate(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "prompt.tmpl")
+	if err := os.WriteFile(path, []byte("Release {{.Version}}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tmpl, err := LoadTemplate(path)
+	if err != nil {
+		t.Fatalf("LoadTemplate() error = %v", err)
+	}
+	if got, _ := tmpl.Prompt(newTemplateData()); got != "Release v1.2.0" {
+		t.Errorf("Prompt() = %q", got)
+	}
+
+	if _, err := LoadTemplate(filepath.Join(dir, "missing.tmpl")); err == nil {
+		t.Error("Expected an error for a missing template")
+	}
+
+	// An empty path is the default template
+	tmpl, err = LoadTemplate("")
+	if err != nil {
+		t.Fatalf("LoadTemplate(\"\") error = %v", err)
+	}
+	data := newTemplateData()
+	got, _ := tmpl.Prompt(data)
+	if got != renderPrompt(t, data) {
+		t.Error("Expected the default template to render the default prompt")
+	}
+}
+
+func TestLoadPolishTemplate(t *testing.T) {
+	data := newTemplateData()
+	data.Draft = "### Added\n- **Export** - export things"
+
+	tmpl, err := LoadPolishTemplate("")
+	if err != nil {
+		t.Fatalf("LoadPolishTemplate() error = %v", err)
+	}
+	got, err := tmpl.Prompt(data)
+	if err != nil {
+		t.Fatalf("Prompt() error = %v", err)
+	}
+	if !strings.Contains(got, "CHANGELOG:\n### Added\n- **Export** - export things\n") ||
+		!strings.Contains(got, "Keep the exact same items") {
+		t.Errorf("Unexpected default polish prompt:\n%s", got)
+	}
+
+	path := filepath.Join(t.TempDir(), "polish.tmpl")
+	if err := os.WriteFile(path, []byte("Make {{.Version}} upbeat:\n{{.Draft}}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	tmpl, err = LoadPolishTemplate(path)
+	if err != nil {
+		t.Fatalf("LoadPolishTemplate() error = %v", err)
+	}
+	if got, _ := tmpl.Prompt(data); got != "Make v1.2.0 upbeat:\n"+data.Draft {
+		t.Errorf("Prompt() = %q", got)
+	}
+}
+
+func TestPreviousNotes(t *testing.T) {
+	changelog := `# Changelog
+
+## [Unreleased]
+
+### Added
+- Work in progress
+
+## [v1.1.0] - 2025-01-01
+
+### Added
+- Import
+
+## [v1.0.0] - 2024-12-01
+
+### Added
+- First release
+`
+
+	want := "## [v1.1.0] - 2025-01-01\n\n### Added\n- Import"
+	if got := PreviousNotes(changelog); got != want {
+		t.Errorf("PreviousNotes() = %q, want %q", got, want)
+	}
+	if got := PreviousNotes("# Changelog\n"); got != "" {
+		t.Errorf("PreviousNotes() = %q, want empty", got)
+	}
+}
@@ -0,0 +1,212 @@
+package redact
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// DefaultEntropyThreshold is the Shannon entropy, in bits per character, from
+// which a value assigned to a key, token, secret or password is treated as a secret
+const DefaultEntropyThreshold = 3.5
+
+// Kinds of secret reported by the redactor
+const (
+	KindPrivateKey  = "private-key"
+	KindHighEntropy = "high-entropy"
+	KindCustom      = "custom"
+)
+
+// rule is a secret format recognized by its shape
+type rule struct {
+	kind    string
+	pattern *regexp.Regexp
+}
+
+// builtinRules are well-known credential formats
+var builtinRules = []rule{
+	{"aws-access-key", regexp.MustCompile(`\b(?:AKIA|ASIA|AGPA|AIDA|AROA)[0-9A-Z]{16}\b`)},
+	{"gcp-api-key", regexp.MustCompile(`\bAIza[0-9A-Za-z_\-]{35}\b`)},
+	{"github-token", regexp.MustCompile(`\b(?:gh[pousr]_[A-Za-z0-9]{36,}|github_pat_[A-Za-z0-9_]{22,})\b`)},
+	{"gitlab-token", regexp.MustCompile(`\bglpat-[A-Za-z0-9_\-]{20,}\b`)},
+	{"slack-token", regexp.MustCompile(`\bxox[abposr]-[A-Za-z0-9-]{10,}\b`)},
+	{"stripe-key", regexp.MustCompile(`\b[rs]k_(?:live|test)_[A-Za-z0-9]{16,}\b`)},
+	{"ai-api-key", regexp.MustCompile(`\bsk-(?:ant-|proj-)?[A-Za-z0-9_\-]{20,}\b`)},
+	{"azure-storage-key", regexp.MustCompile(`AccountKey=[A-Za-z0-9+/]{40,}={0,2}`)},
+	{"jwt", regexp.MustCompile(`\beyJ[A-Za-z0-9_\-]{8,}\.eyJ[A-Za-z0-9_\-]{8,}\.[A-Za-z0-9_\-]{8,}`)},
+}
+
+var (
+	privateKeyBegin = regexp.MustCompile(`-----BEGIN [A-Z0-9 ]*PRIVATE KEY( BLOCK)?-----`)
+	privateKeyEnd   = regexp.MustCompile(`-----END [A-Z0-9 ]*PRIVATE KEY( BLOCK)?-----`)
+
+	// assignment matches a value assigned to a name that suggests a credential,
+	// e.g. `api_key = "..."`, `"clientSecret": "..."` or `TOKEN=...`
+	assignment = regexp.MustCompile(`(?i)([A-Za-z0-9_\-]*(?:key|secret|token|passw(?:or)?d|pwd|credential|auth)[A-Za-z0-9_\-]*["']?\s*[:=]\s*["']?)([A-Za-z0-9+/=_\-~]{16,})`)
+)
+
+// Report counts redacted secrets by kind
+type Report map[string]int
+
+// Total returns the number of redacted secrets
+func (r Report) Total() int {
+	total := 0
+	for _, count := range r {
+		total += count
+	}
+	return total
+}
+
+// Add merges another report into r
+func (r Report) Add(other Report) {
+	for kind, count := range other {
+		r[kind] += count
+	}
+}
+
+// String summarizes the report, e.g. "1 aws-access-key, 2 high-entropy"
+func (r Report) String() string {
+	kinds := make([]string, 0, len(r))
+	for kind := range r {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+
+	parts := make([]string, 0, len(kinds))
+	for _, kind := range kinds {
+		parts = append(parts, fmt.Sprintf("%d %s", r[kind], kind))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Redactor replaces secrets in text with placeholders such as [REDACTED:jwt]
+type Redactor struct {
+	rules            []rule
+	entropyThreshold float64
+}
+
+// New creates a redactor with the built-in rules plus the given regular
+// expressions. A threshold of 0 uses DefaultEntropyThreshold.
+func New(patterns []string, entropyThreshold float64) (*Redactor, error) {
+	r := &Redactor{
+		rules:            append([]rule(nil), builtinRules...),
+		entropyThreshold: entropyThreshold,
+	}
+	if r.entropyThreshold <= 0 {
+		r.entropyThreshold = DefaultEntropyThreshold
+	}
+
+	for _, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid redaction pattern %q: %w", pattern, err)
+		}
+		r.rules = append(r.rules, rule{kind: KindCustom, pattern: re})
+	}
+
+	return r, nil
+}
+
+// Redact replaces the secrets in plain text
+func (r *Redactor) Redact(text string) (string, Report) {
+	return r.redact(text, 0)
+}
+
+// RedactDiff replaces the secrets in a unified diff. The +/-/space marker of
+// each line is kept, and so is the line count, so hunks stay valid.
+func (r *Redactor) RedactDiff(text string) (string, Report) {
+	return r.redact(text, 1)
+}
+
+// redact works line by line, keeping the first prefix bytes of each line
+func (r *Redactor) redact(text string, prefix int) (string, Report) {
+	report := make(Report)
+	lines := strings.Split(text, "\n")
+	inKey := false
+
+	for i, line := range lines {
+		keep := ""
+		if len(line) >= prefix {
+			keep, line = line[:prefix], line[prefix:]
+		}
+
+		// Private key blocks span lines: everything from BEGIN to END goes
+		if inKey {
+			if loc := privateKeyEnd.FindStringIndex(line); loc != nil {
+				inKey = false
+				lines[i] = keep + line[loc[1]:]
+			} else {
+				lines[i] = keep
+			}
+			continue
+		}
+		if loc := privateKeyBegin.FindStringIndex(line); loc != nil {
+			report[KindPrivateKey]++
+			rest := line[loc[1]:]
+			if end := privateKeyEnd.FindStringIndex(rest); end != nil {
+				rest = rest[end[1]:]
+			} else {
+				inKey = true
+				rest = ""
+			}
+			lines[i] = keep + line[:loc[0]] + placeholder(KindPrivateKey) + r.redactLine(rest, report)
+			continue
+		}
+
+		lines[i] = keep + r.redactLine(line, report)
+	}
+
+	return strings.Join(lines, "\n"), report
+}
+
+// redactLine applies the single-line rules
+func (r *Redactor) redactLine(line string, report Report) string {
+	for _, rule := range r.rules {
+		line = rule.pattern.ReplaceAllStringFunc(line, func(string) string {
+			report[rule.kind]++
+			return placeholder(rule.kind)
+		})
+	}
+
+	return assignment.ReplaceAllStringFunc(line, func(match string) string {
+		parts := assignment.FindStringSubmatch(match)
+		if !r.isSecret(parts[2]) {
+			return match
+		}
+		report[KindHighEntropy]++
+		return parts[1] + placeholder(KindHighEntropy)
+	})
+}
+
+// isSecret reports whether an assigned value looks random rather than like an
+// identifier or word: it mixes letters and digits and has high entropy
+func (r *Redactor) isSecret(value string) bool {
+	if !strings.ContainsAny(value, "0123456789") ||
+		!strings.ContainsAny(strings.ToLower(value), "abcdefghijklmnopqrstuvwxyz") {
+		return false
+	}
+	return entropy(value) >= r.entropyThreshold
+}
+
+// entropy returns the Shannon entropy of s in bits per character
+func entropy(s string) float64 {
+	counts := make(map[rune]int)
+	for _, c := range s {
+		counts[c]++
+	}
+
+	n := float64(len(s))
+	bits := 0.0
+	for _, count := range counts {
+		p := float64(count) / n
+		bits -= p * math.Log2(p)
+	}
+	return bits
+}
+
+// placeholder returns the text a secret of the given kind is replaced with
+func placeholder(kind string) string {
+	return "[REDACTED:" + kind + "]"
+}
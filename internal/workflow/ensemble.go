@@ -0,0 +1,314 @@
+package workflow
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/1broseidon/promptext-notes/internal/ai"
+	"github.com/1broseidon/promptext-notes/internal/config"
+	"github.com/1broseidon/promptext-notes/internal/grounding"
+	"github.com/1broseidon/promptext-notes/internal/prompt"
+)
+
+// ensembleJudgeMaxTokens caps the judge's answer, a JSON object with a number
+const ensembleJudgeMaxTokens = 1000
+
+// candidateResult is the outcome of one ensemble candidate
+type candidateResult struct {
+	config.CandidateConfig
+	stage     string // Cost tracker stage of the candidate, e.g. "discovery[2]"
+	content   string
+	err       error
+	latency   time.Duration
+	agreement float64 // Share of its items the other candidates also have
+}
+
+// useEnsemble reports whether ai.ensemble replaces the single discovery call
+func useEnsemble(cfg *config.Config) bool {
+	return cfg != nil && cfg.AI.Ensemble.Mode != "" && cfg.AI.Ensemble.Mode != "off"
+}
+
+// generateEnsemble sends the discovery prompt to every ensemble candidate
+// concurrently, reports their latency, tokens and cost, and returns the
+// candidate the judge picks (judge mode) or the candidate the others agree
+// with most plus the items most candidates have (merge mode)
+func generateEnsemble(ctx context.Context, tracker *ai.CostTracker, stage config.StageConfig, inputs *promptInputs, cfg *config.Config, verbose bool) (string, error) {
+	candidates := cfg.EnsembleCandidates()
+	if verbose {
+		fmt.Fprintf(os.Stderr, "\n🎭 Sending the discovery prompt to %d ensemble candidates...\n", len(candidates))
+	}
+
+	results := make([]candidateResult, len(candidates))
+	var wg sync.WaitGroup
+	for i, candidate := range candidates {
+		results[i] = candidateResult{CandidateConfig: candidate, stage: fmt.Sprintf("%s[%d]", stage.Name, i+1)}
+		wg.Add(1)
+		go func(result *candidateResult) {
+			defer wg.Done()
+			start := time.Now()
+			result.content, result.err = runCandidate(ctx, tracker, stage, result, inputs, cfg)
+			result.latency = time.Since(start)
+		}(&results[i])
+	}
+	wg.Wait()
+
+	var succeeded []*candidateResult
+	var failures []error
+	for i := range results {
+		if results[i].err != nil {
+			failures = append(failures, fmt.Errorf("%s/%s: %w", results[i].Provider, results[i].Model, results[i].err))
+			continue
+		}
+		succeeded = append(succeeded, &results[i])
+	}
+	if len(succeeded) == 0 {
+		return "", fmt.Errorf("all ensemble candidates failed: %w", errors.Join(failures...))
+	}
+
+	minScore := cfg.GetGroundingMinScore()
+	scoreAgreement(succeeded, minScore)
+
+	selected, how := -1, "merge"
+	if cfg.AI.Ensemble.Mode == "judge" && len(succeeded) > 1 {
+		var err error
+		if selected, err = judgeEnsemble(ctx, tracker, succeeded, inputs, cfg, verbose); err != nil {
+			return "", err
+		}
+		how = "judge"
+	}
+
+	var content string
+	added := 0
+	if selected >= 0 {
+		content = succeeded[selected].content
+	} else {
+		selected, content, added = mergeCandidates(succeeded, minScore)
+		how = "merge"
+	}
+
+	if verbose {
+		printEnsembleReport(results, tracker, succeeded[selected], how, added)
+	}
+	return content, nil
+}
+
+// runCandidate generates the discovery draft with one candidate's provider
+// and model; usage is recorded under the candidate's stage
+func runCandidate(ctx context.Context, tracker *ai.CostTracker, stage config.StageConfig, result *candidateResult, inputs *promptInputs, cfg *config.Config) (string, error) {
+	candidateCfg := *cfg
+	candidateCfg.AI.Provider = result.Provider
+	candidateCfg.AI.Model = result.Model
+	candidateCfg.AI.Custom = cfg.CustomFor(result.Model)
+	candidateCfg.AI.APIKeyEnv = result.APIKeyEnv
+	candidateCfg.AI.MaxTokens = stage.MaxTokens
+	candidateCfg.AI.Temperature = *stage.Temperature
+
+	provider, err := ai.NewProvider(&candidateCfg)
+	if err != nil {
+		return "", fmt.Errorf("failed to create provider: %w", err)
+	}
+
+	// Each candidate fits the prompt to its own context window
+	in := *inputs
+	return generateDraft(ctx, provider, tracker, result.stage, &in, &candidateCfg, false)
+}
+
+// scoreAgreement sets each candidate's agreement: the share of its items
+// found in the other candidates, averaged over them
+func scoreAgreement(results []*candidateResult, minScore float64) {
+	if len(results) < 2 {
+		return
+	}
+	for i, result := range results {
+		items := len(grounding.ParseItems(result.content))
+		if items == 0 {
+			continue
+		}
+
+		found := 0
+		for j, other := range results {
+			if i == j {
+				continue
+			}
+			for _, check := range grounding.Check(result.content, candidateSources(other.content), minScore) {
+				if check.Grounded {
+					found++
+				}
+			}
+		}
+		result.agreement = float64(found) / float64(items*(len(results)-1))
+	}
+}
+
+// mergeCandidates starts from the candidate the others agree with most
+// (the first on ties) and adds the items a majority of candidates have that
+// it lacks. It returns the index of that candidate, the merged release notes
+// and the number of items added.
+func mergeCandidates(results []*candidateResult, minScore float64) (int, string, int) {
+	best := 0
+	for i := range results {
+		if results[i].agreement > results[best].agreement {
+			best = i
+		}
+	}
+
+	merged := results[best].content
+	added := 0
+	for i, result := range results {
+		if i == best {
+			continue
+		}
+		for _, item := range grounding.ParseItems(result.content) {
+			votes := 0
+			for _, other := range results {
+				if containsItem(other.content, item.Text, minScore) {
+					votes++
+				}
+			}
+			if votes*2 <= len(results) || containsItem(merged, item.Text, minScore) {
+				continue
+			}
+			merged = insertItem(merged, item.Section, item.Text)
+			added++
+		}
+	}
+	return best, merged, added
+}
+
+// candidateSources turns the items of a candidate into grounding sources
+func candidateSources(content string) []grounding.Source {
+	var sources []grounding.Source
+	for _, item := range grounding.ParseItems(content) {
+		sources = append(sources, grounding.Source{Kind: grounding.SourceCandidate, Name: item.Text})
+	}
+	return sources
+}
+
+// containsItem reports whether the release notes have an item matching text
+func containsItem(content, text string, minScore float64) bool {
+	results := grounding.Check("- "+text, candidateSources(content), minScore)
+	return len(results) == 1 && results[0].Grounded
+}
+
+// insertItem adds an item at the end of the section, appending the section
+// when the release notes don't have it
+func insertItem(content, section, text string) string {
+	lines := strings.Split(strings.TrimRight(content, "\n"), "\n")
+	key := sectionKey(section)
+
+	insertAt := -1
+	inSection := false
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "#") {
+			if inSection {
+				break
+			}
+			inSection = key != "" && sectionKey(strings.TrimLeft(trimmed, "#")) == key
+			if inSection {
+				insertAt = i + 1
+			}
+			continue
+		}
+		if inSection && trimmed != "" {
+			insertAt = i + 1
+		}
+	}
+
+	item := "- " + text
+	if insertAt < 0 {
+		if section != "" {
+			lines = append(lines, "", "### "+section)
+		}
+		lines = append(lines, item)
+	} else {
+		lines = append(lines[:insertAt], append([]string{item}, lines[insertAt:]...)...)
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// judgeEnsemble asks the ensemble judge which candidate is best and returns
+// its index, or -1 when the judge's answer can't be used
+func judgeEnsemble(ctx context.Context, tracker *ai.CostTracker, results []*candidateResult, in *promptInputs, cfg *config.Config, verbose bool) (int, error) {
+	judgeCfg := cfg.EnsembleJudge()
+	temperature := 0.0
+	judge, err := newStageProvider(config.StageConfig{
+		Name:        "ensemble judge",
+		Provider:    judgeCfg.Provider,
+		Model:       judgeCfg.Model,
+		APIKeyEnv:   judgeCfg.APIKeyEnv,
+		MaxTokens:   ensembleJudgeMaxTokens,
+		Temperature: &temperature,
+	}, cfg)
+	if err != nil {
+		return -1, err
+	}
+	if verbose {
+		fmt.Fprintf(os.Stderr, "\n⚖️  Asking the ensemble judge %s (%s)...\n", judgeCfg.Provider, judgeCfg.Model)
+	}
+	judge = ai.WithCostTracking(judge, tracker, "ensemble", cfg)
+
+	candidates := make([]string, len(results))
+	for i, result := range results {
+		candidates[i] = result.content
+	}
+
+	req := judge.NewRequest(prompt.EnsembleJudgePrompt(candidates, in.commits, in.fence))
+	req.SystemPrompt = prompt.EnsembleJudgeSystemPrompt(in.fence)
+	req.Temperature = 0
+	response, err := judge.Generate(ctx, req)
+	if err != nil {
+		return -1, fmt.Errorf("failed to run the ensemble judge: %w", err)
+	}
+
+	var answer struct {
+		Best int `json:"best"`
+	}
+	if err := decodeJSONObject(response.Content, &answer); err != nil || answer.Best < 1 || answer.Best > len(results) {
+		// Fall back to the deterministic merge
+		if verbose {
+			fmt.Fprintf(os.Stderr, "   Warning: could not read the judge's answer, merging instead: %q\n", response.Content)
+		}
+		return -1, nil
+	}
+	return answer.Best - 1, nil
+}
+
+// printEnsembleReport writes each candidate's latency, tokens, cost and
+// agreement with the others to stderr
+func printEnsembleReport(results []candidateResult, tracker *ai.CostTracker, selected *candidateResult, how string, added int) {
+	usage := make(map[string]ai.StageUsage)
+	if tracker != nil {
+		for _, stage := range tracker.Stages() {
+			usage[stage.Stage] = stage
+		}
+	}
+
+	fmt.Fprintf(os.Stderr, "\n🎭 Ensemble candidates (selected by %s):\n", how)
+	for i := range results {
+		result := &results[i]
+		latency := result.latency.Round(time.Millisecond)
+		if result.err != nil {
+			fmt.Fprintf(os.Stderr, "   %-14s %s/%s: failed after %s: %v\n", result.stage, result.Provider, result.Model, latency, result.err)
+			continue
+		}
+
+		stage := usage[result.stage]
+		mark := ""
+		if result == selected {
+			mark = " ← selected"
+		}
+		fmt.Fprintf(os.Stderr, "   %-14s %s/%s: %s, %d tokens (%d in / %d out), $%.4f, %d item(s), %.0f%% agreement%s\n",
+			result.stage, result.Provider, result.Model, latency,
+			stage.TokensUsed, stage.InputTokens, stage.OutputTokens, stage.Cost,
+			len(grounding.ParseItems(result.content)), result.agreement*100, mark)
+	}
+	if added > 0 {
+		fmt.Fprintf(os.Stderr, "   Added %d item(s) most candidates agree on to %s\n", added, selected.stage)
+	}
+}
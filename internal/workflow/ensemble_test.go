@@ -0,0 +1,149 @@
+package workflow
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/1broseidon/promptext-notes/internal/ai"
+	"github.com/1broseidon/promptext-notes/internal/config"
+)
+
+// newEnsembleServer serves an Ollama chat response per model; models without
+// a response fail
+func newEnsembleServer(t *testing.T, responses map[string]string) *config.Config {
+	t.Helper()
+	var mu sync.Mutex
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Model string `json:"model"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+
+		mu.Lock()
+		content, ok := responses[req.Model]
+		mu.Unlock()
+		if !ok {
+			http.Error(w, `{"error":"model not found"}`, http.StatusNotFound)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"model":             req.Model,
+			"message":           map[string]string{"role": "assistant", "content": content},
+			"done":              true,
+			"done_reason":       "stop",
+			"prompt_eval_count": 100,
+			"eval_count":        20,
+		})
+	}))
+	t.Cleanup(server.Close)
+
+	cfg := config.Default()
+	cfg.AI.Provider = "ollama"
+	cfg.AI.Model = "llama3.2"
+	cfg.AI.APIKeyEnv = ""
+	cfg.AI.Custom = map[string]string{"ollama_url": server.URL}
+	cfg.AI.Retry.Attempts = 1
+	cfg.AI.Cache.Mode = "off"
+	return cfg
+}
+
+func TestGenerateEnsembleJudge(t *testing.T) {
+	cfg := newEnsembleServer(t, map[string]string{
+		"model-a": "### Added\n- **Export** - add export command\n",
+		"model-b": "### Added\n- **Export** - add export command\n\n### Fixed\n- **Crash** - fix crash on start\n",
+		"judge":   "```json\n{\"best\": 2}\n```",
+	})
+	cfg.AI.Ensemble.Mode = "judge"
+	cfg.AI.Ensemble.Candidates = []config.CandidateConfig{
+		{Provider: "ollama", Model: "model-a"},
+		{Provider: "ollama", Model: "model-b"},
+		{Provider: "ollama", Model: "missing"},
+	}
+	cfg.AI.Ensemble.Judge = config.CandidateConfig{Provider: "ollama", Model: "judge"}
+
+	tracker := ai.NewCostTracker(0)
+	content, err := generateEnsemble(context.Background(), tracker, cfg.Stages()[0], newOversizedInputs(), cfg, false)
+	if err != nil {
+		t.Fatalf("generateEnsemble() error = %v", err)
+	}
+	if !strings.Contains(content, "### Fixed") {
+		t.Errorf("Expected the judge's pick, got:\n%s", content)
+	}
+
+	stages := make(map[string]ai.StageUsage)
+	for _, stage := range tracker.Stages() {
+		stages[stage.Stage] = stage
+	}
+	for _, name := range []string{"discovery[1]", "discovery[2]", "ensemble"} {
+		if stages[name].Requests != 1 || stages[name].TokensUsed != 120 {
+			t.Errorf("Expected 1 request of 120 tokens for %s, got %+v", name, stages[name])
+		}
+	}
+}
+
+func TestGenerateEnsembleAllFail(t *testing.T) {
+	cfg := newEnsembleServer(t, nil)
+	cfg.AI.Ensemble.Mode = "merge"
+	cfg.AI.Ensemble.Candidates = []config.CandidateConfig{
+		{Provider: "ollama", Model: "model-a"},
+		{Provider: "ollama", Model: "model-b"},
+	}
+
+	_, err := generateEnsemble(context.Background(), nil, cfg.Stages()[0], newOversizedInputs(), cfg, false)
+	if err == nil || !strings.Contains(err.Error(), "all ensemble candidates failed") {
+		t.Errorf("Expected all candidates to fail, got %v", err)
+	}
+}
+
+func TestMergeCandidates(t *testing.T) {
+	results := []*candidateResult{
+		{content: "### Added\n- **Export** - add export command\n- **Themes** - dark theme\n"},
+		{content: "### Added\n- **Export** - new export command\n\n### Fixed\n- **Crash** - fix crash on start\n"},
+		{content: "### Added\n- **Export** - export command added\n\n### Fixed\n- **Crash** - crash on start fixed\n"},
+	}
+	scoreAgreement(results, 0.5)
+
+	best, merged, added := mergeCandidates(results, 0.5)
+	if best != 1 {
+		t.Errorf("Expected the second candidate to agree most, got %d (agreement %.2f, %.2f, %.2f)",
+			best, results[0].agreement, results[1].agreement, results[2].agreement)
+	}
+	if added != 0 || merged != results[1].content {
+		t.Errorf("Expected nothing to add to the best candidate, added %d:\n%s", added, merged)
+	}
+
+	// A majority item the best candidate lacks is added to its section
+	results = []*candidateResult{
+		{content: "### Added\n- **Export** - add export command\n\n### Fixed\n- **Crash** - fix crash on start\n"},
+		{content: "### Added\n- **Export** - new export command\n"},
+		{content: "### Added\n- **Export** - export command added\n- **Themes** - dark theme\n\n### Fixed\n- **Crash** - crash on start fixed\n"},
+	}
+	scoreAgreement(results, 0.5)
+	best, merged, added = mergeCandidates(results, 0.5)
+	if best != 1 || added != 1 || !strings.Contains(merged, "### Fixed\n- **Crash**") {
+		t.Errorf("Expected the crash fix merged into candidate 2, got best %d, added %d:\n%s", best, added, merged)
+	}
+	if strings.Contains(merged, "Themes") {
+		t.Errorf("Expected items of a single candidate not to be merged:\n%s", merged)
+	}
+}
+
+func TestInsertItem(t *testing.T) {
+	notes := "## [v1.0.0]\n\n### Added\n- **Export** - add export\n\n### Fixed\n- **Crash** - fix crash\n"
+
+	got := insertItem(notes, "Added", "**Import** - add import")
+	want := "## [v1.0.0]\n\n### Added\n- **Export** - add export\n- **Import** - add import\n\n### Fixed\n- **Crash** - fix crash\n"
+	if got != want {
+		t.Errorf("insertItem() into an existing section =\n%s\nwant:\n%s", got, want)
+	}
+
+	got = insertItem(notes, "Security", "**Tokens** - redact tokens")
+	if !strings.HasSuffix(got, "- **Crash** - fix crash\n\n### Security\n- **Tokens** - redact tokens\n") {
+		t.Errorf("Expected a missing section to be appended, got:\n%s", got)
+	}
+}
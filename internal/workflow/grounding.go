@@ -0,0 +1,167 @@
+package workflow
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/1broseidon/promptext-notes/internal/ai"
+	"github.com/1broseidon/promptext-notes/internal/config"
+	"github.com/1broseidon/promptext-notes/internal/grounding"
+	"github.com/1broseidon/promptext-notes/internal/prompt"
+)
+
+// verifyGrounding matches every item of the release notes to the commits,
+// changed files and diff hunks it came from, asks the optional judge model
+// about items without lexical support, and reports, marks or removes the
+// remaining ones per ai.grounding.mode
+func verifyGrounding(ctx context.Context, content string, in *promptInputs, cfg *config.Config, tracker *ai.CostTracker, verbose bool) (string, error) {
+	if cfg == nil || cfg.AI.Grounding.Mode == "off" {
+		return content, nil
+	}
+
+	results := grounding.Check(content, groundingSources(in), cfg.GetGroundingMinScore())
+
+	if cfg.AI.Grounding.Judge.Enabled && len(grounding.Ungrounded(results)) > 0 {
+		judge, err := newJudgeProvider(cfg)
+		if err != nil {
+			return "", err
+		}
+		if verbose {
+			fmt.Fprintf(os.Stderr, "\n⚖️  Asking the grounding judge %s (%s)...\n", cfg.GetJudgeProvider(), cfg.GetJudgeModel())
+		}
+		judge = ai.WithCostTracking(judge, tracker, "grounding", cfg)
+		if err := judgeGrounding(ctx, judge, results, in, verbose); err != nil {
+			return "", err
+		}
+	}
+
+	ungrounded := grounding.Ungrounded(results)
+	if verbose {
+		fmt.Fprintf(os.Stderr, "\n🔎 Grounding: %d of %d item(s) supported by the commits and diff\n",
+			len(results)-len(ungrounded), len(results))
+		if len(ungrounded) > 0 {
+			fmt.Fprintf(os.Stderr, "   ⚠️  %d item(s) not found in the inputs (grounding.mode: %s):\n", len(ungrounded), cfg.AI.Grounding.Mode)
+			fmt.Fprint(os.Stderr, grounding.Report(results))
+		}
+	}
+
+	return grounding.Apply(content, results, cfg.AI.Grounding.Mode), nil
+}
+
+// groundingSources collects the evidence items can be matched to: commits,
+// changed files, their hunks and the changes detected in the code
+func groundingSources(in *promptInputs) []grounding.Source {
+	var sources []grounding.Source
+	for _, commit := range in.commits {
+		sources = append(sources, grounding.Source{Kind: grounding.SourceCommit, Name: commit})
+	}
+	for i := range in.diffFiles {
+		file := &in.diffFiles[i]
+		sources = append(sources, grounding.Source{Kind: grounding.SourceFile, Name: file.Path})
+		if file.Dropped() {
+			continue
+		}
+		for _, hunk := range file.Hunks {
+			sources = append(sources, grounding.Source{
+				Kind: grounding.SourceHunk,
+				Name: file.Path + " " + hunk.Header,
+				Text: hunk.Text,
+			})
+		}
+	}
+	for _, change := range in.categories.APIChanges {
+		sources = append(sources, grounding.Source{Kind: grounding.SourceDetected, Name: change})
+	}
+	for _, change := range in.categories.Dependencies {
+		sources = append(sources, grounding.Source{Kind: grounding.SourceDetected, Name: change})
+	}
+	return sources
+}
+
+// judgeGrounding asks the judge model which of the ungrounded items the
+// commits and diff support, and marks those grounded
+func judgeGrounding(ctx context.Context, judge ai.Provider, results []grounding.Result, in *promptInputs, verbose bool) error {
+	var pending []*grounding.Result
+	var items []string
+	for i := range results {
+		if !results[i].Grounded {
+			pending = append(pending, &results[i])
+			items = append(items, results[i].Item.Text)
+		}
+	}
+
+	req := judge.NewRequest(prompt.JudgePrompt(items, in.commits, in.diff, in.fence))
+	req.SystemPrompt = prompt.JudgeSystemPrompt(in.fence)
+	req.Temperature = 0
+	response, err := judge.Generate(ctx, req)
+	if err != nil {
+		return fmt.Errorf("failed to run the grounding judge: %w", err)
+	}
+
+	supported, err := parseJudgeResponse(response.Content)
+	if err != nil {
+		// The lexical verdicts stand
+		if verbose {
+			fmt.Fprintf(os.Stderr, "   Warning: could not read the judge's answer: %v\n", err)
+		}
+		return nil
+	}
+	for _, number := range supported {
+		if number >= 1 && number <= len(pending) {
+			pending[number-1].Grounded = true
+			pending[number-1].Judged = true
+		}
+	}
+	return nil
+}
+
+// parseJudgeResponse reads the numbers of the supported items
+func parseJudgeResponse(content string) ([]int, error) {
+	var answer struct {
+		Supported []int `json:"supported"`
+	}
+	if err := decodeJSONObject(content, &answer); err != nil {
+		return nil, err
+	}
+	return answer.Supported, nil
+}
+
+// decodeJSONObject decodes the JSON object in a judge's response, ignoring
+// any text or code fence around it
+func decodeJSONObject(content string, v interface{}) error {
+	start := strings.Index(content, "{")
+	end := strings.LastIndex(content, "}")
+	if start < 0 || end < start {
+		return fmt.Errorf("no JSON object in the response")
+	}
+	return json.Unmarshal([]byte(content[start:end+1]), v)
+}
+
+// newJudgeProvider creates the provider for the grounding judge
+func newJudgeProvider(cfg *config.Config) (ai.Provider, error) {
+	judgeCfg := &config.Config{
+		AI: config.AIConfig{
+			Provider:    cfg.GetJudgeProvider(),
+			Model:       cfg.GetJudgeModel(),
+			APIKeyEnv:   cfg.GetJudgeAPIKeyEnv(),
+			MaxTokens:   cfg.AI.Grounding.Judge.MaxTokens,
+			Temperature: 0,
+			Timeout:     cfg.AI.Timeout,
+			Retry:       cfg.AI.Retry,
+			Custom:      cfg.CustomFor(cfg.GetJudgeModel()),
+			Pricing:     cfg.AI.Pricing,
+			Cache:       cfg.AI.Cache,
+			Replay:      cfg.AI.Replay,
+			Exec:        cfg.AI.Exec,
+		},
+	}
+
+	judge, err := ai.NewProvider(judgeCfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create grounding judge provider: %w", err)
+	}
+	return judge, nil
+}
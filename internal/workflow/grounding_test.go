@@ -0,0 +1,65 @@
+package workflow
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/1broseidon/promptext-notes/internal/ai"
+	"github.com/1broseidon/promptext-notes/internal/config"
+	"github.com/1broseidon/promptext-notes/internal/grounding"
+	"github.com/1broseidon/promptext-notes/internal/prompt"
+)
+
+const groundingDraft = "### Added\n- **Export** - Add export command\n- **Dark mode** - New dark theme for the UI\n"
+
+func TestGenerateWithAIGroundingRemove(t *testing.T) {
+	cfg := config.Default()
+	cfg.AI.ContextWindow = 1_000_000
+	cfg.AI.Grounding.Mode = "remove"
+
+	provider := &scriptedProvider{responses: []*ai.Response{
+		{Content: groundingDraft, StopReason: ai.StopReasonComplete},
+	}}
+
+	content, err := generateWithAI(context.Background(), provider, newOversizedInputs(), cfg, false)
+	if err != nil {
+		t.Fatalf("generateWithAI() error = %v", err)
+	}
+	if strings.Contains(content, "Dark mode") || !strings.Contains(content, "**Export**") {
+		t.Errorf("Expected only the ungrounded item removed, got:\n%s", content)
+	}
+}
+
+func TestJudgeGrounding(t *testing.T) {
+	in := newOversizedInputs()
+	in.fence = prompt.NewFence()
+	results := grounding.Check(groundingDraft, groundingSources(in), 0.5)
+
+	judge := &scriptedProvider{responses: []*ai.Response{
+		{Content: `{"supported": [1]}`, StopReason: ai.StopReasonComplete},
+	}}
+	if err := judgeGrounding(context.Background(), judge, results, in, false); err != nil {
+		t.Fatalf("judgeGrounding() error = %v", err)
+	}
+
+	if !strings.Contains(judge.requests[0].Prompt, "1. **Dark mode**") {
+		t.Errorf("Expected the ungrounded item numbered in the judge prompt:\n%s", judge.requests[0].Prompt)
+	}
+	if !results[1].Grounded || !results[1].Judged {
+		t.Error("Expected the item the judge supports to be grounded")
+	}
+	if len(grounding.Ungrounded(results)) != 0 {
+		t.Error("Expected no ungrounded items left")
+	}
+}
+
+func TestParseJudgeResponse(t *testing.T) {
+	supported, err := parseJudgeResponse("```json\n{\"supported\": [2, 3]}\n```")
+	if err != nil || len(supported) != 2 || supported[0] != 2 {
+		t.Errorf("parseJudgeResponse() = %v, %v", supported, err)
+	}
+	if _, err := parseJudgeResponse("all of them"); err == nil {
+		t.Error("Expected an error without a JSON object")
+	}
+}
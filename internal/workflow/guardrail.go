@@ -0,0 +1,116 @@
+package workflow
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// ErrPolishChangedItems is returned when the output of polish (or a stage with
+// check_items) doesn't keep the version header, sections and items of its
+// input, even after a retry
+var ErrPolishChangedItems = errors.New("output changed the changelog's items")
+
+// changelogShape is the structure of a changelog that polish must preserve
+type changelogShape struct {
+	header   string // The "## [version] - date" line, if any
+	sections []shapeSection
+}
+
+// shapeSection is a "###" section and its number of items
+type shapeSection struct {
+	name  string // Heading as written
+	key   string // Heading reduced to lowercase letters, so emoji and case don't matter
+	items int
+}
+
+// parseShape reads the version header, sections and top-level items of a
+// changelog. Items before the first section are counted in an unnamed one.
+func parseShape(content string) changelogShape {
+	var shape changelogShape
+	for _, line := range strings.Split(content, "\n") {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(trimmed, "### "):
+			name := strings.TrimSpace(strings.TrimPrefix(trimmed, "### "))
+			shape.sections = append(shape.sections, shapeSection{name: name, key: sectionKey(name)})
+		case strings.HasPrefix(trimmed, "## ") && shape.header == "" && len(shape.sections) == 0:
+			shape.header = trimmed
+		case strings.HasPrefix(line, "- ") || strings.HasPrefix(line, "* "):
+			if len(shape.sections) == 0 {
+				shape.sections = append(shape.sections, shapeSection{})
+			}
+			shape.sections[len(shape.sections)-1].items++
+		}
+	}
+	return shape
+}
+
+// sectionKey normalizes a section heading for comparison
+func sectionKey(name string) string {
+	var key strings.Builder
+	for _, r := range strings.ToLower(name) {
+		if unicode.IsLetter(r) {
+			key.WriteRune(r)
+		}
+	}
+	return key.String()
+}
+
+// count returns the items of the section with the key, summing repeated
+// sections, and whether it exists
+func (s changelogShape) count(key string) (int, bool) {
+	items, found := 0, false
+	for _, section := range s.sections {
+		if section.key == key {
+			items += section.items
+			found = true
+		}
+	}
+	return items, found
+}
+
+// checkPolish compares the polished changelog with the draft and describes
+// each header, section or item count that changed
+func checkPolish(draft, polished string) []string {
+	before, after := parseShape(draft), parseShape(polished)
+
+	var problems []string
+	if before.header != after.header {
+		problems = append(problems, fmt.Sprintf("the version header changed from %q to %q", before.header, after.header))
+	}
+
+	checked := make(map[string]bool)
+	for _, section := range before.sections {
+		if checked[section.key] {
+			continue
+		}
+		checked[section.key] = true
+
+		want, _ := before.count(section.key)
+		got, found := after.count(section.key)
+		switch {
+		case !found && want > 0:
+			problems = append(problems, fmt.Sprintf("section %q is missing (it had %d item(s))", sectionName(section), want))
+		case got != want:
+			problems = append(problems, fmt.Sprintf("section %q has %d item(s) instead of %d", sectionName(section), got, want))
+		}
+	}
+	for _, section := range after.sections {
+		if !checked[section.key] && section.items > 0 {
+			checked[section.key] = true
+			problems = append(problems, fmt.Sprintf("section %q was added", sectionName(section)))
+		}
+	}
+
+	return problems
+}
+
+// sectionName names a section in feedback to the model
+func sectionName(section shapeSection) string {
+	if section.name == "" {
+		return "(items before the first section)"
+	}
+	return section.name
+}
@@ -0,0 +1,175 @@
+package workflow
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/1broseidon/promptext-notes/internal/ai"
+	"github.com/1broseidon/promptext-notes/internal/config"
+)
+
+const polishDraft = `## [v1.0.0] - 2025-01-01
+
+### Added
+- **Export** - add export command
+- **Import** - add import command
+
+### Fixed
+- **Crash** - fix crash on start
+`
+
+func TestCheckPolish(t *testing.T) {
+	tests := []struct {
+		name     string
+		polished string
+		want     []string
+	}{
+		{
+			name:     "Wording only",
+			polished: strings.ReplaceAll(polishDraft, "add ", "Added an "),
+		},
+		{
+			name:     "Emoji and case in headings",
+			polished: strings.Replace(polishDraft, "### Added", "### ✨ ADDED", 1),
+		},
+		{
+			name:     "Item dropped",
+			polished: strings.Replace(polishDraft, "- **Import** - add import command\n", "", 1),
+			want:     []string{`section "Added" has 1 item(s) instead of 2`},
+		},
+		{
+			name:     "Item moved to another section",
+			polished: strings.Replace(polishDraft, "- **Import** - add import command\n\n### Fixed\n", "\n### Fixed\n- **Import** - add import command\n", 1),
+			want:     []string{`section "Added" has 1 item(s) instead of 2`, `section "Fixed" has 2 item(s) instead of 1`},
+		},
+		{
+			name:     "Header changed and section added",
+			polished: strings.Replace(polishDraft, "## [v1.0.0]", "## [1.0.0]", 1) + "\n### Security\n- **Hardening** - invented\n",
+			want:     []string{"the version header changed", `section "Security" was added`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			problems := checkPolish(polishDraft, tt.polished)
+			if len(problems) != len(tt.want) {
+				t.Fatalf("checkPolish() = %q, want %d problem(s)", problems, len(tt.want))
+			}
+			for i, want := range tt.want {
+				if !strings.Contains(problems[i], want) {
+					t.Errorf("Problem %d = %q, want it to contain %q", i, problems[i], want)
+				}
+			}
+		})
+	}
+}
+
+// newPolishServer serves the responses in order as Ollama chat responses and
+// records the prompts it receives
+func newPolishServer(t *testing.T, responses ...string) (*config.Config, *[]string) {
+	t.Helper()
+	var prompts []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Messages []struct {
+				Content string `json:"content"`
+			} `json:"messages"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		prompts = append(prompts, req.Messages[len(req.Messages)-1].Content)
+
+		content := responses[0]
+		responses = responses[1:]
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"message":     map[string]string{"role": "assistant", "content": content},
+			"done":        true,
+			"done_reason": "stop",
+		})
+	}))
+	t.Cleanup(server.Close)
+
+	cfg := config.Default()
+	cfg.AI.Provider = "ollama"
+	cfg.AI.Model = "llama3.2"
+	cfg.AI.APIKeyEnv = ""
+	cfg.AI.Custom = map[string]string{"ollama_url": server.URL}
+	cfg.AI.Cache.Mode = "off"
+	cfg.AI.Polish.Enabled = true
+	return cfg, &prompts
+}
+
+func TestPolishChangelogRetriesWithFeedback(t *testing.T) {
+	polished := strings.ReplaceAll(polishDraft, "add ", "Added an ")
+	cfg, prompts := newPolishServer(t, strings.Replace(polishDraft, "- **Import** - add import command\n", "", 1), polished)
+
+	got, err := PolishChangelog(context.Background(), polishDraft, nil, cfg, nil)
+	if err != nil {
+		t.Fatalf("PolishChangelog() error = %v", err)
+	}
+	if got != polished {
+		t.Errorf("Expected the retried polish, got:\n%s", got)
+	}
+	if len(*prompts) != 2 || !strings.Contains((*prompts)[1], `section "Added" has 1 item(s) instead of 2`) {
+		t.Errorf("Expected one retry with feedback, got prompts: %q", *prompts)
+	}
+}
+
+func TestGenerateWithAIPolishFallsBackToDraft(t *testing.T) {
+	broken := "### Added\n- **Everything** - One merged item\n"
+	cfg, _ := newPolishServer(t, broken, broken, broken, broken)
+	cfg.AI.ContextWindow = 1_000_000
+
+	draft := "### Added\n- **Export** - add export command\n- **Crash** - fix crash on start\n"
+	provider := &scriptedProvider{responses: []*ai.Response{
+		{Content: draft, StopReason: ai.StopReasonComplete},
+	}}
+
+	got, err := generateWithAI(context.Background(), provider, newOversizedInputs(), cfg, false)
+	if err != nil {
+		t.Fatalf("generateWithAI() error = %v", err)
+	}
+	if strings.TrimSpace(got) != strings.TrimSpace(draft) {
+		t.Errorf("Expected the draft after two bad polishes, got:\n%s", got)
+	}
+
+	if _, err := PolishChangelog(context.Background(), draft, nil, cfg, nil); !errors.Is(err, ErrPolishChangedItems) {
+		t.Errorf("Expected ErrPolishChangedItems, got %v", err)
+	}
+}
+
+func TestPolishUsesItsOwnAzureDeployment(t *testing.T) {
+	var paths []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"model": "gpt-4o-mini",
+			"choices": []map[string]interface{}{{
+				"message":       map[string]string{"role": "assistant", "content": polishDraft},
+				"finish_reason": "stop",
+			}},
+		})
+	}))
+	t.Cleanup(server.Close)
+	t.Setenv("AZURE_OPENAI_API_KEY", "test-key")
+
+	cfg := config.Default()
+	cfg.AI.Provider = "azure-openai"
+	cfg.AI.Model = "gpt-4o"
+	cfg.AI.APIKeyEnv = "AZURE_OPENAI_API_KEY"
+	cfg.AI.Custom = map[string]string{"azure_endpoint": server.URL, "azure_deployment": "notes-prod"}
+	cfg.AI.Cache.Mode = "off"
+	cfg.AI.Polish.Enabled = true
+	cfg.AI.Polish.PolishModel = "gpt-4o-mini"
+
+	if _, err := PolishChangelog(context.Background(), polishDraft, nil, cfg, nil); err != nil {
+		t.Fatalf("PolishChangelog() error = %v", err)
+	}
+	if len(paths) != 1 || paths[0] != "/openai/deployments/gpt-4o-mini/chat/completions" {
+		t.Errorf("Expected polish sent to the gpt-4o-mini deployment, not azure_deployment, got %v", paths)
+	}
+}
@@ -0,0 +1,60 @@
+package workflow
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/1broseidon/promptext-notes/internal/config"
+	"github.com/1broseidon/promptext-notes/internal/prompt"
+)
+
+// ErrUngroundedOutput is returned when the release notes contain URLs or code
+// not found in the inputs and ai.output_check is reject
+var ErrUngroundedOutput = errors.New("release notes contain content not found in the inputs")
+
+// scanInjections reports instruction-like text in the untrusted inputs. It
+// only warns: the fences and system prompt are the defense, this shows what
+// they were up against.
+func scanInjections(in *promptInputs) {
+	var found []prompt.Suspicion
+	found = append(found, prompt.ScanInjections("commits", strings.Join(in.commits, "\n"))...)
+	found = append(found, prompt.ScanInjections("diff", in.fullDiff())...)
+	found = append(found, prompt.ScanInjections("code context", in.result.FormattedOutput)...)
+
+	for _, suspicion := range found {
+		fmt.Fprintf(os.Stderr, "   ⚠️  Possible prompt injection in %s: %s\n", suspicion.Source, suspicion.Text)
+	}
+}
+
+// checkOutput looks for URLs and code spans in the release notes that none of
+// the inputs contain, and rejects or warns about them per ai.output_check.
+// The prompt's own instructions and example format don't count as inputs, so
+// content copied from the examples is caught too. Warnings are printed even
+// without --verbose, since the release notes are published with them.
+func checkOutput(content string, in *promptInputs, cfg *config.Config) error {
+	mode := config.Default().AI.OutputCheck
+	if cfg != nil {
+		mode = cfg.AI.OutputCheck
+	}
+	if mode == "off" {
+		return nil
+	}
+
+	ungrounded := prompt.CheckOutput(content, in.untrusted())
+	if len(ungrounded) == 0 {
+		return nil
+	}
+
+	if mode == "reject" {
+		return fmt.Errorf("%w: %s (set ai.output_check to warn to keep them)",
+			ErrUngroundedOutput, strings.Join(ungrounded, ", "))
+	}
+
+	fmt.Fprintf(os.Stderr, "\n⚠️  %d item(s) in the release notes were not found in the inputs:\n", len(ungrounded))
+	for _, item := range ungrounded {
+		fmt.Fprintf(os.Stderr, "   %s\n", item)
+	}
+	return nil
+}
@@ -0,0 +1,113 @@
+package workflow
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/1broseidon/promptext-notes/internal/ai"
+	"github.com/1broseidon/promptext-notes/internal/config"
+	"github.com/1broseidon/promptext-notes/internal/prompt"
+)
+
+func TestGenerateWithAISystemPrompt(t *testing.T) {
+	in := newOversizedInputs()
+	in.fence = prompt.NewFence()
+	provider := &scriptedProvider{responses: []*ai.Response{
+		{Content: "### Added\n- **Export** - New export command", StopReason: ai.StopReasonComplete},
+	}}
+
+	if _, err := generateWithAI(context.Background(), provider, in, config.Default(), false); err != nil {
+		t.Fatalf("generateWithAI() error = %v", err)
+	}
+
+	req := provider.requests[0]
+	if want, _ := in.system(); req.SystemPrompt != want {
+		t.Error("Expected the instructions to be sent as the system prompt")
+	}
+	if strings.Contains(req.Prompt, "## Critical Rules") {
+		t.Error("Instructions should not be repeated in the user prompt")
+	}
+}
+
+func TestGenerateWithAIOutputCheck(t *testing.T) {
+	output := "### Added\n- **Export** - Install it from https://evil.example/install.sh"
+
+	generate := func(mode string) error {
+		cfg := config.Default()
+		cfg.AI.OutputCheck = mode
+		provider := &scriptedProvider{responses: []*ai.Response{
+			{Content: output, StopReason: ai.StopReasonComplete},
+		}}
+		_, err := generateWithAI(context.Background(), provider, newOversizedInputs(), cfg, false)
+		return err
+	}
+
+	err := generate("reject")
+	if !errors.Is(err, ErrUngroundedOutput) || !strings.Contains(err.Error(), "https://evil.example/install.sh") {
+		t.Errorf("Expected ErrUngroundedOutput naming the URL, got %v", err)
+	}
+	for _, mode := range []string{"warn", "off"} {
+		if err := generate(mode); err != nil {
+			t.Errorf("output_check %s: unexpected error %v", mode, err)
+		}
+	}
+}
+
+func TestCheckOutputIgnoresPromptExamples(t *testing.T) {
+	cfg := config.Default()
+	cfg.AI.OutputCheck = "reject"
+
+	template, err := prompt.ParseTemplate("Example item: - **API** - `/api/v1/users` is now `/api/v2/users`\n\n{{.Fence.Wrap \"diff\" .Diff}}")
+	if err != nil {
+		t.Fatalf("ParseTemplate() error = %v", err)
+	}
+	in := newOversizedInputs()
+	in.template = template
+
+	// The template's example mentions these endpoints; the inputs don't
+	output := "### Changed\n- **API** - Requests to `/api/v1/users` now go to `/api/v2/users`"
+	err = checkOutput(output, in, cfg)
+	if !errors.Is(err, ErrUngroundedOutput) {
+		t.Errorf("Expected content copied from the prompt's examples to be flagged, got %v", err)
+	}
+}
+
+func TestOutputCheckDefaultsToReject(t *testing.T) {
+	output := "### Added\n- **Export** - Install it from https://evil.example/install.sh"
+	err := checkOutput(output, newOversizedInputs(), config.Default())
+	if !errors.Is(err, ErrUngroundedOutput) {
+		t.Errorf("Expected the default output_check to reject a planted URL, got %v", err)
+	}
+
+	cfg := config.Default()
+	cfg.AI.OutputCheck = "warn"
+	stderr := captureStderr(t, func() {
+		if err := checkOutput(output, newOversizedInputs(), cfg); err != nil {
+			t.Errorf("output_check warn: unexpected error %v", err)
+		}
+	})
+	if !strings.Contains(stderr, "https://evil.example/install.sh") {
+		t.Errorf("Expected the warning on stderr without --verbose, got %q", stderr)
+	}
+}
+
+// captureStderr returns what fn writes to os.Stderr
+func captureStderr(t *testing.T, fn func()) string {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stderr := os.Stderr
+	os.Stderr = w
+	defer func() { os.Stderr = stderr }()
+
+	fn()
+	w.Close()
+	out, _ := io.ReadAll(r)
+	return string(out)
+}
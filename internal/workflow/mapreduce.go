@@ -0,0 +1,342 @@
+package workflow
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/1broseidon/promptext-notes/internal/ai"
+	"github.com/1broseidon/promptext-notes/internal/config"
+	"github.com/1broseidon/promptext-notes/internal/git"
+	"github.com/1broseidon/promptext-notes/internal/prompt"
+)
+
+// rootComponent groups files at the repository root and commits without files
+const rootComponent = "(root)"
+
+// chunk is one part of a large release, summarized on its own in the map step
+type chunk struct {
+	components []string
+	commits    []string
+	diffs      []string
+	tokens     int
+}
+
+// label names the components a chunk covers
+func (c *chunk) label() string {
+	if len(c.components) <= 4 {
+		return strings.Join(c.components, ", ")
+	}
+	return fmt.Sprintf("%s and %d more", strings.Join(c.components[:4], ", "), len(c.components)-4)
+}
+
+// useMapReduce decides whether the release is summarized in chunks instead of
+// with a single prompt
+func useMapReduce(in *promptInputs, provider ai.Provider, cfg *config.Config) (bool, error) {
+	if cfg == nil {
+		return false, nil
+	}
+
+	mr := cfg.AI.MapReduce
+	switch mr.Mode {
+	case "always":
+		return true, nil
+	case "auto":
+		if mr.MinCommits > 0 && len(in.commits) >= mr.MinCommits {
+			return true, nil
+		}
+		budget := newPromptBudget(provider, cfg)
+		if !budget.known {
+			return false, nil
+		}
+		systemPrompt, err := in.system()
+		if err != nil {
+			return false, err
+		}
+		promptText, err := in.build()
+		if err != nil {
+			return false, err
+		}
+		return ai.CountTokens(systemPrompt)+ai.CountTokens(promptText) > budget.available, nil
+	default:
+		return false, nil
+	}
+}
+
+// generateMapReduce summarizes the release per component in parallel (map),
+// then merges the summaries into the changelog with a final prompt (reduce)
+func generateMapReduce(ctx context.Context, provider ai.Provider, tracker *ai.CostTracker, in *promptInputs, cfg *config.Config, verbose bool) (string, error) {
+	mr := cfg.AI.MapReduce
+
+	commitFiles, err := git.GetCommitFiles(in.sinceTag)
+	if err != nil && verbose {
+		fmt.Fprintf(os.Stderr, "   Warning: could not map commits to files: %v\n", err)
+	}
+
+	// Each chunk prompt must fit the context window with its instructions
+	chunkTokens := mr.ChunkTokens
+	budget := newPromptBudget(provider, cfg)
+	if budget.known {
+		overhead := ai.CountTokens(prompt.ChunkSystemPrompt(in.fence)) +
+			ai.CountTokens(prompt.GenerateChunkPrompt(in.version, "", 1, 1, nil, "", in.fence)) + 100
+		if budget.available-overhead < chunkTokens {
+			chunkTokens = budget.available - overhead
+		}
+		if chunkTokens < 1000 {
+			return "", fmt.Errorf("%w: no room for map-reduce chunks in %s (%d tokens available)",
+				ErrContextOverflow, budget.model, budget.available)
+		}
+	}
+
+	chunks := buildChunks(in, commitFiles, mr.GroupDepth, chunkTokens)
+	if verbose {
+		fmt.Fprintf(os.Stderr, "\n🧩 Summarizing %d parts of ~%d tokens with up to %d in parallel...\n",
+			len(chunks), chunkTokens, mr.Concurrency)
+	}
+
+	summaries, err := summarizeChunks(ctx, ai.WithCostTracking(provider, tracker, "map", cfg), chunks, in.version, in.fence, cfg, verbose)
+	if err != nil {
+		return "", err
+	}
+
+	if verbose {
+		fmt.Fprintf(os.Stderr, "   %d of %d parts have user-facing changes\n", len(summaries), len(chunks))
+	}
+
+	reducePrompt, err := in.reduce(summaries)
+	if err != nil {
+		return "", err
+	}
+	systemPrompt, err := in.system()
+	if err != nil {
+		return "", err
+	}
+	if budget.known {
+		if tokens := ai.CountTokens(systemPrompt) + ai.CountTokens(reducePrompt); tokens > budget.available {
+			return "", fmt.Errorf("%w: reduce prompt is ~%d tokens for %d available in %s (try a larger map_reduce.chunk_tokens)",
+				ErrContextOverflow, tokens, budget.available, budget.model)
+		}
+	}
+
+	return generateAIContent(ctx, ai.WithCostTracking(provider, tracker, "reduce", cfg), in, systemPrompt, reducePrompt, cfg, verbose)
+}
+
+// summarizeChunks runs the map step with at most map_reduce.concurrency
+// requests in flight. Summaries come back in chunk order; chunks without
+// user-facing changes are dropped. The first failure cancels the rest.
+func summarizeChunks(ctx context.Context, provider ai.Provider, chunks []*chunk, version string, fence prompt.Fence, cfg *config.Config, verbose bool) ([]prompt.ChunkSummary, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results := make([]*prompt.ChunkSummary, len(chunks))
+	errs := make([]error, len(chunks))
+	sem := make(chan struct{}, cfg.AI.MapReduce.Concurrency)
+	systemPrompt := prompt.ChunkSystemPrompt(fence)
+
+	var wg sync.WaitGroup
+	for i, c := range chunks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			if err := ctx.Err(); err != nil {
+				errs[i] = err
+				return
+			}
+
+			chunkPrompt := prompt.GenerateChunkPrompt(version, c.label(), i+1, len(chunks),
+				c.commits, strings.Join(c.diffs, ""), fence)
+			req := provider.NewRequest(chunkPrompt)
+			req.SystemPrompt = systemPrompt
+			resp, err := generateComplete(ctx, provider, req, cfg, false)
+			if err != nil {
+				errs[i] = fmt.Errorf("failed to summarize part %d (%s): %w", i+1, c.label(), err)
+				cancel()
+				return
+			}
+
+			if verbose {
+				fmt.Fprintf(os.Stderr, "   ✓ Part %d/%d (%s): %d tokens\n", i+1, len(chunks), c.label(), resp.TokensUsed)
+			}
+
+			summary := strings.TrimSpace(resp.Content)
+			if summary == "" || strings.Contains(summary, prompt.NoChangesMarker) {
+				return
+			}
+			results[i] = &prompt.ChunkSummary{Component: c.label(), Summary: summary}
+		}()
+	}
+	wg.Wait()
+
+	// Report the failure that caused the cancellation, not the parts it cancelled
+	var firstErr error
+	for _, err := range errs {
+		if err != nil && !errors.Is(err, context.Canceled) {
+			return nil, err
+		}
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	if firstErr != nil {
+		return nil, firstErr
+	}
+
+	var summaries []prompt.ChunkSummary
+	for _, result := range results {
+		if result != nil {
+			summaries = append(summaries, *result)
+		}
+	}
+	return summaries, nil
+}
+
+// buildChunks groups commits and per-file diffs by component (the first depth
+// directories of each path) and packs them, in component order, into chunks of
+// at most maxTokens. Small components share a chunk, large ones are split.
+func buildChunks(in *promptInputs, commitFiles []git.CommitFiles, depth, maxTokens int) []*chunk {
+	type group struct {
+		commits []string
+		diffs   []string
+	}
+	groups := make(map[string]*group)
+	groupFor := func(component string) *group {
+		if groups[component] == nil {
+			groups[component] = &group{}
+		}
+		return groups[component]
+	}
+
+	// Lockfiles, vendored and binary files are left out; diff stats still list them
+	for i := range in.diffFiles {
+		f := &in.diffFiles[i]
+		if f.Dropped() {
+			continue
+		}
+		g := groupFor(componentOf(f.Path, depth))
+		g.diffs = append(g.diffs, f.Text())
+	}
+
+	// Only commits that survived filtering are assigned, each to the
+	// component most of its files are in
+	remaining := make(map[string]int)
+	for _, commit := range in.commits {
+		remaining[commit]++
+	}
+	for _, commit := range commitFiles {
+		if remaining[commit.Subject] == 0 {
+			continue
+		}
+		remaining[commit.Subject]--
+
+		g := groupFor(primaryComponent(commit.Files, depth))
+		g.commits = append(g.commits, commit.Subject)
+	}
+	for _, commit := range in.commits {
+		if remaining[commit] > 0 {
+			remaining[commit]--
+			g := groupFor(rootComponent)
+			g.commits = append(g.commits, commit)
+		}
+	}
+
+	components := make([]string, 0, len(groups))
+	for component := range groups {
+		components = append(components, component)
+	}
+	sort.Strings(components)
+
+	var chunks []*chunk
+	current := &chunk{}
+	add := func(component, text string, isCommit bool) {
+		tokens := ai.CountTokens(text)
+		if tokens > maxTokens {
+			text = clipDiff(text, tokens, maxTokens)
+			tokens = ai.CountTokens(text)
+		}
+
+		if current.tokens > 0 && current.tokens+tokens > maxTokens {
+			chunks = append(chunks, current)
+			current = &chunk{}
+		}
+
+		if n := len(current.components); n == 0 || current.components[n-1] != component {
+			current.components = append(current.components, component)
+		}
+		if isCommit {
+			current.commits = append(current.commits, text)
+		} else {
+			current.diffs = append(current.diffs, text)
+		}
+		current.tokens += tokens
+	}
+
+	for _, component := range components {
+		g := groups[component]
+		for _, commit := range g.commits {
+			add(component, commit, true)
+		}
+		for _, fileDiff := range g.diffs {
+			add(component, fileDiff, false)
+		}
+	}
+	if current.tokens > 0 {
+		chunks = append(chunks, current)
+	}
+
+	return chunks
+}
+
+// componentOf returns the first depth directories of a file path
+func componentOf(file string, depth int) string {
+	dir := path.Dir(file)
+	if dir == "." || dir == "/" {
+		return rootComponent
+	}
+
+	parts := strings.Split(dir, "/")
+	if len(parts) > depth {
+		parts = parts[:depth]
+	}
+	return strings.Join(parts, "/")
+}
+
+// primaryComponent returns the component most of the files are in
+func primaryComponent(files []string, depth int) string {
+	if len(files) == 0 {
+		return rootComponent
+	}
+
+	counts := make(map[string]int)
+	best := ""
+	for _, file := range files {
+		component := componentOf(file, depth)
+		counts[component]++
+		if best == "" || counts[component] > counts[best] {
+			best = component
+		}
+	}
+	return best
+}
+
+// clipDiff cuts text that is over maxTokens at a line boundary
+func clipDiff(text string, tokens, maxTokens int) string {
+	// Leave room for the note; token density is assumed even across the text
+	keep := len(text) * (maxTokens - 50) / tokens
+	if keep < 0 {
+		keep = 0
+	}
+	clipped := text[:keep]
+	if i := strings.LastIndex(clipped, "\n"); i > 0 {
+		clipped = clipped[:i+1]
+	}
+	return clipped + "[... rest of this file's diff omitted ...]\n"
+}
@@ -0,0 +1,265 @@
+package workflow
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/1broseidon/promptext-notes/internal/ai"
+	"github.com/1broseidon/promptext-notes/internal/config"
+	"github.com/1broseidon/promptext-notes/internal/diff"
+	"github.com/1broseidon/promptext-notes/internal/git"
+	"github.com/1broseidon/promptext-notes/internal/prompt"
+)
+
+// concurrentProvider answers each request with a function and tracks the
+// highest number of requests in flight
+type concurrentProvider struct {
+	respond  func(prompt string) (string, error)
+	inFlight atomic.Int32
+	maxSeen  atomic.Int32
+
+	mu      sync.Mutex
+	prompts []string
+}
+
+func (p *concurrentProvider) Generate(ctx context.Context, req *ai.Request) (*ai.Response, error) {
+	n := p.inFlight.Add(1)
+	defer p.inFlight.Add(-1)
+	for {
+		seen := p.maxSeen.Load()
+		if n <= seen || p.maxSeen.CompareAndSwap(seen, n) {
+			break
+		}
+	}
+
+	p.mu.Lock()
+	p.prompts = append(p.prompts, req.Prompt)
+	p.mu.Unlock()
+
+	time.Sleep(5 * time.Millisecond)
+
+	content, err := p.respond(req.Prompt)
+	if err != nil {
+		return nil, err
+	}
+	return &ai.Response{Content: content, TokensUsed: 10, StopReason: ai.StopReasonComplete}, nil
+}
+
+func (p *concurrentProvider) Name() string          { return "concurrent" }
+func (p *concurrentProvider) ValidateConfig() error { return nil }
+func (p *concurrentProvider) NewRequest(prompt string) *ai.Request {
+	return &ai.Request{Prompt: prompt, MaxTokens: 100}
+}
+
+func TestComponentOf(t *testing.T) {
+	tests := []struct {
+		file  string
+		depth int
+		want  string
+	}{
+		{"main.go", 2, rootComponent},
+		{"cmd/app/main.go", 2, "cmd/app"},
+		{"internal/ai/openai/client.go", 2, "internal/ai"},
+		{"internal/ai/openai/client.go", 1, "internal"},
+	}
+
+	for _, tt := range tests {
+		if got := componentOf(tt.file, tt.depth); got != tt.want {
+			t.Errorf("componentOf(%q, %d) = %q, want %q", tt.file, tt.depth, got, tt.want)
+		}
+	}
+}
+
+func TestBuildChunks(t *testing.T) {
+	in := &promptInputs{
+		commits: []string{"feat: add export", "fix: crash on start", "chore: tidy"},
+		diffFiles: diff.Parse(fileDiff("internal/export/pdf.go", 5) + fileDiff("cmd/app/main.go", 5) +
+			fileDiff("internal/export/html.go", 5) + fileDiff("go.sum", 50)),
+	}
+	commitFiles := []git.CommitFiles{
+		{Subject: "feat: add export", Files: []string{"internal/export/pdf.go", "internal/export/html.go"}},
+		{Subject: "fix: crash on start", Files: []string{"cmd/app/main.go"}},
+		{Subject: "Merge branch 'main'", Files: nil}, // filtered out
+	}
+
+	// A large budget packs everything into one chunk in component order
+	chunks := buildChunks(in, commitFiles, 2, 100_000)
+	if len(chunks) != 1 {
+		t.Fatalf("Expected 1 chunk, got %d", len(chunks))
+	}
+	if got := chunks[0].label(); got != "(root), cmd/app, internal/export" {
+		t.Errorf("Unexpected label: %q", got)
+	}
+	if len(chunks[0].commits) != 3 || len(chunks[0].diffs) != 3 {
+		t.Errorf("Expected 3 commits and 3 diffs (go.sum dropped), got %d and %d", len(chunks[0].commits), len(chunks[0].diffs))
+	}
+	for _, c := range chunks[0].commits {
+		if strings.HasPrefix(c, "Merge") {
+			t.Error("Filtered commits must not be assigned")
+		}
+	}
+
+	// A small budget splits components apart and keeps each chunk within it
+	budget := ai.CountTokens(fileDiff("internal/export/pdf.go", 5)) + 20
+	chunks = buildChunks(in, commitFiles, 2, budget)
+	if len(chunks) < 3 {
+		t.Fatalf("Expected at least 3 chunks, got %d", len(chunks))
+	}
+	for _, c := range chunks {
+		if c.tokens > budget {
+			t.Errorf("Chunk %q has %d tokens, over budget %d", c.label(), c.tokens, budget)
+		}
+	}
+}
+
+func TestBuildChunksClipsLargeFiles(t *testing.T) {
+	in := &promptInputs{diffFiles: diff.Parse(fileDiff("internal/big/gen.go", 2000))}
+
+	chunks := buildChunks(in, nil, 2, 2000)
+	if len(chunks) != 1 {
+		t.Fatalf("Expected 1 chunk, got %d", len(chunks))
+	}
+	if chunks[0].tokens > 2000 {
+		t.Errorf("Clipped chunk has %d tokens, over budget", chunks[0].tokens)
+	}
+	if !strings.Contains(chunks[0].diffs[0], "rest of this file's diff omitted") {
+		t.Error("Expected a note on the clipped diff")
+	}
+}
+
+func TestSummarizeChunks(t *testing.T) {
+	cfg := config.Default()
+	cfg.AI.MapReduce.Concurrency = 2
+
+	chunks := []*chunk{
+		{components: []string{"cmd/app"}, commits: []string{"fix: crash"}},
+		{components: []string{"docs"}, commits: []string{"docs: typo"}},
+		{components: []string{"internal/export"}, commits: []string{"feat: export"}},
+		{components: []string{"internal/ai"}, commits: []string{"feat: gemini"}},
+	}
+
+	provider := &concurrentProvider{respond: func(p string) (string, error) {
+		if strings.Contains(p, "docs: typo") {
+			return prompt.NoChangesMarker, nil
+		}
+		for _, c := range []string{"fix: crash", "feat: export", "feat: gemini"} {
+			if strings.Contains(p, c) {
+				return "### Changed\n- " + c, nil
+			}
+		}
+		return "", errors.New("unexpected prompt")
+	}}
+
+	summaries, err := summarizeChunks(context.Background(), provider, chunks, "v1.0.0", prompt.NewFence(), cfg, false)
+	if err != nil {
+		t.Fatalf("summarizeChunks() error = %v", err)
+	}
+
+	if len(summaries) != 3 {
+		t.Fatalf("Expected 3 summaries (one without changes dropped), got %d", len(summaries))
+	}
+	want := []string{"cmd/app", "internal/export", "internal/ai"}
+	for i, summary := range summaries {
+		if summary.Component != want[i] {
+			t.Errorf("Summary %d is for %q, want %q (chunk order)", i, summary.Component, want[i])
+		}
+	}
+	if max := provider.maxSeen.Load(); max > 2 {
+		t.Errorf("Expected at most 2 requests in flight, saw %d", max)
+	}
+}
+
+func TestSummarizeChunksError(t *testing.T) {
+	cfg := config.Default()
+	cfg.AI.MapReduce.Concurrency = 1
+
+	chunks := []*chunk{
+		{components: []string{"a"}, commits: []string{"feat: a"}},
+		{components: []string{"b"}, commits: []string{"feat: b"}},
+		{components: []string{"c"}, commits: []string{"feat: c"}},
+	}
+
+	provider := &concurrentProvider{respond: func(p string) (string, error) {
+		if strings.Contains(p, "feat: a") {
+			return "", errors.New("rate limited")
+		}
+		return "### Added\n- item", nil
+	}}
+
+	_, err := summarizeChunks(context.Background(), provider, chunks, "v1.0.0", prompt.NewFence(), cfg, false)
+	if err == nil || !strings.Contains(err.Error(), "rate limited") {
+		t.Fatalf("Expected the failing part's error, got %v", err)
+	}
+	if errors.Is(err, context.Canceled) {
+		t.Error("Expected the original failure, not a cancellation")
+	}
+}
+
+func TestUseMapReduce(t *testing.T) {
+	in := newOversizedInputs()
+	provider := &scriptedProvider{}
+	use := func(cfg *config.Config) bool {
+		t.Helper()
+		mapReduce, err := useMapReduce(in, provider, cfg)
+		if err != nil {
+			t.Fatalf("useMapReduce() error = %v", err)
+		}
+		return mapReduce
+	}
+
+	cfg := config.Default()
+	cfg.AI.ContextWindow = 1_000_000
+	if use(cfg) {
+		t.Error("Small releases should use the single prompt")
+	}
+
+	cfg.AI.MapReduce.MinCommits = 2
+	if !use(cfg) {
+		t.Error("Expected map-reduce at min_commits")
+	}
+
+	cfg.AI.MapReduce.MinCommits = 0
+	cfg.AI.ContextWindow = 12_000
+	if !use(cfg) {
+		t.Error("Expected map-reduce when the prompt overflows the context window")
+	}
+
+	cfg.AI.MapReduce.Mode = "off"
+	if use(cfg) {
+		t.Error("Map-reduce should be disabled with mode off")
+	}
+}
+
+func TestReducePromptUsesTemplate(t *testing.T) {
+	summaries := []prompt.ChunkSummary{{Component: "cmd/app", Summary: "### Fixed\n- crash"}}
+
+	in := newOversizedInputs()
+	got, err := in.reduce(summaries)
+	if err != nil {
+		t.Fatalf("reduce() error = %v", err)
+	}
+	if !strings.Contains(got, "### Part 1: `cmd/app`") {
+		t.Errorf("Expected the default reduce prompt, got:\n%s", got)
+	}
+
+	// A template without a reduce block keeps the default
+	if in.template, err = prompt.ParseTemplate("Notes for {{.Version}}"); err != nil {
+		t.Fatalf("ParseTemplate() error = %v", err)
+	}
+	if got, err = in.reduce(summaries); err != nil || !strings.Contains(got, "## Part Summaries") {
+		t.Errorf("Expected the default reduce block, got %q (error %v)", got, err)
+	}
+
+	in.template, err = prompt.ParseTemplate(`{{define "reduce"}}Merge for {{.Version}}:{{range .Summaries}} {{.Component}}{{end}}{{end}}`)
+	if err != nil {
+		t.Fatalf("ParseTemplate() error = %v", err)
+	}
+	if got, err = in.reduce(summaries); err != nil || got != "Merge for v1.0.0: cmd/app" {
+		t.Errorf("Expected the template's reduce block, got %q (error %v)", got, err)
+	}
+}
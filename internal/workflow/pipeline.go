@@ -0,0 +1,233 @@
+package workflow
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/1broseidon/promptext-notes/internal/ai"
+	"github.com/1broseidon/promptext-notes/internal/config"
+	"github.com/1broseidon/promptext-notes/internal/prompt"
+)
+
+// runPipeline runs the generation stages in order, passing each stage's
+// output to the next as .Draft, and returns the last output
+func runPipeline(ctx context.Context, provider ai.Provider, tracker *ai.CostTracker, inputs *promptInputs, cfg *config.Config, verbose bool) (string, error) {
+	stages := []config.StageConfig{{Name: "discovery", Preset: "discovery"}}
+	if cfg != nil {
+		stages = cfg.Stages()
+	}
+
+	output := ""
+	inputs.outputs = make(map[string]string)
+	for _, stage := range stages {
+		var err error
+		if stage.Preset == "discovery" {
+			output, err = runDiscoveryStage(ctx, provider, tracker, stage, output, inputs, cfg, verbose)
+			if err != nil {
+				return "", err
+			}
+			inputs.outputs[stage.Name] = output
+			continue
+		}
+
+		if verbose {
+			if stage.Preset == "polish" {
+				fmt.Fprintf(os.Stderr, "\n✨ Polishing changelog with %s (%s)...\n", stage.Provider, stage.Model)
+			} else {
+				fmt.Fprintf(os.Stderr, "\n🔁 Running stage %s with %s (%s)...\n", stage.Name, stage.Provider, stage.Model)
+			}
+		}
+
+		staged, err := runStage(ctx, stage, output, inputs, cfg, tracker, verbose)
+		if errors.Is(err, ErrPolishChangedItems) {
+			// Keep the input rather than output that lost or invented items,
+			// and say so even without --verbose: the stage's output is lost
+			fmt.Fprintf(os.Stderr, "⚠️  Keeping the output of the previous stage: %v\n", err)
+			inputs.outputs[stage.Name] = output
+			continue
+		}
+		if err != nil {
+			return "", fmt.Errorf("failed to run stage %s: %w", stage.Name, err)
+		}
+
+		if verbose {
+			fmt.Fprintf(os.Stderr, "   ✓ Stage %s complete\n", stage.Name)
+		}
+		if err := checkOutput(staged, inputs, cfg); err != nil {
+			return "", err
+		}
+
+		output = staged
+		inputs.outputs[stage.Name] = output
+	}
+
+	return output, nil
+}
+
+// runDiscoveryStage writes the release notes from the commits, diff and code
+// context, then checks them against the inputs. It uses the run's provider
+// unless the stage names another provider or model, or ai.ensemble is on.
+func runDiscoveryStage(ctx context.Context, provider ai.Provider, tracker *ai.CostTracker, stage config.StageConfig, previous string, inputs *promptInputs, cfg *config.Config, verbose bool) (string, error) {
+	inputs.draft = previous
+
+	if cfg != nil {
+		overridden := stage.Provider != cfg.AI.Provider || stage.Model != cfg.AI.Model ||
+			stage.MaxTokens != cfg.AI.MaxTokens || *stage.Temperature != cfg.AI.Temperature
+		if overridden && !useEnsemble(cfg) {
+			discoveryCfg := *cfg
+			discoveryCfg.AI.Provider = stage.Provider
+			discoveryCfg.AI.Model = stage.Model
+			discoveryCfg.AI.Custom = cfg.CustomFor(stage.Model)
+			discoveryCfg.AI.APIKeyEnv = stage.APIKeyEnv
+			discoveryCfg.AI.MaxTokens = stage.MaxTokens
+			discoveryCfg.AI.Temperature = *stage.Temperature
+
+			var err error
+			if provider, err = ai.NewProvider(&discoveryCfg); err != nil {
+				return "", fmt.Errorf("failed to create %s provider: %w", stage.Name, err)
+			}
+		}
+
+		if stage.PromptTemplate != "" {
+			template, err := prompt.LoadTemplate(stage.PromptTemplate)
+			if err != nil {
+				return "", err
+			}
+			inputs.template = template
+		}
+	}
+
+	var content string
+	var err error
+	if useEnsemble(cfg) {
+		content, err = generateEnsemble(ctx, tracker, stage, inputs, cfg, verbose)
+	} else {
+		content, err = generateDraft(ctx, provider, tracker, stage.Name, inputs, cfg, verbose)
+	}
+	if err != nil {
+		return "", err
+	}
+	if err := checkOutput(content, inputs, cfg); err != nil {
+		return "", err
+	}
+	return verifyGrounding(ctx, content, inputs, cfg, tracker, verbose)
+}
+
+// runStage runs a stage after discovery: its prompt template is rendered with
+// the inputs plus the previous stage's output as .Draft, fitted to the stage
+// model's context window like the discovery prompt, and sent with the rules
+// for fenced input as the system prompt. Stages that must keep the sections
+// and items of their input (polish, check_items) are retried once with
+// feedback, then return ErrPolishChangedItems.
+// Usage is recorded in tracker (which may be nil) under the stage's name.
+func runStage(ctx context.Context, stage config.StageConfig, input string, inputs *promptInputs, cfg *config.Config, tracker *ai.CostTracker, verbose bool) (string, error) {
+	stageAI, err := newStageProvider(stage, cfg)
+	if err != nil {
+		return "", err
+	}
+	stageAI = ai.WithCostTracking(stageAI, tracker, stage.Name, cfg)
+
+	stageInputs := *inputs
+	stageInputs.render = func(data *prompt.TemplateData) (string, error) {
+		return renderStagePrompt(stage, input, data, cfg)
+	}
+	stagePrompt, err := fitContextWindow(&stageInputs, stageAI, cfg, verbose)
+	if err != nil {
+		return "", fmt.Errorf("%s stage: %w", stage.Name, err)
+	}
+	systemPrompt, err := stageInputs.system()
+	if err != nil {
+		return "", err
+	}
+
+	attempts := 1
+	checkItems := stage.Preset == "polish" || stage.CheckItems
+	if checkItems {
+		attempts = 2
+	}
+
+	var problems []string
+	for attempt := 0; attempt < attempts; attempt++ {
+		if attempt > 0 {
+			stagePrompt = prompt.PolishRetryPrompt(stagePrompt, problems)
+		}
+
+		req := stageAI.NewRequest(stagePrompt)
+		req.SystemPrompt = systemPrompt
+
+		// Generate, continuing or failing on truncation
+		resp, err := generateComplete(ctx, stageAI, req, cfg, false)
+		if err != nil {
+			return "", fmt.Errorf("failed to generate %s output: %w", stage.Name, err)
+		}
+
+		if !checkItems {
+			return resp.Content, nil
+		}
+		problems = checkPolish(input, resp.Content)
+		if len(problems) == 0 {
+			return resp.Content, nil
+		}
+	}
+
+	return "", fmt.Errorf("%w (stage %s): %s", ErrPolishChangedItems, stage.Name, strings.Join(problems, "; "))
+}
+
+// newStageProvider creates the provider for a stage from the stage's
+// provider, model and limits plus the shared timeout, retry, reasoning and
+// pricing settings
+func newStageProvider(stage config.StageConfig, cfg *config.Config) (ai.Provider, error) {
+	stageCfg := &config.Config{
+		AI: config.AIConfig{
+			Provider:        stage.Provider,
+			Model:           stage.Model,
+			APIKeyEnv:       stage.APIKeyEnv,
+			MaxTokens:       stage.MaxTokens,
+			Temperature:     *stage.Temperature,
+			Timeout:         cfg.AI.Timeout,
+			ReasoningEffort: cfg.AI.ReasoningEffort,
+			ThinkingBudget:  cfg.AI.ThinkingBudget,
+			Retry:           cfg.AI.Retry,
+			Custom:          cfg.CustomFor(stage.Model),
+			Pricing:         cfg.AI.Pricing,
+			Cache:           cfg.AI.Cache,
+			Replay:          cfg.AI.Replay,
+			Exec:            cfg.AI.Exec,
+		},
+	}
+
+	stageAI, err := ai.NewProvider(stageCfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s AI provider: %w", stage.Name, err)
+	}
+	return stageAI, nil
+}
+
+// renderStagePrompt renders the stage's prompt template; the polish preset
+// defaults to the built-in polish template, and a legacy polish_prompt with a
+// single %s for the draft takes precedence when set
+func renderStagePrompt(stage config.StageConfig, input string, data *prompt.TemplateData, cfg *config.Config) (string, error) {
+	var tmpl *prompt.StageTemplate
+	var err error
+	if stage.Preset == "polish" {
+		if stage.PromptTemplate == "" && cfg.AI.Polish.PolishPrompt != "" {
+			return fmt.Sprintf(cfg.AI.Polish.PolishPrompt, input), nil
+		}
+		tmpl, err = prompt.LoadPolishTemplate(stage.PromptTemplate)
+	} else {
+		tmpl, err = prompt.LoadStageTemplate(stage.Name, stage.PromptTemplate)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	stageData := prompt.TemplateData{}
+	if data != nil {
+		stageData = *data
+	}
+	stageData.Draft = input
+	return tmpl.Prompt(&stageData)
+}
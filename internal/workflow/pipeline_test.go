@@ -0,0 +1,189 @@
+package workflow
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/1broseidon/promptext-notes/internal/ai"
+	"github.com/1broseidon/promptext-notes/internal/config"
+)
+
+func TestRunPipelineCustomStages(t *testing.T) {
+	dir := t.TempDir()
+	critique := filepath.Join(dir, "critique.tmpl")
+	translate := filepath.Join(dir, "translate.tmpl")
+	if err := os.WriteFile(critique, []byte("Critique {{.Version}}:\n{{.Draft}}"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(translate, []byte("Apply {{.Draft}} to:\n{{index .Outputs \"draft\"}}"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, prompts := newPolishServer(t, "Too vague", "### Ajouté\n- **Export** - commande d'export\n")
+	cfg.AI.Polish.Enabled = false
+	cfg.AI.ContextWindow = 1_000_000
+	cfg.AI.Pipeline = []config.StageConfig{
+		{Name: "draft", Preset: "discovery"},
+		{Name: "critique", PromptTemplate: critique},
+		{Name: "translate", PromptTemplate: translate},
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+
+	draft := "### Added\n- **Export** - add export command\n"
+	provider := &scriptedProvider{responses: []*ai.Response{
+		{Content: draft, StopReason: ai.StopReasonComplete},
+	}}
+	tracker := ai.NewCostTracker(0)
+
+	got, err := runPipeline(context.Background(), provider, tracker, newOversizedInputs(), cfg, false)
+	if err != nil {
+		t.Fatalf("runPipeline() error = %v", err)
+	}
+	if !strings.Contains(got, "commande d'export") {
+		t.Errorf("Expected the last stage's output, got:\n%s", got)
+	}
+
+	if len(*prompts) != 2 {
+		t.Fatalf("Expected 2 stage requests, got %d", len(*prompts))
+	}
+	if (*prompts)[0] != "Critique v1.0.0:\n"+strings.TrimSpace(draft) {
+		t.Errorf("Expected the draft passed to the critique stage, got %q", (*prompts)[0])
+	}
+	if (*prompts)[1] != "Apply Too vague to:\n"+strings.TrimSpace(draft) {
+		t.Errorf("Expected the critique and the draft in the translate prompt, got %q", (*prompts)[1])
+	}
+
+	var stages []string
+	for _, stage := range tracker.Stages() {
+		stages = append(stages, stage.Stage)
+	}
+	if strings.Join(stages, ",") != "draft,critique,translate" {
+		t.Errorf("Expected usage per stage, got %v", stages)
+	}
+}
+
+func TestRunPipelineCheckItemsKeepsInput(t *testing.T) {
+	dir := t.TempDir()
+	shorten := filepath.Join(dir, "shorten.tmpl")
+	if err := os.WriteFile(shorten, []byte("Shorten:\n{{.Draft}}"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, prompts := newPolishServer(t, "### Added\n", "### Added\n")
+	cfg.AI.Polish.Enabled = false
+	cfg.AI.ContextWindow = 1_000_000
+	cfg.AI.Pipeline = []config.StageConfig{
+		{Preset: "discovery"},
+		{Name: "shorten", PromptTemplate: shorten, CheckItems: true},
+	}
+
+	draft := "### Added\n- **Export** - add export command\n"
+	provider := &scriptedProvider{responses: []*ai.Response{
+		{Content: draft, StopReason: ai.StopReasonComplete},
+	}}
+
+	var got string
+	var err error
+	stderr := captureStderr(t, func() {
+		got, err = runPipeline(context.Background(), provider, ai.NewCostTracker(0), newOversizedInputs(), cfg, false)
+	})
+	if err != nil {
+		t.Fatalf("runPipeline() error = %v", err)
+	}
+	if strings.TrimSpace(got) != strings.TrimSpace(draft) {
+		t.Errorf("Expected the stage's input kept, got:\n%s", got)
+	}
+	if !strings.Contains(stderr, "Keeping the output of the previous stage") {
+		t.Errorf("Expected a warning without --verbose, got %q", stderr)
+	}
+	if len(*prompts) != 2 {
+		t.Errorf("Expected one retry, got %d requests", len(*prompts))
+	}
+}
+
+func TestRunStageRequest(t *testing.T) {
+	type message struct {
+		Role    string `json:"role"`
+		Content string `json:"content"`
+	}
+	var requests [][]message
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Messages []message `json:"messages"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		requests = append(requests, req.Messages)
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"message":     map[string]string{"role": "assistant", "content": "### Added\n- **Export** - reviewed"},
+			"done":        true,
+			"done_reason": "stop",
+		})
+	}))
+	t.Cleanup(server.Close)
+
+	review := filepath.Join(t.TempDir(), "review.tmpl")
+	if err := os.WriteFile(review, []byte("Check the notes against the diff:\n{{.Fence.Wrap \"diff\" .Diff}}\n{{.Draft}}"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := config.Default()
+	cfg.AI.Provider = "ollama"
+	cfg.AI.Model = "llama3.2"
+	cfg.AI.APIKeyEnv = ""
+	cfg.AI.MaxTokens = 1000
+	cfg.AI.Custom = map[string]string{"ollama_url": server.URL}
+	cfg.AI.Cache.Mode = "off"
+	cfg.AI.ContextWindow = 8000
+	cfg.AI.Pipeline = []config.StageConfig{{Preset: "discovery"}, {Name: "review", PromptTemplate: review}}
+	stage := cfg.Stages()[1]
+
+	in := newOversizedInputs()
+	if _, err := runStage(context.Background(), stage, "### Added\n- **Export** - add export", in, cfg, nil, false); err != nil {
+		t.Fatalf("runStage() error = %v", err)
+	}
+	if len(requests) != 1 || len(requests[0]) != 2 || requests[0][0].Role != "system" {
+		t.Fatalf("Expected a system and a user message, got %+v", requests)
+	}
+	if !strings.Contains(requests[0][0].Content, "## Untrusted Input") {
+		t.Errorf("Expected the rules for fenced input in the system prompt, got:\n%s", requests[0][0].Content)
+	}
+	if tokens := ai.CountTokens(requests[0][1].Content); tokens > 8000-1000-400 {
+		t.Errorf("Expected the stage prompt fitted to the context window, got ~%d tokens", tokens)
+	}
+	if in.diff == "" || ai.CountTokens(in.diff) < 8000 {
+		t.Error("Expected the run's inputs to keep the full diff")
+	}
+
+	cfg.AI.OnContextOverflow = "fail"
+	if _, err := runStage(context.Background(), stage, "### Added\n", in, cfg, nil, false); !errors.Is(err, ErrContextOverflow) {
+		t.Errorf("Expected ErrContextOverflow for a stage prompt over the context window, got %v", err)
+	}
+}
+
+func TestNewStageProviderKeepsReasoning(t *testing.T) {
+	t.Setenv("OPENAI_API_KEY", "test-key")
+	cfg := config.Default()
+	cfg.AI.Provider = "openai"
+	cfg.AI.Model = "o3-mini"
+	cfg.AI.APIKeyEnv = "OPENAI_API_KEY"
+	cfg.AI.ReasoningEffort = "high"
+	cfg.AI.Cache.Mode = "off"
+	cfg.AI.Pipeline = []config.StageConfig{{Preset: "discovery"}, {Name: "review", PromptTemplate: "review.tmpl"}}
+
+	provider, err := newStageProvider(cfg.Stages()[1], cfg)
+	if err != nil {
+		t.Fatalf("newStageProvider() error = %v", err)
+	}
+	if req := provider.NewRequest("prompt"); req.ReasoningEffort != "high" {
+		t.Errorf("Expected the stage request to keep reasoning_effort high, got %q", req.ReasoningEffort)
+	}
+}
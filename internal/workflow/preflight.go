@@ -0,0 +1,294 @@
+package workflow
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/1broseidon/promptext-notes/internal/ai"
+	"github.com/1broseidon/promptext-notes/internal/analyzer"
+	"github.com/1broseidon/promptext-notes/internal/config"
+	"github.com/1broseidon/promptext-notes/internal/diff"
+	"github.com/1broseidon/promptext-notes/internal/git"
+	"github.com/1broseidon/promptext-notes/internal/prompt"
+	"github.com/1broseidon/promptext/pkg/promptext"
+)
+
+// ErrContextOverflow is returned when the prompt does not fit the model's context window
+var ErrContextOverflow = errors.New("prompt exceeds the model context window")
+
+// promptInputs holds everything the discovery prompt is built from, so the
+// prompt can be rebuilt after oversized inputs are reduced
+type promptInputs struct {
+	version    string
+	sinceTag   string
+	commits    []string
+	categories analyzer.CommitCategories
+	result     *promptext.Result
+	diffStats  string
+	diff       string       // Diff as shown in the prompt, fitted to the diff budget
+	diffFiles  []diff.File  // The full parsed diff
+	fence      prompt.Fence // Encloses the untrusted inputs in every prompt of the run
+
+	template      *prompt.Template // Discovery prompt template (nil = prompt.Default())
+	previousNotes string           // Notes of the previous release, for templates
+	hashes        []git.Commit     // Commit hashes shown in the prompt for structured output
+
+	draft   string            // Output of the previous pipeline stage, if discovery isn't first
+	outputs map[string]string // Outputs of the earlier pipeline stages by name
+
+	// render renders a pipeline stage's prompt in place of the template
+	render func(data *prompt.TemplateData) (string, error)
+}
+
+// data collects the inputs for rendering a prompt template
+func (in *promptInputs) data() *prompt.TemplateData {
+	data := prompt.NewTemplateData(in.version, in.sinceTag, in.hashedCommits(),
+		in.categories, in.result, in.diffStats, in.diff, in.fence)
+	data.PreviousNotes = in.previousNotes
+	data.Draft = in.draft
+	data.Outputs = in.outputs
+	return data
+}
+
+// hashedCommits prefixes each commit with its abbreviated hash when hashes
+// were read, so structured output can cite the commits of each item
+func (in *promptInputs) hashedCommits() []string {
+	if len(in.hashes) == 0 {
+		return in.commits
+	}
+
+	bySubject := make(map[string][]string)
+	for _, commit := range in.hashes {
+		bySubject[commit.Subject] = append(bySubject[commit.Subject], commit.Hash)
+	}
+
+	commits := make([]string, len(in.commits))
+	for i, subject := range in.commits {
+		commits[i] = subject
+		if hashes := bySubject[subject]; len(hashes) > 0 {
+			commits[i] = hashes[0] + " " + subject
+			bySubject[subject] = hashes[1:]
+		}
+	}
+	return commits
+}
+
+// knownHashes returns the commit hashes items may cite, or nil when none
+// were read, which skips the check
+func (in *promptInputs) knownHashes() []string {
+	if in.hashes == nil {
+		return nil
+	}
+	hashes := make([]string, 0, len(in.hashes))
+	for _, commit := range in.hashes {
+		hashes = append(hashes, commit.Hash)
+	}
+	return hashes
+}
+
+// tmpl returns the discovery prompt template
+func (in *promptInputs) tmpl() *prompt.Template {
+	if in.template == nil {
+		return prompt.Default()
+	}
+	return in.template
+}
+
+// build renders the discovery prompt, or the stage's prompt, from the inputs
+func (in *promptInputs) build() (string, error) {
+	if in.render != nil {
+		return in.render(in.data())
+	}
+	return in.tmpl().Prompt(in.data())
+}
+
+// reduce renders the prompt merging the map-reduce part summaries, from the
+// template's "reduce" block
+func (in *promptInputs) reduce(summaries []prompt.ChunkSummary) (string, error) {
+	data := in.data()
+	data.Summaries = summaries
+	return in.tmpl().Reduce(data)
+}
+
+// system renders the system prompt sent with the discovery and reduce
+// prompts; stages after discovery get only the rules for fenced input
+func (in *promptInputs) system() (string, error) {
+	if in.render != nil {
+		return prompt.StageSystemPrompt(in.fence), nil
+	}
+	return in.tmpl().System(in.data())
+}
+
+// fullDiff returns the text of every parsed file, including those left out of
+// the prompt's diff
+func (in *promptInputs) fullDiff() string {
+	var text strings.Builder
+	for i := range in.diffFiles {
+		text.WriteString(in.diffFiles[i].Text())
+	}
+	return text.String()
+}
+
+// untrusted returns the text of every input the release notes may draw on:
+// commits, the full diff and its stats, code context, previous notes and the
+// detected API and dependency changes, without the prompt's instructions
+func (in *promptInputs) untrusted() string {
+	parts := []string{in.version, in.sinceTag, strings.Join(in.hashedCommits(), "\n"), in.diffStats, in.fullDiff(), in.previousNotes,
+		strings.Join(in.categories.APIChanges, "\n"), strings.Join(in.categories.Dependencies, "\n")}
+	if in.result != nil {
+		parts = append(parts, in.result.FormattedOutput)
+	}
+	return strings.Join(parts, "\n")
+}
+
+// promptSection is a named part of the prompt and its token count
+type promptSection struct {
+	name   string
+	tokens int
+}
+
+// measurePrompt counts the tokens of the whole prompt and of each variable
+// section; "instructions" is the fixed template text around them
+func measurePrompt(in *promptInputs, promptText string) (int, []promptSection) {
+	total := ai.CountTokens(promptText)
+
+	sections := []promptSection{
+		{"diff", ai.CountTokens(in.diff)},
+		{"code context", ai.CountTokens(in.result.FormattedOutput)},
+		{"commits", ai.CountTokens(strings.Join(in.commits, "\n"))},
+		{"diff stats", ai.CountTokens(in.diffStats)},
+	}
+
+	variable := 0
+	for _, section := range sections {
+		variable += section.tokens
+	}
+
+	instructions := total - variable
+	if instructions < 0 {
+		instructions = 0
+	}
+	sections = append(sections, promptSection{"instructions", instructions})
+
+	return total, sections
+}
+
+// formatSections renders a per-section token report
+func formatSections(total int, sections []promptSection) string {
+	var report strings.Builder
+	for _, section := range sections {
+		report.WriteString(fmt.Sprintf("   %-13s ~%d tokens\n", section.name, section.tokens))
+	}
+	report.WriteString(fmt.Sprintf("   %-13s ~%d tokens\n", "total", total))
+	return report.String()
+}
+
+// promptBudget is the room a provider's model has for a prompt
+type promptBudget struct {
+	model     string
+	window    int
+	available int  // Tokens left for the prompt after reserving the output
+	known     bool // False when the model's context window is unknown
+}
+
+// newPromptBudget looks up the context window of the provider's default model
+func newPromptBudget(provider ai.Provider, cfg *config.Config) promptBudget {
+	defaults := provider.NewRequest("")
+	window, known := ai.ContextWindow(cfg, provider.Name(), defaults.Model)
+
+	// Reserve room for the output plus 5% headroom for tokenizer differences
+	return promptBudget{
+		model:     defaults.Model,
+		window:    window,
+		available: window - defaults.MaxTokens - window/20,
+		known:     known,
+	}
+}
+
+// fitContextWindow counts the prompt's tokens before it is sent and, when it
+// would overflow the model's context window, either fails or reduces the
+// prompt (dropping code context first, then shrinking the diff budget)
+func fitContextWindow(in *promptInputs, provider ai.Provider, cfg *config.Config, verbose bool) (string, error) {
+	promptText, err := in.build()
+	if err != nil {
+		return "", err
+	}
+	if cfg == nil {
+		return promptText, nil
+	}
+
+	budget := newPromptBudget(provider, cfg)
+	if !budget.known {
+		if verbose {
+			fmt.Fprintf(os.Stderr, "   Prompt: ~%d tokens (context window of %s unknown, not checked)\n",
+				ai.CountTokens(promptText), budget.model)
+		}
+		return promptText, nil
+	}
+	systemPrompt, err := in.system()
+	if err != nil {
+		return "", err
+	}
+	available := budget.available - ai.CountTokens(systemPrompt)
+
+	total, sections := measurePrompt(in, promptText)
+	if verbose {
+		fmt.Fprintf(os.Stderr, "   Prompt: ~%d tokens (%d available in %d-token context window of %s)\n",
+			total, available, budget.window, budget.model)
+	}
+	if total <= available {
+		return promptText, nil
+	}
+
+	report := formatSections(total, sections)
+	if cfg.AI.OnContextOverflow == "fail" {
+		return "", fmt.Errorf("%w: ~%d tokens for %d available in %s\n%s",
+			ErrContextOverflow, total, available, budget.model, report)
+	}
+
+	if verbose {
+		fmt.Fprintf(os.Stderr, "   ⚠️  Prompt exceeds the context window by ~%d tokens:\n%s", total-available, report)
+	}
+
+	// Step 1: drop the code context (secondary source)
+	reduced := *in
+	if in.result.FormattedOutput != "" {
+		result := *in.result
+		result.FormattedOutput = "(omitted to fit the model's context window)"
+		reduced.result = &result
+
+		if promptText, err = reduced.build(); err != nil {
+			return "", err
+		}
+		total = ai.CountTokens(promptText)
+		if verbose {
+			fmt.Fprintf(os.Stderr, "   Dropped code context, prompt now ~%d tokens\n", total)
+		}
+	}
+
+	// Step 2: shrink the diff budget, keeping the most relevant files
+	if total > available && len(reduced.diffFiles) > 0 {
+		diffBudget := ai.CountTokens(reduced.diff) - (total - available)
+		selection := diff.Budget(reduced.diffFiles, diffBudget)
+		reduced.diff = selection.Text
+
+		if promptText, err = reduced.build(); err != nil {
+			return "", err
+		}
+		total = ai.CountTokens(promptText)
+		if verbose {
+			fmt.Fprintf(os.Stderr, "   Reduced the diff to %d full and %d partial file(s), prompt now ~%d tokens\n",
+				selection.Included, selection.Partial, total)
+		}
+	}
+
+	if total > available {
+		total, sections = measurePrompt(&reduced, promptText)
+		return "", fmt.Errorf("%w even after reduction: ~%d tokens for %d available in %s\n%s",
+			ErrContextOverflow, total, available, budget.model, formatSections(total, sections))
+	}
+
+	return promptText, nil
+}
@@ -0,0 +1,111 @@
+package workflow
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/1broseidon/promptext-notes/internal/analyzer"
+	"github.com/1broseidon/promptext-notes/internal/config"
+	"github.com/1broseidon/promptext-notes/internal/diff"
+	"github.com/1broseidon/promptext/pkg/promptext"
+)
+
+// fileDiff builds a single-file unified diff with the given number of added lines
+func fileDiff(name string, lines int) string {
+	var diff strings.Builder
+	diff.WriteString("diff --git a/" + name + " b/" + name + "\n")
+	diff.WriteString("--- a/" + name + "\n+++ b/" + name + "\n@@ -0,0 +1 @@\n")
+	for i := 0; i < lines; i++ {
+		diff.WriteString("+func generated() { return \"some changed line of code\" }\n")
+	}
+	return diff.String()
+}
+
+// newOversizedInputs returns prompt inputs with a large diff and code context
+func newOversizedInputs() *promptInputs {
+	var unified strings.Builder
+	for _, name := range []string{"a.go", "b.go", "c.go", "d.go"} {
+		unified.WriteString(fileDiff(name, 200))
+	}
+	files := diff.Parse(unified.String())
+
+	return &promptInputs{
+		version:    "v1.0.0",
+		sinceTag:   "v0.9.0",
+		commits:    []string{"feat: add export", "fix: crash on start"},
+		categories: analyzer.CommitCategories{},
+		result: &promptext.Result{
+			FormattedOutput: strings.Repeat("package main\n\nfunc main() {}\n", 500),
+			ProjectOutput:   &promptext.ProjectOutput{},
+		},
+		diffStats: " 4 files changed, 800 insertions(+)",
+		diff:      diff.Budget(files, 100_000).Text,
+		diffFiles: files,
+	}
+}
+
+func TestFitContextWindowWithinLimit(t *testing.T) {
+	in := newOversizedInputs()
+	cfg := config.Default()
+	cfg.AI.ContextWindow = 1_000_000
+
+	got, err := fitContextWindow(in, &scriptedProvider{}, cfg, false)
+	if err != nil {
+		t.Fatalf("fitContextWindow() error = %v", err)
+	}
+	if want, _ := in.build(); got != want {
+		t.Error("Prompt within the context window should be unchanged")
+	}
+}
+
+func TestFitContextWindowReduce(t *testing.T) {
+	in := newOversizedInputs()
+	cfg := config.Default()
+	cfg.AI.ContextWindow = 12_000
+
+	got, err := fitContextWindow(in, &scriptedProvider{}, cfg, false)
+	if err != nil {
+		t.Fatalf("fitContextWindow() error = %v", err)
+	}
+	if strings.Contains(got, "func main() {}") {
+		t.Error("Expected code context to be dropped")
+	}
+	if !strings.Contains(got, "not shown") {
+		t.Error("Expected diff files to be replaced by stat lines")
+	}
+	if !strings.Contains(got, "feat: add export") {
+		t.Error("Commits must be kept")
+	}
+
+	// The inputs themselves are not modified
+	if !strings.Contains(in.result.FormattedOutput, "func main() {}") {
+		t.Error("Original code context should be left untouched")
+	}
+}
+
+func TestFitContextWindowFail(t *testing.T) {
+	cfg := config.Default()
+	cfg.AI.ContextWindow = 12_000
+	cfg.AI.OnContextOverflow = "fail"
+
+	_, err := fitContextWindow(newOversizedInputs(), &scriptedProvider{}, cfg, false)
+	if !errors.Is(err, ErrContextOverflow) {
+		t.Fatalf("Expected ErrContextOverflow, got %v", err)
+	}
+	for _, section := range []string{"diff", "code context", "commits", "instructions"} {
+		if !strings.Contains(err.Error(), section) {
+			t.Errorf("Expected %q in the token report, got %v", section, err)
+		}
+	}
+}
+
+func TestFitContextWindowTooSmall(t *testing.T) {
+	cfg := config.Default()
+	cfg.AI.ContextWindow = 500
+
+	_, err := fitContextWindow(newOversizedInputs(), &scriptedProvider{}, cfg, false)
+	if !errors.Is(err, ErrContextOverflow) {
+		t.Fatalf("Expected ErrContextOverflow, got %v", err)
+	}
+}
@@ -0,0 +1,124 @@
+package workflow
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/1broseidon/promptext-notes/internal/config"
+	"github.com/1broseidon/promptext-notes/internal/redact"
+	"github.com/1broseidon/promptext/pkg/promptext"
+)
+
+// ErrSecretsFound is returned when secrets are found and ai.redaction.mode is abort
+var ErrSecretsFound = errors.New("secrets found in the release inputs")
+
+// redaction tracks the secrets removed from each input before the prompt is built
+type redaction struct {
+	redactor *redact.Redactor
+	mode     string
+	sources  []string // Input names in the order they were redacted
+	reports  map[string]redact.Report
+}
+
+// newRedaction creates the redaction stage from the config; it returns nil
+// when redaction is off
+func newRedaction(cfg *config.Config) (*redaction, error) {
+	settings := config.Default().AI.Redaction
+	if cfg != nil {
+		settings = cfg.AI.Redaction
+	}
+	if settings.Mode == "off" {
+		return nil, nil
+	}
+
+	redactor, err := redact.New(settings.Patterns, settings.EntropyThreshold)
+	if err != nil {
+		return nil, err
+	}
+
+	return &redaction{
+		redactor: redactor,
+		mode:     settings.Mode,
+		reports:  make(map[string]redact.Report),
+	}, nil
+}
+
+// gitData redacts the diff, keeping it parseable, and the commit messages
+func (r *redaction) gitData(data *gitData) {
+	var report redact.Report
+	data.diff, report = r.redactor.RedactDiff(data.diff)
+	r.record("diff", report)
+
+	commits := make(redact.Report)
+	for i, commit := range data.commits {
+		data.commits[i], report = r.redactor.Redact(commit)
+		commits.Add(report)
+	}
+	r.record("commits", commits)
+}
+
+// codeContext redacts the extracted file contents and their formatted output
+func (r *redaction) codeContext(result *promptext.Result) {
+	var report redact.Report
+	result.FormattedOutput, report = r.redactor.Redact(result.FormattedOutput)
+	r.record("code context", report)
+
+	// The files are redacted as well for anything rendering them directly;
+	// their secrets are already counted in the formatted output
+	if result.ProjectOutput != nil {
+		for i := range result.ProjectOutput.Files {
+			result.ProjectOutput.Files[i].Content, _ = r.redactor.Redact(result.ProjectOutput.Files[i].Content)
+		}
+	}
+}
+
+// record stores the report for one input
+func (r *redaction) record(source string, report redact.Report) {
+	if _, ok := r.reports[source]; !ok {
+		r.sources = append(r.sources, source)
+	}
+	r.reports[source] = report
+}
+
+// finish reports what was redacted and fails in abort mode if anything was
+func (r *redaction) finish(verbose bool) error {
+	total := make(redact.Report)
+	for _, source := range r.sources {
+		total.Add(r.reports[source])
+	}
+	if total.Total() == 0 {
+		return nil
+	}
+
+	if r.mode == "abort" {
+		return fmt.Errorf("%w: %s (set ai.redaction.mode to redact to replace them with placeholders)",
+			ErrSecretsFound, r.summary())
+	}
+
+	if verbose {
+		fmt.Fprintf(os.Stderr, "\n🔒 Redacted %d secret(s) before building the prompt:\n", total.Total())
+		for _, source := range r.sources {
+			if report := r.reports[source]; report.Total() > 0 {
+				fmt.Fprintf(os.Stderr, "   %s: %s\n", source, report)
+			}
+		}
+	}
+	return nil
+}
+
+// summary lists the secrets found per input on one line
+func (r *redaction) summary() string {
+	var summary string
+	for _, source := range r.sources {
+		report := r.reports[source]
+		if report.Total() == 0 {
+			continue
+		}
+		if summary != "" {
+			summary += "; "
+		}
+		summary += fmt.Sprintf("%s: %s", source, report)
+	}
+	return summary
+}
@@ -0,0 +1,86 @@
+package workflow
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/1broseidon/promptext-notes/internal/ai"
+	"github.com/1broseidon/promptext-notes/internal/config"
+	"github.com/1broseidon/promptext-notes/internal/generator"
+	"github.com/1broseidon/promptext-notes/internal/git"
+	"github.com/1broseidon/promptext-notes/internal/notes"
+	"github.com/1broseidon/promptext-notes/internal/prompt"
+)
+
+// maxJSONRepairs is how many times an invalid JSON response is sent back to
+// the model with the validation errors before giving up
+const maxJSONRepairs = 2
+
+// ErrInvalidStructuredOutput is returned when the model's JSON still doesn't
+// match the release notes schema after the repair requests
+var ErrInvalidStructuredOutput = errors.New("structured output does not match the release notes schema")
+
+// structuredOutput reports whether the release notes are requested as JSON
+func structuredOutput(cfg *config.Config) bool {
+	return cfg != nil && cfg.AI.OutputFormat == "json"
+}
+
+// commitHashes reads the abbreviated hash of each commit so items can cite
+// their source commits (non-fatal)
+func commitHashes(sinceTag string, verbose bool) []git.Commit {
+	commits, err := git.GetCommitHashes(sinceTag)
+	if err != nil {
+		if verbose {
+			fmt.Fprintf(os.Stderr, "   Warning: could not read commit hashes, cited commits won't be checked: %v\n", err)
+		}
+		return nil
+	}
+	return commits
+}
+
+// generateStructured requests the release notes as JSON in the provider's
+// native structured output mode, validates them against the schema and the
+// commit history, asks the model to repair invalid output and renders the
+// result with the generator
+func generateStructured(ctx context.Context, provider ai.Provider, in *promptInputs, systemPrompt, promptText string, cfg *config.Config, verbose bool) (string, *ai.Response, error) {
+	schema := notes.Schema()
+	schemaJSON, err := json.MarshalIndent(schema, "", "  ")
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to encode the release notes schema: %w", err)
+	}
+	systemPrompt += "\n" + prompt.JSONInstructions(string(schemaJSON), notes.Sections)
+
+	total := &ai.Response{}
+	userPrompt := promptText
+	for attempt := 0; ; attempt++ {
+		req := provider.NewRequest(userPrompt)
+		req.SystemPrompt = systemPrompt
+		req.JSONSchema = &ai.JSONSchema{Name: "release_notes", Schema: schema}
+
+		response, err := generateComplete(ctx, provider, req, cfg, verbose)
+		if err != nil {
+			return "", nil, fmt.Errorf("failed to generate AI response: %w", err)
+		}
+		total.TokensUsed += response.TokensUsed
+		total.CostEstimate += response.CostEstimate
+
+		releaseNotes, err := notes.Parse(response.Content)
+		if err == nil {
+			err = releaseNotes.Validate(in.knownHashes())
+		}
+		if err == nil {
+			return generator.RenderNotes(in.version, releaseNotes, cfg), total, nil
+		}
+
+		if attempt == maxJSONRepairs {
+			return "", nil, fmt.Errorf("%w: %v", ErrInvalidStructuredOutput, err)
+		}
+		if verbose {
+			fmt.Fprintf(os.Stderr, "   ⚠️  Invalid JSON output, requesting a repair (%d/%d): %v\n", attempt+1, maxJSONRepairs, err)
+		}
+		userPrompt = prompt.JSONRepairPrompt(promptText, response.Content, err)
+	}
+}
@@ -0,0 +1,95 @@
+package workflow
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/1broseidon/promptext-notes/internal/ai"
+	"github.com/1broseidon/promptext-notes/internal/config"
+	"github.com/1broseidon/promptext-notes/internal/git"
+)
+
+func TestGenerateWithAIStructuredRepair(t *testing.T) {
+	cfg := config.Default()
+	cfg.AI.OutputFormat = "json"
+	cfg.AI.ContextWindow = 1_000_000
+
+	in := newOversizedInputs()
+	in.hashes = []git.Commit{
+		{Hash: "a1b2c3d", Subject: "feat: add export"},
+		{Hash: "e4f5a6b", Subject: "fix: crash on start"},
+	}
+
+	provider := &scriptedProvider{responses: []*ai.Response{
+		{Content: `{"sections": [{"name": "new", "items": [{"title": "Export", "description": "", "commits": ["0000000"]}]}]}`, StopReason: ai.StopReasonComplete},
+		{Content: "```json\n" + `{"sections": [{"name": "added", "items": [{"title": "Export", "description": "Export release notes.", "commits": ["a1b2c3d"]}]}]}` + "\n```", StopReason: ai.StopReasonComplete},
+	}}
+
+	content, err := generateWithAI(context.Background(), provider, in, cfg, false)
+	if err != nil {
+		t.Fatalf("generateWithAI() error = %v", err)
+	}
+
+	if !strings.Contains(content, "## [v1.0.0]") || !strings.Contains(content, "### Added\n- **Export** - Export release notes.") {
+		t.Errorf("Expected the JSON rendered as markdown, got:\n%s", content)
+	}
+	if len(provider.requests) != 2 {
+		t.Fatalf("Expected one repair request, got %d requests", len(provider.requests))
+	}
+
+	first := provider.requests[0]
+	if first.JSONSchema == nil || !strings.Contains(first.SystemPrompt, "## Output Format: JSON") {
+		t.Error("Expected the schema in the request and the JSON instructions in the system prompt")
+	}
+	if !strings.Contains(first.Prompt, "a1b2c3d feat: add export") {
+		t.Error("Expected commits prefixed with their hashes")
+	}
+
+	repair := provider.requests[1].Prompt
+	for _, want := range []string{`unknown name "new"`, `commit "0000000" is not in the commit history`, `"name": "new"`} {
+		if !strings.Contains(repair, want) {
+			t.Errorf("Repair prompt should contain %q", want)
+		}
+	}
+}
+
+func TestGenerateWithAIStructuredGivesUp(t *testing.T) {
+	cfg := config.Default()
+	cfg.AI.OutputFormat = "json"
+	cfg.AI.ContextWindow = 1_000_000
+
+	var responses []*ai.Response
+	for i := 0; i <= maxJSONRepairs; i++ {
+		responses = append(responses, &ai.Response{Content: "### Added\n- Export", StopReason: ai.StopReasonComplete})
+	}
+	provider := &scriptedProvider{responses: responses}
+
+	_, err := generateWithAI(context.Background(), provider, newOversizedInputs(), cfg, false)
+	if !errors.Is(err, ErrInvalidStructuredOutput) {
+		t.Errorf("Expected ErrInvalidStructuredOutput, got %v", err)
+	}
+	if len(provider.requests) != maxJSONRepairs+1 {
+		t.Errorf("Expected %d requests, got %d", maxJSONRepairs+1, len(provider.requests))
+	}
+}
+
+func TestGenerateWithAIStructuredWithoutHashes(t *testing.T) {
+	cfg := config.Default()
+	cfg.AI.OutputFormat = "json"
+	cfg.AI.ContextWindow = 1_000_000
+
+	// The commit hashes couldn't be read, so citations can't be checked
+	provider := &scriptedProvider{responses: []*ai.Response{
+		{Content: `{"sections": [{"name": "added", "items": [{"title": "Export", "description": "Export release notes.", "commits": ["a1b2c3d"]}]}]}`, StopReason: ai.StopReasonComplete},
+	}}
+
+	content, err := generateWithAI(context.Background(), provider, newOversizedInputs(), cfg, false)
+	if err != nil {
+		t.Fatalf("generateWithAI() error = %v", err)
+	}
+	if !strings.Contains(content, "- **Export** - Export release notes.") || len(provider.requests) != 1 {
+		t.Errorf("Expected the notes accepted without a repair, got %d requests:\n%s", len(provider.requests), content)
+	}
+}
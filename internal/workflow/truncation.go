@@ -0,0 +1,93 @@
+package workflow
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/1broseidon/promptext-notes/internal/ai"
+	"github.com/1broseidon/promptext-notes/internal/config"
+)
+
+// continuationPrompt asks the model to resume a response that hit the token limit.
+// The first %s is the original prompt, the second is the output produced so far.
+const continuationPrompt = `%s
+
+---
+
+Your previous response was cut off because it reached the output token limit.
+This is the output you produced so far:
+
+%s
+
+Continue EXACTLY where the output above stops. Do not repeat any text that was
+already written and do not add any preamble or commentary.`
+
+// generateComplete calls the provider and handles responses that were cut off by
+// max_tokens, either by requesting continuations or by failing loudly.
+func generateComplete(ctx context.Context, provider ai.Provider, req *ai.Request, cfg *config.Config, verbose bool) (*ai.Response, error) {
+	response, err := provider.Generate(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	if !response.Truncated() {
+		return response, nil
+	}
+
+	mode, maxContinuations := truncationSettings(cfg)
+	if mode == "fail" {
+		return nil, fmt.Errorf("%w (%d tokens used, raise max_tokens or set on_truncation: continue)",
+			ai.ErrTruncated, response.TokensUsed)
+	}
+
+	for i := 1; i <= maxContinuations && response.Truncated(); i++ {
+		if verbose {
+			fmt.Fprintf(os.Stderr, "   ⚠️  Output truncated at max_tokens, requesting continuation %d/%d...\n",
+				i, maxContinuations)
+		}
+
+		contReq := *req
+		contReq.Prompt = fmt.Sprintf(continuationPrompt, req.Prompt, response.Content)
+
+		next, err := provider.Generate(ctx, &contReq)
+		if err != nil {
+			return nil, fmt.Errorf("continuation request failed: %w", err)
+		}
+
+		response = mergeContinuation(response, next)
+	}
+
+	if response.Truncated() {
+		return nil, fmt.Errorf("%w after %d continuation(s)", ai.ErrTruncated, maxContinuations)
+	}
+
+	return response, nil
+}
+
+// truncationSettings returns the effective truncation mode and continuation limit
+func truncationSettings(cfg *config.Config) (string, int) {
+	if cfg == nil {
+		defaults := config.Default()
+		return defaults.AI.OnTruncation, defaults.GetMaxContinuations()
+	}
+
+	mode := cfg.AI.OnTruncation
+	if mode == "" {
+		mode = "continue"
+	}
+
+	return mode, cfg.GetMaxContinuations()
+}
+
+// mergeContinuation appends a continuation response to the partial response,
+// accumulating usage and taking the stop reason of the latest request
+func mergeContinuation(partial, next *ai.Response) *ai.Response {
+	merged := *partial
+	merged.Content = partial.Content + next.Content
+	merged.TokensUsed = partial.TokensUsed + next.TokensUsed
+	merged.CostEstimate = partial.CostEstimate + next.CostEstimate
+	merged.StopReason = next.StopReason
+	merged.Cached = partial.Cached && next.Cached
+	return &merged
+}
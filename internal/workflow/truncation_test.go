@@ -0,0 +1,100 @@
+package workflow
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/1broseidon/promptext-notes/internal/ai"
+	"github.com/1broseidon/promptext-notes/internal/config"
+)
+
+// scriptedProvider returns queued responses in order and records requests
+type scriptedProvider struct {
+	responses []*ai.Response
+	requests  []*ai.Request
+}
+
+func (p *scriptedProvider) Generate(ctx context.Context, req *ai.Request) (*ai.Response, error) {
+	p.requests = append(p.requests, req)
+	if len(p.responses) == 0 {
+		return nil, errors.New("no scripted response left")
+	}
+	resp := p.responses[0]
+	p.responses = p.responses[1:]
+	return resp, nil
+}
+
+func (p *scriptedProvider) Name() string          { return "scripted" }
+func (p *scriptedProvider) ValidateConfig() error { return nil }
+func (p *scriptedProvider) NewRequest(prompt string) *ai.Request {
+	return &ai.Request{Prompt: prompt, MaxTokens: 100}
+}
+
+func TestGenerateCompleteContinues(t *testing.T) {
+	provider := &scriptedProvider{
+		responses: []*ai.Response{
+			{Content: "### Added\n- Fir", TokensUsed: 10, CostEstimate: 0.1, StopReason: ai.StopReasonMaxTokens},
+			{Content: "st item\n", TokensUsed: 5, CostEstimate: 0.05, StopReason: ai.StopReasonComplete},
+		},
+	}
+
+	resp, err := generateComplete(context.Background(), provider, provider.NewRequest("prompt"), config.Default(), false)
+	if err != nil {
+		t.Fatalf("generateComplete() error = %v", err)
+	}
+
+	if resp.Content != "### Added\n- First item\n" {
+		t.Errorf("Unexpected merged content: %q", resp.Content)
+	}
+	if resp.TokensUsed != 15 {
+		t.Errorf("Expected 15 tokens, got %d", resp.TokensUsed)
+	}
+	if resp.Truncated() {
+		t.Error("Merged response should not be truncated")
+	}
+	if len(provider.requests) != 2 {
+		t.Fatalf("Expected 2 requests, got %d", len(provider.requests))
+	}
+	if !strings.Contains(provider.requests[1].Prompt, "- Fir") {
+		t.Error("Continuation prompt should include the partial output")
+	}
+}
+
+func TestGenerateCompleteFailMode(t *testing.T) {
+	cfg := config.Default()
+	cfg.AI.OnTruncation = "fail"
+
+	provider := &scriptedProvider{
+		responses: []*ai.Response{
+			{Content: "partial", StopReason: ai.StopReasonMaxTokens},
+		},
+	}
+
+	_, err := generateComplete(context.Background(), provider, provider.NewRequest("prompt"), cfg, false)
+	if !errors.Is(err, ai.ErrTruncated) {
+		t.Fatalf("Expected ErrTruncated, got %v", err)
+	}
+	if len(provider.requests) != 1 {
+		t.Errorf("Expected no continuation requests, got %d requests", len(provider.requests))
+	}
+}
+
+func TestGenerateCompleteExhaustsContinuations(t *testing.T) {
+	cfg := config.Default()
+	maxContinuations := 1
+	cfg.AI.MaxContinuations = &maxContinuations
+
+	provider := &scriptedProvider{
+		responses: []*ai.Response{
+			{Content: "a", StopReason: ai.StopReasonMaxTokens},
+			{Content: "b", StopReason: ai.StopReasonMaxTokens},
+		},
+	}
+
+	_, err := generateComplete(context.Background(), provider, provider.NewRequest("prompt"), cfg, false)
+	if !errors.Is(err, ai.ErrTruncated) {
+		t.Fatalf("Expected ErrTruncated, got %v", err)
+	}
+}
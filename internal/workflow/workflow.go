@@ -8,8 +8,11 @@ import (
 
 	"github.com/1broseidon/promptext-notes/internal/ai"
 	"github.com/1broseidon/promptext-notes/internal/analyzer"
+	"github.com/1broseidon/promptext-notes/internal/apidiff"
 	"github.com/1broseidon/promptext-notes/internal/config"
 	aicontext "github.com/1broseidon/promptext-notes/internal/context"
+	"github.com/1broseidon/promptext-notes/internal/deps"
+	"github.com/1broseidon/promptext-notes/internal/diff"
 	"github.com/1broseidon/promptext-notes/internal/generator"
 	"github.com/1broseidon/promptext-notes/internal/git"
 	"github.com/1broseidon/promptext-notes/internal/prompt"
@@ -23,7 +26,8 @@ type GenerateOptions struct {
 	UseAI        bool
 	AIPromptOnly bool
 	Verbose      bool
-	ExcludeFiles []string // Files to exclude from AI context (e.g., CHANGELOG.md)
+	IncludeFiles []string // Glob patterns of files to include in AI context and diff (e.g., *.go, *.ts)
+	ExcludeFiles []string // Files to exclude from AI context and diff (e.g., CHANGELOG.md)
 }
 
 // gitData holds git-related data for release notes
@@ -113,12 +117,38 @@ func GenerateReleaseNotes(ctx context.Context, opts GenerateOptions, provider ai
 		return "", err
 	}
 
+	// Secrets are removed from everything that may be sent to a provider,
+	// before the diff is parsed and the prompt is built
+	var redactor *redaction
+	if opts.UseAI || opts.AIPromptOnly {
+		redactor, err = newRedaction(cfg)
+		if err != nil {
+			return "", err
+		}
+	}
+	if redactor != nil {
+		redactor.gitData(gitData)
+	}
+
+	diffFiles := diff.Parse(gitData.diff)
+
 	// Extract code context
 	if opts.Verbose {
 		fmt.Fprintln(os.Stderr, "\n🔍 Extracting code context with promptext...")
 	}
 
-	result, err := aicontext.ExtractCodeContext(gitData.changedFiles, opts.ExcludeFiles)
+	extractOpts := aicontext.ExtractOptions{
+		Include:  opts.IncludeFiles,
+		Exclude:  opts.ExcludeFiles,
+		Diff:     diffFiles,
+		SinceRef: opts.SinceTag,
+	}
+	if cfg != nil {
+		extractOpts.TokenBudget = cfg.AI.ContextTokens
+		extractOpts.OverviewBudget = cfg.AI.OverviewTokens
+	}
+
+	result, contextFiles, err := aicontext.ExtractCodeContext(gitData.changedFiles, extractOpts)
 	if err != nil {
 		return "", fmt.Errorf("failed to extract context: %w", err)
 	}
@@ -126,27 +156,83 @@ func GenerateReleaseNotes(ctx context.Context, opts GenerateOptions, provider ai
 	if opts.Verbose {
 		fmt.Fprintf(os.Stderr, "   Extracted context: ~%d tokens from %d files\n",
 			result.TokenCount, len(result.ProjectOutput.Files))
+		printContextFiles(contextFiles)
+	}
+
+	if redactor != nil {
+		redactor.codeContext(result)
+		if err := redactor.finish(opts.Verbose); err != nil {
+			return "", err
+		}
 	}
 
 	// Filter and categorize commits
 	filteredCommits := filterCommitsIfNeeded(gitData.commits, cfg, opts.Verbose)
 	categories := analyzer.CategorizeCommits(filteredCommits)
+	categories.APIChanges = detectAPIChanges(opts.SinceTag, gitData.changedFiles, opts.Verbose)
+	categories.Dependencies = detectDependencyChanges(opts.SinceTag, gitData.changedFiles, opts.Verbose)
+
+	// Fit the diff to its token budget, most relevant files first
+	diff.Filter(diffFiles, func(path string) bool {
+		return aicontext.Selected(path, opts.IncludeFiles, opts.ExcludeFiles)
+	})
+	selection := diff.Budget(diffFiles, diffTokenBudget(cfg))
+	if opts.Verbose && len(diffFiles) > 0 {
+		fmt.Fprintf(os.Stderr, "   Diff: %d file(s) in full, %d partially, %d over budget and %d filtered/lockfile/vendored/binary as stats (~%d tokens)\n",
+			selection.Included, selection.Partial, selection.Omitted, selection.Dropped, selection.Tokens)
+	}
 
 	// Generate AI prompt (use filtered commits)
-	promptText := prompt.GenerateAIPrompt(opts.Version, opts.SinceTag, filteredCommits,
-		categories, result, gitData.diffStats, gitData.diff)
+	inputs := &promptInputs{
+		version:    opts.Version,
+		sinceTag:   opts.SinceTag,
+		commits:    filteredCommits,
+		categories: categories,
+		result:     result,
+		diffStats:  gitData.diffStats,
+		diff:       selection.Text,
+		diffFiles:  diffFiles,
+	}
+
+	if opts.UseAI || opts.AIPromptOnly {
+		templatePath := ""
+		if cfg != nil {
+			templatePath = cfg.AI.PromptTemplate
+		}
+		if inputs.template, err = prompt.LoadTemplate(templatePath); err != nil {
+			return "", err
+		}
+		inputs.previousNotes = previousNotes(opts.SinceTag, opts.Verbose)
+		if structuredOutput(cfg) {
+			inputs.hashes = commitHashes(opts.SinceTag, opts.Verbose)
+		}
+	}
+
+	inputs.fence = prompt.NewFenceFor(inputs.untrusted())
+
+	if opts.Verbose {
+		scanInjections(inputs)
+	}
 
 	// If only prompt is requested, return it
 	if opts.AIPromptOnly {
 		if opts.Verbose {
 			fmt.Fprintln(os.Stderr, "\n📝 Generated AI prompt (see stdout)")
 		}
-		return promptText, nil
+		systemPrompt, err := inputs.system()
+		if err != nil {
+			return "", err
+		}
+		promptText, err := inputs.build()
+		if err != nil {
+			return "", err
+		}
+		return systemPrompt + "\n---\n\n" + promptText, nil
 	}
 
 	// If AI enhancement is requested, call the AI provider
 	if opts.UseAI && provider != nil {
-		return generateWithAI(ctx, provider, promptText, cfg, opts.Verbose)
+		return generateWithAI(ctx, provider, inputs, cfg, opts.Verbose)
 	}
 
 	// Otherwise, generate basic release notes
@@ -157,61 +243,204 @@ func GenerateReleaseNotes(ctx context.Context, opts GenerateOptions, provider ai
 	return generator.GenerateReleaseNotes(opts.Version, categories, result, cfg), nil
 }
 
-// generateWithAI handles AI generation with optional polish stage
-func generateWithAI(ctx context.Context, provider ai.Provider, promptText string, cfg *config.Config, verbose bool) (string, error) {
-	content, err := generateAIContent(ctx, provider, promptText, verbose)
+// printContextFiles writes each file in the code context and why it was included
+func printContextFiles(files []aicontext.ContextFile) {
+	for _, file := range files {
+		reason := file.Reason
+		if file.Via != "" {
+			reason += " via " + file.Via
+		}
+		fmt.Fprintf(os.Stderr, "     %s (~%d tokens, %s)\n", file.Path, file.Tokens, reason)
+	}
+}
+
+// detectAPIChanges finds removed and changed exported Go APIs (non-fatal)
+func detectAPIChanges(sinceTag string, changedFiles []string, verbose bool) []string {
+	changes, err := apidiff.Detect(sinceTag, changedFiles)
 	if err != nil {
-		return "", err
+		if verbose {
+			fmt.Fprintf(os.Stderr, "   Warning: could not compare exported APIs: %v\n", err)
+		}
+		return nil
 	}
 
-	// Stage 2: Polish if enabled
-	if cfg != nil && cfg.AI.Polish.Enabled {
+	var items []string
+	for _, change := range changes {
+		items = append(items, change.String())
+	}
+
+	if verbose && len(items) > 0 {
+		fmt.Fprintf(os.Stderr, "   ⚠️  Detected %d breaking change(s) in exported Go APIs:\n", len(items))
+		for _, item := range items {
+			fmt.Fprintf(os.Stderr, "     %s\n", item)
+		}
+	}
+
+	return items
+}
+
+// detectDependencyChanges diffs dependency manifests between the refs (non-fatal)
+func detectDependencyChanges(sinceTag string, changedFiles []string, verbose bool) []string {
+	changes, err := deps.Detect(sinceTag, changedFiles)
+	if err != nil {
 		if verbose {
-			polishProvider := cfg.GetPolishProvider()
-			polishModel := cfg.GetPolishModel()
-			fmt.Fprintf(os.Stderr, "\n✨ Polishing changelog with %s (%s)...\n", polishProvider, polishModel)
+			fmt.Fprintf(os.Stderr, "   Warning: could not compare dependency manifests: %v\n", err)
 		}
+		return nil
+	}
 
-		polishedContent, err := PolishChangelog(ctx, content, cfg)
-		if err != nil {
-			return "", fmt.Errorf("failed to polish changelog: %w", err)
+	var items []string
+	for _, change := range changes {
+		items = append(items, change.String())
+	}
+
+	if verbose && len(items) > 0 {
+		fmt.Fprintf(os.Stderr, "   📦 Detected %d dependency change(s)\n", len(items))
+	}
+
+	return items
+}
+
+// previousNotes reads the release notes of the since tag from CHANGELOG.md as
+// of that tag, for prompt templates (non-fatal)
+func previousNotes(sinceTag string, verbose bool) string {
+	if sinceTag == "" {
+		return ""
+	}
+
+	changelog, err := git.ShowFile(sinceTag, "CHANGELOG.md")
+	if err != nil {
+		if verbose {
+			fmt.Fprintf(os.Stderr, "   No CHANGELOG.md at %s for the previous release notes\n", sinceTag)
 		}
+		return ""
+	}
+	return prompt.PreviousNotes(changelog)
+}
 
+// diffTokenBudget returns the configured diff budget in tokens
+func diffTokenBudget(cfg *config.Config) int {
+	if cfg == nil || cfg.AI.DiffTokens <= 0 {
+		return config.Default().AI.DiffTokens
+	}
+	return cfg.AI.DiffTokens
+}
+
+// generateWithAI handles AI generation through the configured pipeline: by
+// default discovery (a single prompt or map-reduce for large releases) and an
+// optional polish stage
+func generateWithAI(ctx context.Context, provider ai.Provider, inputs *promptInputs, cfg *config.Config, verbose bool) (string, error) {
+	// Track cost per stage and enforce ai.max_cost_usd across all stages
+	maxCost := 0.0
+	if cfg != nil {
+		maxCost = cfg.AI.MaxCostUSD
+	}
+	tracker := ai.NewCostTracker(maxCost)
+	if verbose {
+		defer printCostReport(tracker)
+	}
+
+	return runPipeline(ctx, provider, tracker, inputs, cfg, verbose)
+}
+
+// generateDraft produces the stage 1 changelog, summarizing releases too large
+// for one prompt in chunks ("map" and "reduce" usage) and otherwise fitting a
+// single prompt to the context window (usage recorded under stage)
+func generateDraft(ctx context.Context, provider ai.Provider, tracker *ai.CostTracker, stage string, inputs *promptInputs, cfg *config.Config, verbose bool) (string, error) {
+	if verbose {
+		fmt.Fprintln(os.Stderr, "\n📏 Counting prompt tokens...")
+	}
+
+	mapReduce, err := useMapReduce(inputs, provider, cfg)
+	if err != nil {
+		return "", err
+	}
+	if mapReduce {
 		if verbose {
-			fmt.Fprintln(os.Stderr, "   ✓ Polish complete")
+			fmt.Fprintf(os.Stderr, "   Using map-reduce summarization (map_reduce.mode: %s)\n", cfg.AI.MapReduce.Mode)
 		}
+		return generateMapReduce(ctx, provider, tracker, inputs, cfg, verbose)
+	}
 
-		return polishedContent, nil
+	promptText, err := fitContextWindow(inputs, provider, cfg, verbose)
+	if err != nil {
+		return "", err
 	}
 
-	return content, nil
+	systemPrompt, err := inputs.system()
+	if err != nil {
+		return "", err
+	}
+
+	return generateAIContent(ctx, ai.WithCostTracking(provider, tracker, stage, cfg), inputs, systemPrompt, promptText, cfg, verbose)
 }
 
-// generateAIContent calls the AI provider and processes the response
-func generateAIContent(ctx context.Context, provider ai.Provider, promptText string, verbose bool) (string, error) {
+// generateAIContent calls the AI provider with the instructions as the system
+// prompt and processes the response. With ai.output_format json the notes are
+// requested as JSON and rendered by the generator.
+func generateAIContent(ctx context.Context, provider ai.Provider, in *promptInputs, systemPrompt, promptText string, cfg *config.Config, verbose bool) (string, error) {
 	if verbose {
 		fmt.Fprintf(os.Stderr, "\n🤖 Generating AI-enhanced changelog using %s...\n", provider.Name())
 	}
 
-	// Create AI request using provider's configured defaults
-	req := provider.NewRequest(promptText)
+	var content string
+	var response *ai.Response
+	if structuredOutput(cfg) {
+		var err error
+		content, response, err = generateStructured(ctx, provider, in, systemPrompt, promptText, cfg, verbose)
+		if err != nil {
+			return "", err
+		}
+	} else {
+		// Create AI request using provider's configured defaults
+		req := provider.NewRequest(promptText)
+		req.SystemPrompt = systemPrompt
+
+		// Call AI provider (stage 1: discovery), continuing or failing on truncation
+		var err error
+		response, err = generateComplete(ctx, provider, req, cfg, verbose)
+		if err != nil {
+			return "", fmt.Errorf("failed to generate AI response: %w", err)
+		}
 
-	// Call AI provider (stage 1: discovery)
-	response, err := provider.Generate(ctx, req)
-	if err != nil {
-		return "", fmt.Errorf("failed to generate AI response: %w", err)
+		// Post-process the AI response to remove any extra headers
+		content = stripAIHeaders(response.Content)
 	}
 
 	if verbose {
 		fmt.Fprintf(os.Stderr, "   ✓ Generated %d tokens", response.TokensUsed)
-		if response.CostEstimate > 0 {
+		if response.Cached {
+			fmt.Fprint(os.Stderr, " (cached, cost: $0.0000)")
+		} else if response.CostEstimate > 0 {
 			fmt.Fprintf(os.Stderr, " (estimated cost: $%.4f)", response.CostEstimate)
 		}
 		fmt.Fprintln(os.Stderr)
 	}
 
-	// Post-process the AI response to remove any extra headers
-	return stripAIHeaders(response.Content), nil
+	return content, nil
+}
+
+// printCostReport writes per-stage and total usage to stderr
+func printCostReport(tracker *ai.CostTracker) {
+	stages := tracker.Stages()
+	if len(stages) == 0 {
+		return
+	}
+
+	fmt.Fprintln(os.Stderr, "\n💰 Usage by stage:")
+	for _, stage := range stages {
+		cost := fmt.Sprintf("$%.4f", stage.Cost)
+		if stage.Unpriced {
+			cost += " (unknown pricing for some requests)"
+		}
+		if stage.Cached > 0 {
+			cost += fmt.Sprintf(" (%d cached)", stage.Cached)
+		}
+		fmt.Fprintf(os.Stderr, "   %-10s %s/%s: %d request(s), %d tokens (%d in / %d out), %s\n",
+			stage.Stage, stage.Provider, stage.Model, stage.Requests,
+			stage.TokensUsed, stage.InputTokens, stage.OutputTokens, cost)
+	}
+	fmt.Fprintf(os.Stderr, "   Total: $%.4f\n", tracker.TotalCost())
 }
 
 // stripAIHeaders removes common AI-generated headers from the response
@@ -0,0 +1,176 @@
+package workflow
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	"github.com/1broseidon/promptext-notes/internal/ai"
+	"github.com/1broseidon/promptext-notes/internal/config"
+)
+
+// newTestRepo creates a git repository with a v0.1.0 tag and one feature
+// commit after it, and makes it the working directory
+func newTestRepo(t *testing.T) {
+	t.Helper()
+	dir := t.TempDir()
+
+	git := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", append([]string{"-c", "user.name=Test", "-c", "user.email=test@example.com"}, args...)...)
+		cmd.Dir = dir
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	write := func(name, content string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	git("init", "-q")
+	write("main.go", "package main\n\nfunc main() {}\n")
+	git("add", ".")
+	git("commit", "-q", "-m", "initial commit")
+	git("tag", "v0.1.0")
+
+	write("export.go", "package main\n\n// Export writes the notes as JSON\nfunc Export() {}\n")
+	git("add", ".")
+	git("commit", "-q", "-m", "feat: add export command")
+
+	t.Chdir(dir)
+}
+
+// newNotesServer fakes Ollama, answering every request with the same notes
+// and counting the requests
+func newNotesServer(t *testing.T) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"message":     map[string]string{"role": "assistant", "content": "### Added\n- **Export** - add export command"},
+			"done":        true,
+			"done_reason": "stop",
+		})
+	}))
+	t.Cleanup(server.Close)
+	return server, &calls
+}
+
+// newRunConfig configures a run against the fake Ollama server
+func newRunConfig(serverURL string) *config.Config {
+	cfg := config.Default()
+	cfg.AI.Provider = "ollama"
+	cfg.AI.Model = "llama3.2"
+	cfg.AI.APIKeyEnv = ""
+	cfg.AI.Custom = map[string]string{"ollama_url": serverURL}
+	cfg.AI.Cache.Mode = "off"
+	return cfg
+}
+
+// responseRecorder keeps every response a provider returns
+type responseRecorder struct {
+	ai.Provider
+	responses []*ai.Response
+}
+
+func (r *responseRecorder) Generate(ctx context.Context, req *ai.Request) (*ai.Response, error) {
+	resp, err := r.Provider.Generate(ctx, req)
+	if resp != nil {
+		r.responses = append(r.responses, resp)
+	}
+	return resp, err
+}
+
+func TestGenerateReleaseNotesCachesReruns(t *testing.T) {
+	newTestRepo(t)
+	server, calls := newNotesServer(t)
+
+	cfg := newRunConfig(server.URL)
+	cfg.AI.Cache.Mode = "on"
+	cfg.AI.Cache.Dir = t.TempDir()
+	provider, err := ai.NewProvider(cfg)
+	if err != nil {
+		t.Fatalf("NewProvider() error = %v", err)
+	}
+	recorder := &responseRecorder{Provider: provider}
+
+	opts := GenerateOptions{Version: "v0.2.0", SinceTag: "v0.1.0", UseAI: true}
+	first, err := GenerateReleaseNotes(context.Background(), opts, recorder, cfg)
+	if err != nil {
+		t.Fatalf("GenerateReleaseNotes() error = %v", err)
+	}
+	second, err := GenerateReleaseNotes(context.Background(), opts, recorder, cfg)
+	if err != nil {
+		t.Fatalf("GenerateReleaseNotes() error = %v", err)
+	}
+
+	if calls.Load() != 1 {
+		t.Errorf("Expected the re-run to be served from the cache, got %d provider requests", calls.Load())
+	}
+	if len(recorder.responses) != 2 || !recorder.responses[1].Cached {
+		t.Errorf("Expected the second response to be cached, got %+v", recorder.responses)
+	}
+	if first != second {
+		t.Errorf("Expected the same release notes from the cache, got:\n%s\nand:\n%s", first, second)
+	}
+}
+
+func TestGenerateReleaseNotesReplaysRecordedRun(t *testing.T) {
+	newTestRepo(t)
+	server, calls := newNotesServer(t)
+	fixtures := t.TempDir()
+	opts := GenerateOptions{Version: "v0.2.0", SinceTag: "v0.1.0", UseAI: true}
+
+	t.Setenv("SOURCE_DATE_EPOCH", "1735689600") // 2025-01-01
+	cfg := newRunConfig(server.URL)
+	cfg.AI.Replay.Dir = fixtures
+	cfg.AI.Replay.Record = true
+	provider, err := ai.NewProvider(cfg)
+	if err != nil {
+		t.Fatalf("NewProvider() error = %v", err)
+	}
+	recorded, err := GenerateReleaseNotes(context.Background(), opts, provider, cfg)
+	if err != nil {
+		t.Fatalf("GenerateReleaseNotes() error = %v", err)
+	}
+	server.Close()
+
+	replayCfg := newRunConfig(server.URL)
+	replayCfg.AI.Provider = "replay"
+	replayCfg.AI.Replay = config.ReplayConfig{Dir: fixtures, Provider: "ollama"}
+	if err := replayCfg.Validate(); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+	replay, err := ai.NewProvider(replayCfg)
+	if err != nil {
+		t.Fatalf("NewProvider() error = %v", err)
+	}
+	replayed, err := GenerateReleaseNotes(context.Background(), opts, replay, replayCfg)
+	if err != nil {
+		t.Fatalf("Expected the recorded run to replay, got error %v", err)
+	}
+
+	if calls.Load() != 1 {
+		t.Errorf("Expected only the recording run to reach the provider, got %d requests", calls.Load())
+	}
+	if replayed != recorded {
+		t.Errorf("Expected the recorded release notes, got:\n%s\nwant:\n%s", replayed, recorded)
+	}
+
+	// The date is in the prompt, so a run on another day needs it pinned
+	t.Setenv("SOURCE_DATE_EPOCH", "1735776000") // 2025-01-02
+	if _, err := GenerateReleaseNotes(context.Background(), opts, replay, replayCfg); !errors.Is(err, ai.ErrFixtureNotFound) {
+		t.Errorf("Expected ErrFixtureNotFound for another date, got %v", err)
+	}
+}